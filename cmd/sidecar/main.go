@@ -22,12 +22,25 @@ import (
 
 // secretsConfig is the JSON configuration structure passed via environment
 type secretsConfig struct {
-	Secrets       []secretEntry `json:"secrets"`
-	Folders       []folderEntry `json:"folders,omitempty"`
-	FailOnError   bool          `json:"failOnError"`
-	StrictLookup  bool          `json:"strictLookup"`
-	RefreshSignal string        `json:"refreshSignal"`
-	AuthMethod    string        `json:"authMethod,omitempty"` // "secret", "aws-secrets-manager", "gcp-secret-manager", "azure-key-vault"
+	Secrets       []secretEntry   `json:"secrets"`
+	Folders       []folderEntry   `json:"folders,omitempty"`
+	Templates     []templateEntry `json:"templates,omitempty"`
+	FailOnError   bool            `json:"failOnError"`
+	StrictLookup  bool            `json:"strictLookup"`
+	RefreshSignal string          `json:"refreshSignal"`
+	SignalProcess string          `json:"signalProcess,omitempty"`
+	Hooks         []hookEntry     `json:"hooks,omitempty"`
+	AuthMethod    string          `json:"authMethod,omitempty"`  // "secret", "aws-secrets-manager", "gcp-secret-manager", "azure-key-vault"
+	CacheDir      string          `json:"cacheDir,omitempty"`    // Directory for the encrypted persistent cache
+	CacheMaxAge   string          `json:"cacheMaxAge,omitempty"` // Maximum age of cached values (default: 24h)
+	SecretsAPI    bool            `json:"secretsApi,omitempty"`  // Serve the local secrets API on a Unix socket
+	ExecRestart   bool            `json:"execRestart,omitempty"` // Exec mode: restart the app process when values change
+	OutputRoots   []string        `json:"outputRoots,omitempty"` // Directories published atomically (default: /keeper/secrets)
+
+	// K8s Secret rotation
+	K8sSecretRotation  bool   `json:"k8sSecretRotation,omitempty"`
+	K8sSecretNamespace string `json:"k8sSecretNamespace,omitempty"`
+	K8sSecretMode      string `json:"k8sSecretMode,omitempty"`
 
 	// Cloud provider configuration
 	AWSSecretID     string `json:"awsSecretId,omitempty"`
@@ -38,19 +51,57 @@ type secretsConfig struct {
 }
 
 type secretEntry struct {
-	Name     string   `json:"name"`
-	Path     string   `json:"path"`
-	Format   string   `json:"format"`
-	Fields   []string `json:"fields,omitempty"`
-	Notation string   `json:"notation,omitempty"`
-	FileName string   `json:"fileName,omitempty"`
-	IsFile   bool     `json:"isFile,omitempty"`
+	Name         string   `json:"name"`
+	Path         string   `json:"path"`
+	Format       string   `json:"format"`
+	Template     string   `json:"template,omitempty"`
+	TemplateFile string   `json:"templateFile,omitempty"`
+	Fields       []string `json:"fields,omitempty"`
+	Notation     string   `json:"notation,omitempty"`
+	FileName     string   `json:"fileName,omitempty"`
+	IsFile       bool     `json:"isFile,omitempty"`
+	EnvPrefix    string   `json:"envPrefix,omitempty"`
+
+	InjectAsK8sSecret bool              `json:"injectAsK8sSecret,omitempty"`
+	K8sSecretName     string            `json:"k8sSecretName,omitempty"`
+	K8sSecretKeys     map[string]string `json:"k8sSecretKeys,omitempty"`
+
+	Hooks []hookEntry `json:"hooks,omitempty"`
+}
+
+type hookEntry struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url,omitempty"`
+	Command []string `json:"command,omitempty"`
+	Timeout string   `json:"timeout,omitempty"`
+	Retries int      `json:"retries,omitempty"`
+}
+
+type templateEntry struct {
+	Name         string            `json:"name"`
+	Path         string            `json:"path"`
+	Records      map[string]string `json:"records"`
+	Template     string            `json:"template"`
+	TemplateFile string            `json:"templateFile,omitempty"`
+
+	Hooks []hookEntry `json:"hooks,omitempty"`
 }
 
 type folderEntry struct {
 	FolderUID  string `json:"folderUid,omitempty"`
 	FolderPath string `json:"folderPath,omitempty"`
 	OutputPath string `json:"outputPath"`
+	Prune      *bool  `json:"prune,omitempty"` // Defaults to true
+
+	Format          string `json:"format,omitempty"`
+	Template        string `json:"template,omitempty"`
+	FilenamePattern string `json:"filenamePattern,omitempty"`
+
+	Recursive bool                 `json:"recursive,omitempty"`
+	Filter    sidecar.RecordFilter `json:"filter,omitempty"`
+
+	InjectAsK8sSecret   bool   `json:"injectAsK8sSecret,omitempty"`
+	K8sSecretNamePrefix string `json:"k8sSecretNamePrefix,omitempty"`
 }
 
 func main() {
@@ -59,12 +110,20 @@ func main() {
 		refreshInterval time.Duration
 		logLevel        string
 		logFormat       string
+		refreshSignal   string
+		allowUnsafe     bool
+		execWrapperPath string
+		execCredentials string
 	)
 
-	flag.StringVar(&mode, "mode", "sidecar", "Operating mode: init or sidecar")
+	flag.StringVar(&mode, "mode", "sidecar", "Operating mode: init, sidecar or exec (runs the command after --)")
 	flag.DurationVar(&refreshInterval, "refresh-interval", 5*time.Minute, "Secret refresh interval (sidecar mode only)")
 	flag.StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
 	flag.StringVar(&logFormat, "log-format", "json", "Log format (json, console)")
+	flag.StringVar(&refreshSignal, "signal", "", "Signal to send to the app process when secrets change (overrides refreshSignal in KEEPER_CONFIG)")
+	flag.BoolVar(&allowUnsafe, "allow-unsafe-template-funcs", false, "Allow template functions that read the environment or network (env, expandenv, getHostByName)")
+	flag.StringVar(&execWrapperPath, "install-exec-wrapper", "", "Copy this binary to the path for app containers in exec mode (init mode only)")
+	flag.StringVar(&execCredentials, "exec-credentials", "", "Init mode: write the KSM config to these comma-separated files for exec wrappers. Exec mode: read the KSM config from this file")
 	flag.Parse()
 
 	// Set up logger
@@ -74,6 +133,13 @@ func main() {
 		zap.String("mode", mode),
 		zap.Duration("refreshInterval", refreshInterval))
 
+	if execWrapperPath != "" && mode == "init" {
+		if err := sidecar.InstallExecWrapper(execWrapperPath); err != nil {
+			logger.Fatal("failed to install exec wrapper", zap.Error(err))
+		}
+		logger.Info("installed exec wrapper", zap.String("path", execWrapperPath))
+	}
+
 	// Load custom CA certificate if present (for corporate proxies)
 	if err := loadCustomCACert(logger); err != nil {
 		logger.Warn("failed to load custom CA certificate", zap.Error(err))
@@ -90,6 +156,10 @@ func main() {
 		logger.Fatal("failed to parse KEEPER_CONFIG", zap.Error(err))
 	}
 
+	if refreshSignal != "" {
+		cfg.RefreshSignal = refreshSignal
+	}
+
 	// Create context for cloud SDK calls
 	ctx := context.Background()
 
@@ -97,8 +167,15 @@ func main() {
 	var ksmConfig string
 	var err error
 
-	switch cfg.AuthMethod {
-	case "aws-secrets-manager":
+	switch {
+	case mode == "exec" && execCredentials != "":
+		// The init container left the credentials for this wrapper
+		ksmConfig, err = sidecar.ReadExecCredentials(execCredentials)
+		if err != nil {
+			logger.Fatal("failed to load KSM config for the exec wrapper", zap.Error(err))
+		}
+
+	case cfg.AuthMethod == "aws-secrets-manager":
 		logger.Info("fetching KSM config from AWS Secrets Manager",
 			zap.String("secretId", cfg.AWSSecretID),
 			zap.String("region", cfg.AWSRegion))
@@ -109,7 +186,7 @@ func main() {
 		}
 		logger.Info("successfully fetched KSM config from AWS Secrets Manager")
 
-	case "gcp-secret-manager":
+	case cfg.AuthMethod == "gcp-secret-manager":
 		logger.Info("fetching KSM config from GCP Secret Manager",
 			zap.String("secretId", cfg.GCPSecretID))
 
@@ -119,7 +196,7 @@ func main() {
 		}
 		logger.Info("successfully fetched KSM config from GCP Secret Manager")
 
-	case "azure-key-vault":
+	case cfg.AuthMethod == "azure-key-vault":
 		logger.Info("fetching KSM config from Azure Key Vault",
 			zap.String("vaultName", cfg.AzureVaultName),
 			zap.String("secretName", cfg.AzureSecretName))
@@ -130,7 +207,7 @@ func main() {
 		}
 		logger.Info("successfully fetched KSM config from Azure Key Vault")
 
-	case "secret", "":
+	case cfg.AuthMethod == "secret", cfg.AuthMethod == "":
 		// Default: read from K8s Secret via environment variable
 		ksmConfig = os.Getenv("KEEPER_AUTH_CONFIG")
 		if ksmConfig == "" {
@@ -150,17 +227,31 @@ func main() {
 	}
 	logger.Debug("KSM configuration validated successfully")
 
+	if execCredentials != "" && mode == "init" {
+		if err := sidecar.WriteExecCredentials(strings.Split(execCredentials, ","), ksmConfig); err != nil {
+			logger.Fatal("failed to write KSM config for exec wrappers", zap.Error(err))
+		}
+	}
+
 	// Convert to agent config
 	secrets := make([]sidecar.SecretConfig, len(cfg.Secrets))
 	for i, s := range cfg.Secrets {
 		secrets[i] = sidecar.SecretConfig{
-			Name:     s.Name,
-			Path:     s.Path,
-			Format:   s.Format,
-			Fields:   s.Fields,
-			Notation: s.Notation,
-			FileName: s.FileName,
-			IsFile:   s.IsFile,
+			Name:         s.Name,
+			Path:         s.Path,
+			Format:       s.Format,
+			Template:     s.Template,
+			TemplateFile: s.TemplateFile,
+			Fields:       s.Fields,
+			Notation:     s.Notation,
+			FileName:     s.FileName,
+			IsFile:       s.IsFile,
+			EnvPrefix:    s.EnvPrefix,
+			Hooks:        convertHooks(s.Hooks, logger),
+
+			InjectAsK8sSecret: s.InjectAsK8sSecret,
+			K8sSecretName:     s.K8sSecretName,
+			K8sSecretKeys:     s.K8sSecretKeys,
 		}
 	}
 
@@ -171,9 +262,59 @@ func main() {
 			FolderUID:  f.FolderUID,
 			FolderPath: f.FolderPath,
 			OutputPath: f.OutputPath,
+			Prune:      f.Prune == nil || *f.Prune,
+
+			Format:          f.Format,
+			Template:        f.Template,
+			FilenamePattern: f.FilenamePattern,
+
+			Recursive: f.Recursive,
+			Filter:    f.Filter,
+
+			InjectAsK8sSecret:   f.InjectAsK8sSecret,
+			K8sSecretNamePrefix: f.K8sSecretNamePrefix,
+		}
+	}
+
+	// Convert multi-record templates
+	templates := make([]sidecar.TemplateConfig, len(cfg.Templates))
+	for i, t := range cfg.Templates {
+		templates[i] = sidecar.TemplateConfig{
+			Name:         t.Name,
+			Path:         t.Path,
+			Records:      t.Records,
+			Template:     t.Template,
+			TemplateFile: t.TemplateFile,
+			Hooks:        convertHooks(t.Hooks, logger),
 		}
 	}
 
+	var cacheMaxAge time.Duration
+	if cfg.CacheMaxAge != "" {
+		cacheMaxAge, err = time.ParseDuration(cfg.CacheMaxAge)
+		if err != nil {
+			logger.Fatal("invalid cache max age", zap.String("cacheMaxAge", cfg.CacheMaxAge), zap.Error(err))
+		}
+	}
+
+	if mode == "exec" {
+		code, err := sidecar.RunExec(ctx, sidecar.ExecConfig{
+			Command:         flag.Args(),
+			Secrets:         secrets,
+			FailOnError:     cfg.FailOnError,
+			StrictLookup:    cfg.StrictLookup,
+			KSMConfig:       ksmConfig,
+			AuthMethod:      cfg.AuthMethod,
+			RestartOnChange: cfg.ExecRestart,
+			RefreshInterval: refreshInterval,
+			Logger:          logger,
+		})
+		if err != nil {
+			logger.Fatal("exec wrapper failed", zap.Error(err))
+		}
+		os.Exit(code)
+	}
+
 	agentMode := sidecar.ModeSidecar
 	if mode == "init" {
 		agentMode = sidecar.ModeInit
@@ -183,13 +324,27 @@ func main() {
 		Mode:            agentMode,
 		Secrets:         secrets,
 		Folders:         folders,
+		Templates:       templates,
 		RefreshInterval: refreshInterval,
 		FailOnError:     cfg.FailOnError,
 		StrictLookup:    cfg.StrictLookup,
 		RefreshSignal:   cfg.RefreshSignal,
+		SignalProcess:   cfg.SignalProcess,
+		Hooks:           convertHooks(cfg.Hooks, logger),
 		KSMConfig:       ksmConfig,
 		AuthMethod:      cfg.AuthMethod,
+		CacheDir:        cfg.CacheDir,
+		CacheMaxAge:     cacheMaxAge,
+		SecretsAPI:      cfg.SecretsAPI,
+		OutputRoots:     cfg.OutputRoots,
 		Logger:          logger,
+
+		AllowUnsafeTemplateFuncs: allowUnsafe,
+
+		// Only the long-running sidecar keeps K8s Secrets in sync
+		K8sSecretRotation:  cfg.K8sSecretRotation && agentMode == sidecar.ModeSidecar,
+		K8sSecretNamespace: cfg.K8sSecretNamespace,
+		K8sSecretMode:      cfg.K8sSecretMode,
 	}
 
 	// Create and run agent
@@ -205,6 +360,30 @@ func main() {
 	logger.Info("agent completed successfully")
 }
 
+// convertHooks converts hook entries to agent hooks, parsing timeouts
+func convertHooks(entries []hookEntry, logger *zap.Logger) []sidecar.HookConfig {
+	if len(entries) == 0 {
+		return nil
+	}
+	hooks := make([]sidecar.HookConfig, len(entries))
+	for i, h := range entries {
+		hooks[i] = sidecar.HookConfig{
+			Name:    h.Name,
+			URL:     h.URL,
+			Command: h.Command,
+			Retries: h.Retries,
+		}
+		if h.Timeout != "" {
+			timeout, err := time.ParseDuration(h.Timeout)
+			if err != nil {
+				logger.Fatal("invalid hook timeout", zap.String("hook", h.Name), zap.String("timeout", h.Timeout), zap.Error(err))
+			}
+			hooks[i].Timeout = timeout
+		}
+	}
+	return hooks
+}
+
 // loadCustomCACert loads custom CA certificate for corporate proxies/SSL inspection.
 // Supports environments like Zscaler, Palo Alto, Cisco Umbrella, etc.
 func loadCustomCACert(logger *zap.Logger) error {
@@ -2,15 +2,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"time"
 
+	"github.com/keeper-security/keeper-k8s-injector/pkg/apis/v1alpha1"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/controller"
 	"github.com/keeper-security/keeper-k8s-injector/pkg/webhook"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	ctrlzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
 	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -23,6 +32,9 @@ var (
 
 func init() {
 	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme) // Workloads restarted on Keeper changes
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme) // Roles granting K8s Secret rotation
 }
 
 func main() {
@@ -34,6 +46,10 @@ func main() {
 		sidecarImage         string
 		logLevel             string
 		logFormat            string
+		allowUnsafeTemplates bool
+		restartInterval      time.Duration
+		keeperSecrets        bool
+		secretRotation       bool
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
@@ -43,6 +59,10 @@ func main() {
 	flag.StringVar(&sidecarImage, "sidecar-image", "keeper/injector-sidecar:latest", "Image for the sidecar container.")
 	flag.StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error).")
 	flag.StringVar(&logFormat, "log-format", "json", "Log format (json, console).")
+	flag.BoolVar(&allowUnsafeTemplates, "allow-unsafe-template-funcs", false, "Allow template functions that read the sidecar environment or network (env, expandenv, getHostByName).")
+	flag.DurationVar(&restartInterval, "restart-check-interval", webhook.DefaultRestartCheckInterval, "How often workloads with keeper.security/restart-on-change are checked for changed Keeper data (0 disables).")
+	flag.BoolVar(&keeperSecrets, "enable-keepersecret-controller", true, "Reconcile KeeperSecret resources into Kubernetes Secrets (skipped with a warning when the KeeperSecret CRD is not installed).")
+	flag.BoolVar(&secretRotation, "enable-k8s-secret-rotation", false, "Create namespace-scoped Roles that let sidecars update their K8s Secrets (keeper.security/k8s-secret-rotation). Requires create, update and delete on roles and rolebindings.")
 	flag.Parse()
 
 	// Set up logger
@@ -78,6 +98,9 @@ func main() {
 		MemoryRequest:          getEnvOrDefault("KEEPER_SIDECAR_MEMORY_REQUEST", "32Mi"),
 		CPULimit:               getEnvOrDefault("KEEPER_SIDECAR_CPU_LIMIT", "50m"),
 		MemoryLimit:            getEnvOrDefault("KEEPER_SIDECAR_MEMORY_LIMIT", "64Mi"),
+
+		AllowUnsafeTemplateFuncs: allowUnsafeTemplates,
+		K8sSecretRotationRBAC:    secretRotation,
 	}
 
 	// Create decoder for webhook
@@ -90,6 +113,36 @@ func main() {
 	}
 	mgr.GetWebhookServer().Register("/mutate-pods", &ctrlwebhook.Admission{Handler: mutator})
 
+	// Watch injection profiles from startup so admission reads them from the cache
+	for _, obj := range []client.Object{&v1alpha1.KeeperInjectionProfile{}, &v1alpha1.ClusterKeeperInjectionProfile{}} {
+		if _, err := mgr.GetCache().GetInformer(context.Background(), obj); err != nil {
+			logger.Warn("injection profiles unavailable (is the CRD installed?)", zap.Error(err))
+		}
+	}
+
+	// Restart workloads whose Keeper data changed
+	if restartInterval > 0 {
+		if err := mgr.Add(webhook.NewRolloutRestarter(mgr.GetClient(), logger, restartInterval)); err != nil {
+			logger.Fatal("unable to set up restart controller", zap.Error(err))
+		}
+	}
+
+	// Give the env var Secrets of bare pods an owner
+	if err := mgr.Add(webhook.NewEnvSecretCollector(mgr.GetClient(), logger)); err != nil {
+		logger.Fatal("unable to set up env var Secret collector", zap.Error(err))
+	}
+
+	// Reconcile KeeperSecret resources. Helm does not install CRDs on upgrade,
+	// so skip the controller instead of failing when the CRD is missing.
+	if keeperSecrets && !keeperSecretCRDInstalled(mgr.GetRESTMapper(), logger) {
+		keeperSecrets = false
+	}
+	if keeperSecrets {
+		if err := controller.NewKeeperSecretReconciler(mgr.GetClient(), mgr.GetScheme(), logger).SetupWithManager(mgr); err != nil {
+			logger.Fatal("unable to set up KeeperSecret controller", zap.Error(err))
+		}
+	}
+
 	// Add health checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		logger.Fatal("unable to set up health check", zap.Error(err))
@@ -104,6 +157,21 @@ func main() {
 	}
 }
 
+// keeperSecretCRDInstalled reports whether the API server serves KeeperSecret
+func keeperSecretCRDInstalled(mapper meta.RESTMapper, logger *zap.Logger) bool {
+	gk := schema.GroupKind{Group: v1alpha1.GroupVersion.Group, Kind: "KeeperSecret"}
+	_, err := mapper.RESTMapping(gk, v1alpha1.GroupVersion.Version)
+	switch {
+	case meta.IsNoMatchError(err):
+		logger.Warn("KeeperSecret CRD is not installed, skipping the KeeperSecret controller; apply the chart's crds/ to enable it")
+		return false
+	case err != nil:
+		logger.Warn("unable to look up the KeeperSecret CRD, skipping the KeeperSecret controller", zap.Error(err))
+		return false
+	}
+	return true
+}
+
 func setupLogger(level, format string) *zap.Logger {
 	var zapLevel zapcore.Level
 	switch level {
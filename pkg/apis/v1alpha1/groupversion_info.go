@@ -0,0 +1,18 @@
+// Package v1alpha1 contains the keeper.security/v1alpha1 API types.
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of the Keeper custom resources
+	GroupVersion = schema.GroupVersion{Group: "keeper.security", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the types with a scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types of this group version to a scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,123 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KeeperInjectionProfileSpec holds injection defaults for pods that reference
+// the profile with keeper.security/profile. Pod annotations override them.
+type KeeperInjectionProfileSpec struct {
+	// KSMConfig is the name of the Secret holding the KSM configuration (keeper.security/ksm-config)
+	// +optional
+	KSMConfig string `json:"ksmConfig,omitempty"`
+
+	// AuthMethod is "secret" or "oidc" (keeper.security/auth-method)
+	// +optional
+	AuthMethod string `json:"authMethod,omitempty"`
+
+	// Secrets are record titles or UIDs written as JSON files (keeper.security/secrets)
+	// +optional
+	Secrets []string `json:"secrets,omitempty"`
+
+	// Folders are synced like keeper.security/folder-<name> annotations
+	// +optional
+	Folders []KeeperInjectionProfileFolder `json:"folders,omitempty"`
+
+	// FolderFormat is the output format of folder records (keeper.security/folder-format)
+	// +optional
+	FolderFormat string `json:"folderFormat,omitempty"`
+
+	// FolderFilename is the file name pattern of folder records (keeper.security/folder-filename)
+	// +optional
+	FolderFilename string `json:"folderFilename,omitempty"`
+
+	// Config is a keeper.security/config YAML document
+	// +optional
+	Config string `json:"config,omitempty"`
+
+	// RefreshInterval is how often the sidecar refreshes secrets (keeper.security/refresh-interval)
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+
+	// Signal is sent to the app on refresh (keeper.security/signal)
+	// +optional
+	Signal string `json:"signal,omitempty"`
+
+	// SignalContainer receives the signal (keeper.security/signal-container)
+	// +optional
+	SignalContainer string `json:"signalContainer,omitempty"`
+
+	// Resources of the injected init and sidecar containers (default: the webhook's settings)
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Annotations are further keeper.security/* annotations used as defaults
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// KeeperInjectionProfileFolder is a Keeper folder synced into the pod
+type KeeperInjectionProfileFolder struct {
+	// Name is the folder alias (lowercase letters, digits and '-')
+	Name string `json:"name"`
+
+	// Path is the Keeper folder path
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// UID is the Keeper folder UID, used instead of Path
+	// +optional
+	UID string `json:"uid,omitempty"`
+
+	// OutputPath is the directory the records are written to (default: /keeper/secrets/<name>)
+	// +optional
+	OutputPath string `json:"outputPath,omitempty"`
+}
+
+// KeeperInjectionProfile holds injection defaults for pods in its namespace.
+//
+// +kubebuilder:object:root=true
+type KeeperInjectionProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KeeperInjectionProfileSpec `json:"spec,omitempty"`
+}
+
+// KeeperInjectionProfileList is a list of KeeperInjectionProfiles
+//
+// +kubebuilder:object:root=true
+type KeeperInjectionProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KeeperInjectionProfile `json:"items"`
+}
+
+// ClusterKeeperInjectionProfile holds injection defaults for pods in any
+// namespace. A KeeperInjectionProfile of the same name takes its place.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+type ClusterKeeperInjectionProfile struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KeeperInjectionProfileSpec `json:"spec,omitempty"`
+}
+
+// ClusterKeeperInjectionProfileList is a list of ClusterKeeperInjectionProfiles
+//
+// +kubebuilder:object:root=true
+type ClusterKeeperInjectionProfileList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterKeeperInjectionProfile `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(
+		&KeeperInjectionProfile{}, &KeeperInjectionProfileList{},
+		&ClusterKeeperInjectionProfile{}, &ClusterKeeperInjectionProfileList{},
+	)
+}
@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types and reasons reported on a KeeperSecret
+const (
+	// ConditionReady is true when the Secret holds the current Keeper data
+	ConditionReady = "Ready"
+
+	ReasonSynced         = "Synced"         // Secret written from Keeper
+	ReasonInvalidSpec    = "InvalidSpec"    // Records are missing or ambiguous
+	ReasonAuthFailed     = "AuthFailed"     // KSM config missing or rejected
+	ReasonFetchFailed    = "FetchFailed"    // A record or notation could not be read
+	ReasonSecretConflict = "SecretConflict" // Target Secret exists and is not owned by this KeeperSecret
+	ReasonWriteFailed    = "WriteFailed"    // Creating or updating the Secret failed
+)
+
+// KeeperSecretSpec describes the Secret to build from Keeper records
+type KeeperSecretSpec struct {
+	// KSMConfig is the name of the Secret (key "config") holding the KSM configuration
+	KSMConfig string `json:"ksmConfig"`
+
+	// Records are the Keeper records whose fields become Secret keys
+	Records []KeeperSecretRecord `json:"records"`
+
+	// Target describes the generated Secret
+	// +optional
+	Target KeeperSecretTarget `json:"target,omitempty"`
+
+	// RefreshInterval is how often Keeper is read again (default: 5m)
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// KeeperSecretRecord selects the data of one Keeper record
+type KeeperSecretRecord struct {
+	// Name is the record title or UID
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Notation is a Keeper notation (keeper://...) used instead of Name; its
+	// value is stored under the key "value" unless Keys renames it
+	// +optional
+	Notation string `json:"notation,omitempty"`
+
+	// Fields limits the record fields copied into the Secret
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+
+	// Keys maps Keeper fields to Secret keys; overrides Fields
+	// +optional
+	Keys map[string]string `json:"keys,omitempty"`
+}
+
+// KeeperSecretTarget describes the generated Secret
+type KeeperSecretTarget struct {
+	// Name of the Secret (default: the KeeperSecret's name)
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Type of the Secret (default: Opaque)
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Labels added to the Secret
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations added to the Secret
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// KeeperSecretStatus reports the last reconciliation
+type KeeperSecretStatus struct {
+	// ObservedGeneration is the generation the status refers to
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// SecretName is the name of the generated Secret
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// LastSyncTime is when the Secret was last written from Keeper
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Conditions describe the state of the KeeperSecret
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// KeeperSecret is reconciled into a Kubernetes Secret holding Keeper record data.
+// The Secret is owned by the KeeperSecret, not by any pod.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+type KeeperSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KeeperSecretSpec   `json:"spec,omitempty"`
+	Status KeeperSecretStatus `json:"status,omitempty"`
+}
+
+// KeeperSecretList is a list of KeeperSecrets
+//
+// +kubebuilder:object:root=true
+type KeeperSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KeeperSecret `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KeeperSecret{}, &KeeperSecretList{})
+}
@@ -0,0 +1,316 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperSecret) DeepCopyInto(out *KeeperSecret) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy returns a deep copy of the KeeperSecret
+func (in *KeeperSecret) DeepCopy() *KeeperSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *KeeperSecret) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperSecretList) DeepCopyInto(out *KeeperSecretList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]KeeperSecret, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the KeeperSecretList
+func (in *KeeperSecretList) DeepCopy() *KeeperSecretList {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperSecretList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *KeeperSecretList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperSecretSpec) DeepCopyInto(out *KeeperSecretSpec) {
+	*out = *in
+	if in.Records != nil {
+		out.Records = make([]KeeperSecretRecord, len(in.Records))
+		for i := range in.Records {
+			in.Records[i].DeepCopyInto(&out.Records[i])
+		}
+	}
+	in.Target.DeepCopyInto(&out.Target)
+	if in.RefreshInterval != nil {
+		out.RefreshInterval = new(metav1.Duration)
+		*out.RefreshInterval = *in.RefreshInterval
+	}
+}
+
+// DeepCopy returns a deep copy of the KeeperSecretSpec
+func (in *KeeperSecretSpec) DeepCopy() *KeeperSecretSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperSecretSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperSecretRecord) DeepCopyInto(out *KeeperSecretRecord) {
+	*out = *in
+	if in.Fields != nil {
+		out.Fields = make([]string, len(in.Fields))
+		copy(out.Fields, in.Fields)
+	}
+	if in.Keys != nil {
+		out.Keys = make(map[string]string, len(in.Keys))
+		for k, v := range in.Keys {
+			out.Keys[k] = v
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the KeeperSecretRecord
+func (in *KeeperSecretRecord) DeepCopy() *KeeperSecretRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperSecretRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperSecretTarget) DeepCopyInto(out *KeeperSecretTarget) {
+	*out = *in
+	if in.Labels != nil {
+		out.Labels = make(map[string]string, len(in.Labels))
+		for k, v := range in.Labels {
+			out.Labels[k] = v
+		}
+	}
+	if in.Annotations != nil {
+		out.Annotations = make(map[string]string, len(in.Annotations))
+		for k, v := range in.Annotations {
+			out.Annotations[k] = v
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the KeeperSecretTarget
+func (in *KeeperSecretTarget) DeepCopy() *KeeperSecretTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperSecretTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperSecretStatus) DeepCopyInto(out *KeeperSecretStatus) {
+	*out = *in
+	if in.LastSyncTime != nil {
+		out.LastSyncTime = in.LastSyncTime.DeepCopy()
+	}
+	if in.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(in.Conditions))
+		for i := range in.Conditions {
+			in.Conditions[i].DeepCopyInto(&out.Conditions[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the KeeperSecretStatus
+func (in *KeeperSecretStatus) DeepCopy() *KeeperSecretStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperSecretStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperInjectionProfileSpec) DeepCopyInto(out *KeeperInjectionProfileSpec) {
+	*out = *in
+	if in.Secrets != nil {
+		out.Secrets = make([]string, len(in.Secrets))
+		copy(out.Secrets, in.Secrets)
+	}
+	if in.Folders != nil {
+		out.Folders = make([]KeeperInjectionProfileFolder, len(in.Folders))
+		copy(out.Folders, in.Folders)
+	}
+	if in.RefreshInterval != nil {
+		out.RefreshInterval = new(metav1.Duration)
+		*out.RefreshInterval = *in.RefreshInterval
+	}
+	if in.Resources != nil {
+		out.Resources = in.Resources.DeepCopy()
+	}
+	if in.Annotations != nil {
+		out.Annotations = make(map[string]string, len(in.Annotations))
+		for k, v := range in.Annotations {
+			out.Annotations[k] = v
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the KeeperInjectionProfileSpec
+func (in *KeeperInjectionProfileSpec) DeepCopy() *KeeperInjectionProfileSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperInjectionProfileSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperInjectionProfile) DeepCopyInto(out *KeeperInjectionProfile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy returns a deep copy of the KeeperInjectionProfile
+func (in *KeeperInjectionProfile) DeepCopy() *KeeperInjectionProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperInjectionProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *KeeperInjectionProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *KeeperInjectionProfileList) DeepCopyInto(out *KeeperInjectionProfileList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]KeeperInjectionProfile, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the KeeperInjectionProfileList
+func (in *KeeperInjectionProfileList) DeepCopy() *KeeperInjectionProfileList {
+	if in == nil {
+		return nil
+	}
+	out := new(KeeperInjectionProfileList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *KeeperInjectionProfileList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *ClusterKeeperInjectionProfile) DeepCopyInto(out *ClusterKeeperInjectionProfile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy returns a deep copy of the ClusterKeeperInjectionProfile
+func (in *ClusterKeeperInjectionProfile) DeepCopy() *ClusterKeeperInjectionProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterKeeperInjectionProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *ClusterKeeperInjectionProfile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out
+func (in *ClusterKeeperInjectionProfileList) DeepCopyInto(out *ClusterKeeperInjectionProfileList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]ClusterKeeperInjectionProfile, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the ClusterKeeperInjectionProfileList
+func (in *ClusterKeeperInjectionProfileList) DeepCopy() *ClusterKeeperInjectionProfileList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterKeeperInjectionProfileList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *ClusterKeeperInjectionProfileList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
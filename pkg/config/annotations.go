@@ -3,10 +3,19 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 const (
@@ -20,22 +29,55 @@ const (
 	AnnotationConfig     = AnnotationPrefix + "config"
 	AnnotationKSMConfig = AnnotationPrefix + "ksm-config"
 	AnnotationAuthMethod = AnnotationPrefix + "auth-method"
+	AnnotationProfile    = AnnotationPrefix + "profile" // KeeperInjectionProfile supplying defaults
 
 	// Folder annotations
-	AnnotationFolder     = AnnotationPrefix + "folder"      // Folder path (e.g., "Production/Databases")
-	AnnotationFolderUID  = AnnotationPrefix + "folder-uid"  // Folder UID (direct reference)
-	AnnotationFolderPath = AnnotationPrefix + "folder-path" // Output path for folder secrets
+	AnnotationFolder          = AnnotationPrefix + "folder"           // Folder path (e.g., "Production/Databases")
+	AnnotationFolderUID       = AnnotationPrefix + "folder-uid"       // Folder UID (direct reference)
+	AnnotationFolderPath      = AnnotationPrefix + "folder-path"      // Output path for folder secrets
+	AnnotationFolderPrune     = AnnotationPrefix + "folder-prune"     // Remove files of records no longer in the folder (default: "true")
+	AnnotationFolderFormat    = AnnotationPrefix + "folder-format"    // Output format of folder records: json, env, yaml, properties, ini
+	AnnotationFolderFilename  = AnnotationPrefix + "folder-filename"  // File name pattern, e.g. "{{ .Type }}/{{ .Title | lower }}.env"
+	AnnotationFolderRecursive = AnnotationPrefix + "folder-recursive" // Include subfolders, mirrored as subdirectories (default: "false")
+
+	// AnnotationFolderAliasPrefix declares additional folders:
+	// keeper.security/folder-<alias>: "Production/DB:/keeper/secrets/db" (or "uid:<folder UID>:/path").
+	// Options per alias: keeper.security/folder-<alias>.<prune|format|filename|recursive>
+	AnnotationFolderAliasPrefix = AnnotationPrefix + "folder-"
 
 	// Behavior annotations
 	AnnotationFailOnError     = AnnotationPrefix + "fail-on-error"
 	AnnotationRefreshInterval = AnnotationPrefix + "refresh-interval"
 	AnnotationInitOnly        = AnnotationPrefix + "init-only"
 	AnnotationSignal          = AnnotationPrefix + "signal"
+	AnnotationSignalContainer = AnnotationPrefix + "signal-container" // Container that receives the refresh signal
+	AnnotationSignalProcess   = AnnotationPrefix + "signal-process"   // Process name to signal inside the target container
 	AnnotationStrictLookup    = AnnotationPrefix + "strict-lookup"
+	AnnotationSecretsAPI      = AnnotationPrefix + "secrets-api"       // Serve secrets on a Unix socket in the secrets volume
+	AnnotationRestartOnChange = AnnotationPrefix + "restart-on-change" // Roll the owning workload when its Keeper data changes
+
+	// Container targeting annotations (comma-separated container names)
+	AnnotationContainers        = AnnotationPrefix + "containers"         // App containers that receive secrets (default: all)
+	AnnotationExcludeContainers = AnnotationPrefix + "exclude-containers" // App containers that never receive secrets
+	AnnotationInitContainers    = AnnotationPrefix + "init-containers"    // App init containers that mount the secrets volume (implies init-first)
+	AnnotationInitFirst         = AnnotationPrefix + "init-first"         // Run keeper-secrets-init before the app's init containers
+
+	// Post-refresh hook annotations (run by the sidecar when secret content changes)
+	AnnotationReloadURL     = AnnotationPrefix + "reload-url"     // HTTP POST to a localhost endpoint (e.g., "http://127.0.0.1:9000/-/reload")
+	AnnotationReloadCommand = AnnotationPrefix + "reload-command" // Command executed inside the sidecar (space-separated)
+	AnnotationHookTimeout   = AnnotationPrefix + "hook-timeout"   // Per-attempt hook timeout (default: "10s")
+	AnnotationHookRetries   = AnnotationPrefix + "hook-retries"   // Retries after a failed attempt (default: 2)
+
+	// Persistent last-known-good cache annotations
+	AnnotationPersistentCache = AnnotationPrefix + "persistent-cache" // Keep an encrypted cache on a node-local emptyDir (lost when the pod is re-created)
+	AnnotationCacheVolume     = AnnotationPrefix + "cache-volume"     // Existing pod volume (e.g., a PVC) for the cache (implies persistent-cache)
+	AnnotationCacheMaxAge     = AnnotationPrefix + "cache-max-age"    // Maximum age of cached values (default: "24h")
 
 	// Environment variable injection annotations
 	AnnotationInjectEnvVars = AnnotationPrefix + "inject-env-vars" // Inject secrets as env vars instead of files
 	AnnotationEnvPrefix     = AnnotationPrefix + "env-prefix"      // Optional prefix for env var names (e.g., "DB_")
+	AnnotationEnvSource     = AnnotationPrefix + "env-source"      // Where env var values live: "secret" (default), "literal" or "exec"
+	AnnotationExecRestart   = AnnotationPrefix + "exec-restart"    // Exec mode: restart the app process when values change (default: "false")
 
 	// Kubernetes Secret injection annotations (v0.9.0)
 	AnnotationInjectAsK8sSecret  = AnnotationPrefix + "inject-as-k8s-secret" // Enable K8s Secret injection
@@ -60,10 +102,21 @@ const (
 
 	// Default values
 	DefaultSecretsPath     = "/keeper/secrets"
+	DefaultTemplatesPath   = "/keeper/templates"
+	DefaultCachePath       = "/keeper/cache"
 	DefaultRefreshInterval = "5m"
 	DefaultFailOnError     = "true"
 	DefaultInitOnly        = "false"
 	DefaultStrictLookup    = "false"
+	DefaultHookTimeout     = "10s"
+	DefaultHookRetries     = 2
+
+	// EnvSourceSecret stores env var values in a managed Secret referenced with secretKeyRef
+	EnvSourceSecret = "secret"
+	// EnvSourceLiteral writes env var values into the pod spec (the behavior before v0.10.0)
+	EnvSourceLiteral = "literal"
+	// EnvSourceExec loads env var values in the app container at process start (exec wrapper)
+	EnvSourceExec = "exec"
 
 	// KeeperNotationPrefix is the URI scheme for Keeper notation
 	KeeperNotationPrefix = "keeper://"
@@ -81,6 +134,8 @@ type SecretRef struct {
 	Format string
 	// Template is a Go template string for custom formatting
 	Template string
+	// TemplateSource loads the template from a ConfigMap key instead of Template
+	TemplateSource *TemplateSource
 	// Notation is the full Keeper notation string (e.g., keeper://UID/field/password)
 	// If set, this takes precedence over Name/Fields
 	Notation string
@@ -94,11 +149,63 @@ type SecretRef struct {
 	// EnvVarPrefix is an optional prefix for env var names (e.g., "DB_" → DB_PASSWORD)
 	EnvVarPrefix string
 
+	// Containers limits this secret to these app containers (empty = all targeted containers)
+	Containers []string
+
 	// K8s Secret injection (per-secret, v0.9.0)
 	InjectAsK8sSecret bool              // Enable K8s Secret injection for this secret
 	K8sSecretName     string            // K8s Secret name for this secret
 	K8sSecretKeys     map[string]string // Keeper field → Secret key mapping
 	K8sSecretType     string            // Secret type (Opaque, kubernetes.io/tls, etc.)
+
+	// Hooks run after this secret's content changes
+	Hooks []HookRef
+}
+
+// HookRef represents an action the sidecar runs after secret content changes.
+// Exactly one of URL or Command is set.
+type HookRef struct {
+	// Name identifies the hook in logs and metrics
+	Name string `yaml:"name,omitempty"`
+	// URL is a localhost HTTP endpoint that receives a POST
+	URL string `yaml:"url,omitempty"`
+	// Command is executed inside the sidecar container
+	Command []string `yaml:"command,omitempty"`
+	// Timeout per attempt (e.g., "10s")
+	Timeout string `yaml:"timeout,omitempty"`
+	// Retries after a failed attempt
+	Retries *int `yaml:"retries,omitempty"`
+}
+
+// TemplateRef represents a file rendered from several Keeper records.
+// Records maps the alias used in the template ({{ .db.password }}) to a record title or UID.
+type TemplateRef struct {
+	// Name identifies the output in logs, metrics and hooks (default: file name without extension)
+	Name string `yaml:"name,omitempty"`
+	// Path is where to write the rendered file
+	Path string `yaml:"path"`
+	// Records maps aliases to record titles or UIDs
+	Records map[string]string `yaml:"records"`
+	// Template is a Go template string; each alias exposes its record's fields
+	Template string `yaml:"template,omitempty"`
+	// TemplateSource loads the template from a ConfigMap key instead of Template
+	TemplateSource *TemplateSource `yaml:"templateRef,omitempty"`
+	// Hooks run after this output's content changes
+	Hooks []HookRef `yaml:"hooks,omitempty"`
+}
+
+// TemplateSource references a template stored in a ConfigMap key.
+// The ConfigMap is mounted into the init and sidecar containers under DefaultTemplatesPath.
+type TemplateSource struct {
+	// ConfigMap is the ConfigMap name (same namespace as the pod)
+	ConfigMap string `yaml:"configMap"`
+	// Key is the ConfigMap key holding the template
+	Key string `yaml:"key"`
+}
+
+// FilePath returns where the template is mounted inside the agent containers
+func (t *TemplateSource) FilePath() string {
+	return filepath.Join(DefaultTemplatesPath, t.ConfigMap, t.Key)
 }
 
 // FolderRef represents a reference to a folder in Keeper
@@ -109,10 +216,42 @@ type FolderRef struct {
 	FolderPath string
 	// OutputPath is where to write the secrets (default: /keeper/secrets)
 	OutputPath string
+	// Prune removes files of records that left the folder (default: true)
+	Prune bool
+	// Format of each record file: json (default), env, yaml, properties, ini
+	Format string
+	// Template is a Go template rendered per record (overrides Format)
+	Template string
+	// FilenamePattern is a Go template for each record's file name relative to
+	// OutputPath, with .Title, .Type and .UID (default: "{{ .Title }}.<format>")
+	FilenamePattern string
+	// Recursive includes subfolders; their records are written to matching subdirectories
+	Recursive bool
+	// Filter selects which records of the folder are synced
+	Filter RecordFilter
 
 	// K8s Secret injection (per-folder, v0.9.0)
 	InjectAsK8sSecret   bool   // Enable K8s Secret injection for this folder
 	K8sSecretNamePrefix string // Prefix for generated Secret names (e.g., "api-" → "api-stripe")
+
+	alias string // keeper.security/folder-<alias> that declared the folder
+}
+
+// RecordFilter selects folder records. All set conditions must match.
+type RecordFilter struct {
+	// Types are the record types to include (e.g., login, databaseCredentials)
+	Types []string `yaml:"types,omitempty"`
+	// Title is a glob matched against the record title (e.g., "stripe-*")
+	Title string `yaml:"title,omitempty"`
+	// TitleRegex is a regular expression matched against the record title
+	TitleRegex string `yaml:"titleRegex,omitempty"`
+	// Labels are field labels and the values the record must have (e.g., env: prod)
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// IsEmpty reports whether the filter matches every record
+func (f RecordFilter) IsEmpty() bool {
+	return len(f.Types) == 0 && f.Title == "" && f.TitleRegex == "" && len(f.Labels) == 0
 }
 
 // InjectionConfig holds the parsed injection configuration for a pod
@@ -123,6 +262,8 @@ type InjectionConfig struct {
 	Secrets []SecretRef
 	// Folders to fetch all secrets from
 	Folders []FolderRef
+	// Templates render one file from several records
+	Templates []TemplateRef
 	// AuthSecretName is the name of the K8s secret containing KSM credentials
 	AuthSecretName string
 	// AuthSecretNamespace is the namespace of the auth secret (defaults to pod namespace)
@@ -137,6 +278,18 @@ type InjectionConfig struct {
 	FailOnError bool
 	// Signal to send to app container on secret refresh (e.g., "SIGHUP")
 	Signal string
+	// SignalContainer is the container whose process receives Signal (default: first targeted app container)
+	SignalContainer string
+	// SignalProcess is the process name to signal (default: SignalContainer name)
+	SignalProcess string
+	// Containers limits injection to these app containers (empty = all)
+	Containers []string
+	// ExcludeContainers are app containers that never receive secrets
+	ExcludeContainers []string
+	// InitContainers are app init containers that mount the secrets volume
+	InitContainers []string
+	// InitFirst runs keeper-secrets-init before the app's init containers
+	InitFirst bool
 	// CACertSecret is the name of the K8s Secret containing custom CA certificate
 	CACertSecret string
 	// CACertConfigMap is the name of the K8s ConfigMap containing custom CA certificate
@@ -145,6 +298,20 @@ type InjectionConfig struct {
 	CACertKey string
 	// StrictLookup if true, fail on duplicate title matches
 	StrictLookup bool
+	// Hooks run after any secret's content changes
+	Hooks []HookRef
+	// PersistentCache keeps the last-known-good cache encrypted on disk across restarts
+	PersistentCache bool
+	// CacheVolume stores the persistent cache on an existing pod volume instead of an emptyDir
+	CacheVolume string
+	// CacheMaxAge is the maximum age of cached values (e.g., "24h")
+	CacheMaxAge string
+	// SecretsAPI enables the sidecar's local secrets API on a Unix socket
+	SecretsAPI bool
+	// RestartOnChange lets the webhook controller restart the workload (set on its pod template) when its Keeper data changes
+	RestartOnChange bool
+	// Resources of the injected containers, set by an injection profile (default: webhook settings)
+	Resources *corev1.ResourceRequirements
 
 	// Cloud Secrets Provider configuration
 	AWSSecretID     string // AWS Secrets Manager secret ID/ARN
@@ -156,6 +323,8 @@ type InjectionConfig struct {
 	// Environment variable injection configuration
 	InjectEnvVars bool   // Global flag to inject all secrets as env vars
 	EnvPrefix     string // Global prefix for all env var names
+	EnvSource     string // EnvSourceSecret (managed Secret + secretKeyRef), EnvSourceLiteral (values in the pod spec) or EnvSourceExec
+	ExecRestart   bool   // Exec mode: restart the app process when values change
 
 	// Kubernetes Secret injection configuration (v0.9.0)
 	InjectAsK8sSecret  bool   // Global flag to enable K8s Secret injection
@@ -169,7 +338,11 @@ type InjectionConfig struct {
 
 // ParseAnnotations extracts injection configuration from pod annotations
 func ParseAnnotations(pod *corev1.Pod) (*InjectionConfig, error) {
-	annotations := pod.Annotations
+	return parseAnnotations(pod.Annotations)
+}
+
+// parseAnnotations extracts injection configuration from keeper.security/* annotations
+func parseAnnotations(annotations map[string]string) (*InjectionConfig, error) {
 	if annotations == nil {
 		return &InjectionConfig{Enabled: false}, nil
 	}
@@ -208,11 +381,88 @@ func ParseAnnotations(pod *corev1.Pod) (*InjectionConfig, error) {
 		config.InitOnly = strings.ToLower(initOnly) == "true"
 	}
 	if signal, ok := annotations[AnnotationSignal]; ok {
-		config.Signal = signal
+		config.Signal = strings.ToUpper(strings.TrimSpace(signal))
+	}
+	if signalContainer, ok := annotations[AnnotationSignalContainer]; ok {
+		config.SignalContainer = strings.TrimSpace(signalContainer)
+	}
+	if signalProcess, ok := annotations[AnnotationSignalProcess]; ok {
+		config.SignalProcess = strings.TrimSpace(signalProcess)
+	}
+	if containers, ok := annotations[AnnotationContainers]; ok {
+		config.Containers = parseNameList(containers)
+	}
+	if excludeContainers, ok := annotations[AnnotationExcludeContainers]; ok {
+		config.ExcludeContainers = parseNameList(excludeContainers)
+	}
+	if initContainers, ok := annotations[AnnotationInitContainers]; ok {
+		config.InitContainers = parseNameList(initContainers)
+	}
+	config.InitFirst = len(config.InitContainers) > 0
+	if initFirst, ok := annotations[AnnotationInitFirst]; ok {
+		config.InitFirst = strings.ToLower(initFirst) == "true"
+		if !config.InitFirst && len(config.InitContainers) > 0 {
+			return nil, fmt.Errorf("%s needs the secrets before the app's init containers run and cannot be used with %s=false",
+				AnnotationInitContainers, AnnotationInitFirst)
+		}
 	}
 	if strictLookup, ok := annotations[AnnotationStrictLookup]; ok {
 		config.StrictLookup = strings.ToLower(strictLookup) == "true"
 	}
+	if secretsAPI, ok := annotations[AnnotationSecretsAPI]; ok {
+		config.SecretsAPI = strings.ToLower(secretsAPI) == "true"
+	}
+	if restartOnChange, ok := annotations[AnnotationRestartOnChange]; ok {
+		config.RestartOnChange = strings.ToLower(restartOnChange) == "true"
+	}
+
+	// Parse persistent cache annotations
+	if persistentCache, ok := annotations[AnnotationPersistentCache]; ok {
+		config.PersistentCache = strings.ToLower(persistentCache) == "true"
+	}
+	if cacheVolume, ok := annotations[AnnotationCacheVolume]; ok {
+		config.CacheVolume = strings.TrimSpace(cacheVolume)
+		config.PersistentCache = true
+	}
+	if cacheMaxAge, ok := annotations[AnnotationCacheMaxAge]; ok {
+		d, err := time.ParseDuration(strings.TrimSpace(cacheMaxAge))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive duration", AnnotationCacheMaxAge, cacheMaxAge)
+		}
+		config.CacheMaxAge = strings.TrimSpace(cacheMaxAge)
+	}
+	if config.CacheVolume != "" {
+		if errs := validation.IsDNS1123Label(config.CacheVolume); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s %q: %s", AnnotationCacheVolume, config.CacheVolume, strings.Join(errs, "; "))
+		}
+	}
+
+	// Parse post-refresh hook annotations
+	hookTimeout := annotations[AnnotationHookTimeout]
+	var hookRetries *int
+	if retries, ok := annotations[AnnotationHookRetries]; ok {
+		n, err := strconv.Atoi(strings.TrimSpace(retries))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a non-negative integer", AnnotationHookRetries, retries)
+		}
+		hookRetries = &n
+	}
+	if reloadURL, ok := annotations[AnnotationReloadURL]; ok {
+		config.Hooks = append(config.Hooks, HookRef{
+			Name:    "reload-url",
+			URL:     strings.TrimSpace(reloadURL),
+			Timeout: hookTimeout,
+			Retries: hookRetries,
+		})
+	}
+	if reloadCommand, ok := annotations[AnnotationReloadCommand]; ok {
+		config.Hooks = append(config.Hooks, HookRef{
+			Name:    "reload-command",
+			Command: strings.Fields(reloadCommand),
+			Timeout: hookTimeout,
+			Retries: hookRetries,
+		})
+	}
 
 	// Parse environment variable injection annotations
 	if injectEnvVars, ok := annotations[AnnotationInjectEnvVars]; ok {
@@ -221,6 +471,16 @@ func ParseAnnotations(pod *corev1.Pod) (*InjectionConfig, error) {
 	if envPrefix, ok := annotations[AnnotationEnvPrefix]; ok {
 		config.EnvPrefix = envPrefix
 	}
+	config.EnvSource = EnvSourceSecret
+	if envSource, ok := annotations[AnnotationEnvSource]; ok {
+		config.EnvSource = strings.ToLower(strings.TrimSpace(envSource))
+		if config.EnvSource != EnvSourceSecret && config.EnvSource != EnvSourceLiteral && config.EnvSource != EnvSourceExec {
+			return nil, fmt.Errorf("invalid %s %q (valid: %s, %s, %s)", AnnotationEnvSource, envSource, EnvSourceSecret, EnvSourceLiteral, EnvSourceExec)
+		}
+	}
+	if execRestart, ok := annotations[AnnotationExecRestart]; ok {
+		config.ExecRestart = strings.ToLower(execRestart) == "true"
+	}
 
 	// Parse Kubernetes Secret injection annotations (v0.9.0)
 	if injectAsK8sSecret, ok := annotations[AnnotationInjectAsK8sSecret]; ok {
@@ -328,35 +588,68 @@ func ParseAnnotations(pod *corev1.Pod) (*InjectionConfig, error) {
 	// Parse folder annotations (folder path or folder UID)
 	folderPath, hasFolderPath := annotations[AnnotationFolder]
 	folderUID, hasFolderUID := annotations[AnnotationFolderUID]
+	folderPrune := true
+	if prune, ok := annotations[AnnotationFolderPrune]; ok {
+		folderPrune = strings.ToLower(prune) != "false"
+	}
+	folderDefaults := FolderRef{
+		Prune:           folderPrune,
+		Format:          annotations[AnnotationFolderFormat],
+		FilenamePattern: annotations[AnnotationFolderFilename],
+		Recursive:       strings.ToLower(annotations[AnnotationFolderRecursive]) == "true",
+	}
 	if hasFolderPath || hasFolderUID {
-		outputPath := DefaultSecretsPath
+		ref := folderDefaults
+		ref.FolderUID = strings.TrimSpace(folderUID)
+		ref.FolderPath = strings.TrimSpace(folderPath)
+		ref.OutputPath = DefaultSecretsPath
 		if fp, ok := annotations[AnnotationFolderPath]; ok {
-			outputPath = fp
+			ref.OutputPath = fp
 		}
-		config.Folders = append(config.Folders, FolderRef{
-			FolderUID:  strings.TrimSpace(folderUID),
-			FolderPath: strings.TrimSpace(folderPath),
-			OutputPath: outputPath,
-		})
+		config.Folders = append(config.Folders, ref)
 	}
 
+	// Parse additional folders (keeper.security/folder-<alias>)
+	aliasFolders, err := parseFolderAliases(annotations, folderDefaults)
+	if err != nil {
+		return nil, err
+	}
+	config.Folders = append(config.Folders, aliasFolders...)
+
 	// Parse secrets - Level 5: Full YAML config (escape hatch)
 	if fullConfig, ok := annotations[AnnotationConfig]; ok {
-		refs, folders, err := parseFullConfig(fullConfig)
-		if err != nil {
+		if err := parseFullConfig(fullConfig, config, folderPrune); err != nil {
 			return nil, fmt.Errorf("failed to parse %s: %w", AnnotationConfig, err)
 		}
-		config.Secrets = append(config.Secrets, refs...)
-		config.Folders = append(config.Folders, folders...)
 	}
 
 	// Validate configuration
-	if len(config.Secrets) == 0 && len(config.Folders) == 0 {
-		return nil, fmt.Errorf("injection enabled but no secrets or folders specified")
+	if len(config.Secrets) == 0 && len(config.Folders) == 0 && len(config.Templates) == 0 {
+		return nil, fmt.Errorf("injection enabled but no secrets, folders or templates specified")
 	}
 	if config.AuthSecretName == "" && config.AuthMethod == "secret" {
 		return nil, fmt.Errorf("ksm-config annotation required when using secret auth method")
 	}
+	for _, s := range config.Secrets {
+		if err := validateTemplateSource(s.Template, s.TemplateSource); err != nil {
+			return nil, fmt.Errorf("secret %q: %w", s.Name, err)
+		}
+	}
+	if err := validateTemplateRefs(config.Templates); err != nil {
+		return nil, err
+	}
+	if err := validateFolderRefs(config.Folders); err != nil {
+		return nil, err
+	}
+	if err := validateHooks(config); err != nil {
+		return nil, err
+	}
+	if config.SecretsAPI && config.InitOnly {
+		return nil, fmt.Errorf("%s requires the sidecar and cannot be used with %s", AnnotationSecretsAPI, AnnotationInitOnly)
+	}
+	if config.Signal != "" && !IsSupportedSignal(config.Signal) {
+		return nil, fmt.Errorf("unsupported signal %q in %s (supported: %s)", config.Signal, AnnotationSignal, strings.Join(SupportedSignals, ", "))
+	}
 
 	return config, nil
 }
@@ -535,10 +828,100 @@ func parseFileAnnotation(name, value string) SecretRef {
 	return ref
 }
 
+// reservedFolderAliases are folder-* annotations that are not aliases
+var reservedFolderAliases = map[string]bool{
+	"uid": true, "path": true, "prune": true, "format": true, "filename": true, "recursive": true,
+}
+
+// folderAliasPattern matches folder aliases (DNS label)
+var folderAliasPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// parseFolderAliases parses keeper.security/folder-<alias> annotations and their
+// keeper.security/folder-<alias>.<option> overrides, in alias order.
+// The pod-wide folder-* options are the defaults.
+func parseFolderAliases(annotations map[string]string, defaults FolderRef) ([]FolderRef, error) {
+	refs := make(map[string]*FolderRef)
+	options := make(map[string]map[string]string)
+	for key, value := range annotations {
+		name, ok := strings.CutPrefix(key, AnnotationFolderAliasPrefix)
+		if !ok || reservedFolderAliases[name] {
+			continue
+		}
+		alias, option, hasOption := strings.Cut(name, ".")
+		if !folderAliasPattern.MatchString(alias) {
+			return nil, fmt.Errorf("invalid folder alias %q in %s (lowercase letters, digits and '-')", alias, key)
+		}
+		if hasOption {
+			if options[alias] == nil {
+				options[alias] = make(map[string]string)
+			}
+			options[alias][option] = value
+			continue
+		}
+
+		ref := defaults
+		folder, outputPath := value, ""
+		if i := strings.LastIndex(value, ":/"); i >= 0 {
+			folder, outputPath = value[:i], value[i+1:]
+		}
+		folder = strings.TrimSpace(folder)
+		if uid, isUID := strings.CutPrefix(folder, "uid:"); isUID {
+			ref.FolderUID = strings.TrimSpace(uid)
+		} else {
+			ref.FolderPath = folder
+		}
+		if ref.FolderUID == "" && ref.FolderPath == "" {
+			return nil, fmt.Errorf("%s: folder path or uid is required", key)
+		}
+		ref.OutputPath = strings.TrimSpace(outputPath)
+		if ref.OutputPath == "" {
+			ref.OutputPath = DefaultSecretsPath + "/" + alias
+		}
+		ref.alias = alias
+		refs[alias] = &ref
+	}
+
+	for alias, opts := range options {
+		ref, ok := refs[alias]
+		if !ok {
+			return nil, fmt.Errorf("options for folder alias %q but no %s%s annotation", alias, AnnotationFolderAliasPrefix, alias)
+		}
+		for option, value := range opts {
+			switch option {
+			case "prune":
+				ref.Prune = strings.ToLower(value) != "false"
+			case "format":
+				ref.Format = value
+			case "filename":
+				ref.FilenamePattern = value
+			case "recursive":
+				ref.Recursive = strings.ToLower(value) == "true"
+			default:
+				return nil, fmt.Errorf("unknown option %q for folder alias %q (supported: prune, format, filename, recursive)", option, alias)
+			}
+		}
+	}
+
+	aliases := make([]string, 0, len(refs))
+	for alias := range refs {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	folders := make([]FolderRef, 0, len(aliases))
+	for _, alias := range aliases {
+		folders = append(folders, *refs[alias])
+	}
+	return folders, nil
+}
+
 // FullConfig represents the Level 5 YAML configuration structure
 type FullConfig struct {
 	Secrets []SecretYAMLConfig `yaml:"secrets,omitempty"`
 	Folders []FolderYAMLConfig `yaml:"folders,omitempty"`
+	// Templates are outputs rendered from several records
+	Templates []TemplateRef `yaml:"templates,omitempty"`
+	// Hooks run after any secret's content changes
+	Hooks []HookRef `yaml:"hooks,omitempty"`
 }
 
 // SecretYAMLConfig represents a secret in YAML config
@@ -555,6 +938,8 @@ type SecretYAMLConfig struct {
 	Format string `yaml:"format,omitempty"`
 	// Template is a Go template string for custom formatting
 	Template string `yaml:"template,omitempty"`
+	// TemplateSource loads the template from a ConfigMap key (alternative to Template)
+	TemplateSource *TemplateSource `yaml:"templateRef,omitempty"`
 	// File is for file attachment downloads
 	File string `yaml:"file,omitempty"`
 	// FileName is for file attachment downloads (alias for File, for clarity)
@@ -563,6 +948,8 @@ type SecretYAMLConfig struct {
 	InjectAsEnvVars bool `yaml:"injectAsEnvVars,omitempty"`
 	// EnvPrefix is an optional prefix for env var names
 	EnvPrefix string `yaml:"envPrefix,omitempty"`
+	// Containers limits this secret to these app containers
+	Containers []string `yaml:"containers,omitempty"`
 	// InjectAsK8sSecret if true, inject as K8s Secret object (v0.9.0)
 	InjectAsK8sSecret bool `yaml:"injectAsK8sSecret,omitempty"`
 	// K8sSecretName is the name of the K8s Secret to create
@@ -571,6 +958,8 @@ type SecretYAMLConfig struct {
 	K8sSecretKeys map[string]string `yaml:"k8sSecretKeys,omitempty"`
 	// K8sSecretType is the Secret type (Opaque, kubernetes.io/tls, etc.)
 	K8sSecretType string `yaml:"k8sSecretType,omitempty"`
+	// Hooks run after this secret's content changes
+	Hooks []HookRef `yaml:"hooks,omitempty"`
 }
 
 // FolderYAMLConfig represents a folder in YAML config
@@ -583,17 +972,30 @@ type FolderYAMLConfig struct {
 	FolderPath string `yaml:"folderPath,omitempty"`
 	// OutputPath is where to write secrets
 	OutputPath string `yaml:"outputPath,omitempty"`
+	// Prune removes files of records that left the folder (default: keeper.security/folder-prune)
+	Prune *bool `yaml:"prune,omitempty"`
+	// Format of each record file: json, env, yaml, properties, ini
+	Format string `yaml:"format,omitempty"`
+	// Template is a Go template rendered per record
+	Template string `yaml:"template,omitempty"`
+	// Filename is the file name pattern, e.g. "{{ .Type }}/{{ .Title | lower }}.env"
+	Filename string `yaml:"filename,omitempty"`
+	// Recursive includes subfolders, mirrored as subdirectories of OutputPath
+	Recursive bool `yaml:"recursive,omitempty"`
+	// Filter selects which records are synced
+	Filter RecordFilter `yaml:"filter,omitempty"`
 	// InjectAsK8sSecret if true, inject as K8s Secret objects (v0.9.0)
 	InjectAsK8sSecret bool `yaml:"injectAsK8sSecret,omitempty"`
 	// K8sSecretNamePrefix is the prefix for generated Secret names
 	K8sSecretNamePrefix string `yaml:"k8sSecretNamePrefix,omitempty"`
 }
 
-// parseFullConfig parses Level 5 YAML configuration
-func parseFullConfig(configYAML string) ([]SecretRef, []FolderRef, error) {
+// parseFullConfig parses Level 5 YAML configuration into config.
+// defaultPrune applies to folders without an explicit prune setting.
+func parseFullConfig(configYAML string, config *InjectionConfig, defaultPrune bool) error {
 	var cfg FullConfig
 	if err := yaml.Unmarshal([]byte(configYAML), &cfg); err != nil {
-		return nil, nil, fmt.Errorf("invalid YAML: %w", err)
+		return fmt.Errorf("invalid YAML: %w", err)
 	}
 
 	var secrets []SecretRef
@@ -601,12 +1003,15 @@ func parseFullConfig(configYAML string) ([]SecretRef, []FolderRef, error) {
 		ref := SecretRef{
 			Format:            s.Format,
 			Template:          s.Template,
+			TemplateSource:    s.TemplateSource,
 			InjectAsEnvVars:   s.InjectAsEnvVars,
 			EnvVarPrefix:      s.EnvPrefix,
+			Containers:        s.Containers,
 			InjectAsK8sSecret: s.InjectAsK8sSecret,
 			K8sSecretName:     s.K8sSecretName,
 			K8sSecretKeys:     s.K8sSecretKeys,
 			K8sSecretType:     s.K8sSecretType,
+			Hooks:             s.Hooks,
 		}
 
 		// Set default format
@@ -666,6 +1071,15 @@ func parseFullConfig(configYAML string) ([]SecretRef, []FolderRef, error) {
 			OutputPath:          f.OutputPath,
 			InjectAsK8sSecret:   f.InjectAsK8sSecret,
 			K8sSecretNamePrefix: f.K8sSecretNamePrefix,
+			Prune:               defaultPrune,
+			Format:              f.Format,
+			Template:            f.Template,
+			FilenamePattern:     f.Filename,
+			Recursive:           f.Recursive,
+			Filter:              f.Filter,
+		}
+		if f.Prune != nil {
+			ref.Prune = *f.Prune
 		}
 		if ref.OutputPath == "" {
 			ref.OutputPath = DefaultSecretsPath
@@ -673,7 +1087,250 @@ func parseFullConfig(configYAML string) ([]SecretRef, []FolderRef, error) {
 		folders = append(folders, ref)
 	}
 
-	return secrets, folders, nil
+	templates := make([]TemplateRef, 0, len(cfg.Templates))
+	for _, t := range cfg.Templates {
+		if t.Name == "" && t.Path != "" {
+			t.Name = sanitizeName(strings.TrimSuffix(filepath.Base(t.Path), filepath.Ext(t.Path)))
+		}
+		templates = append(templates, t)
+	}
+
+	config.Secrets = append(config.Secrets, secrets...)
+	config.Folders = append(config.Folders, folders...)
+	config.Templates = append(config.Templates, templates...)
+	config.Hooks = append(config.Hooks, cfg.Hooks...)
+	return nil
+}
+
+// templateAliasPattern matches aliases usable as {{ .alias.field }}
+var templateAliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateTemplateRefs checks that every template output has a path,
+// a template and at least one record with a usable alias
+func validateTemplateRefs(templates []TemplateRef) error {
+	for _, t := range templates {
+		switch {
+		case t.Path == "":
+			return fmt.Errorf("template %q: path is required", t.Name)
+		case !filepath.IsAbs(t.Path):
+			return fmt.Errorf("template %q: path %q must be absolute", t.Name, t.Path)
+		case strings.TrimSpace(t.Template) == "" && t.TemplateSource == nil:
+			return fmt.Errorf("template %q: template or templateRef is required", t.Name)
+		case len(t.Records) == 0:
+			return fmt.Errorf("template %q: at least one record is required", t.Name)
+		}
+		if err := validateTemplateSource(t.Template, t.TemplateSource); err != nil {
+			return fmt.Errorf("template %q: %w", t.Name, err)
+		}
+		for alias, record := range t.Records {
+			if !templateAliasPattern.MatchString(alias) {
+				return fmt.Errorf("template %q: invalid alias %q (letters, digits and underscores only)", t.Name, alias)
+			}
+			if strings.TrimSpace(record) == "" {
+				return fmt.Errorf("template %q: alias %q has no record", t.Name, alias)
+			}
+		}
+	}
+	return nil
+}
+
+// folderFormats are the output formats supported for folder records
+var folderFormats = []string{"json", "env", "yaml", "properties", "ini"}
+
+// validateFolderRefs checks folder output paths, formats, templates and record filters.
+// Several folders may share an output directory, as they did before aliases
+// existed, but an alias folder must not collide with another folder: neither
+// the same directory nor one inside a folder that writes subdirectories.
+func validateFolderRefs(folders []FolderRef) error {
+	for i, f := range folders {
+		name := folderName(f)
+		for j, other := range folders {
+			if i == j || (f.alias == "" && other.alias == "") {
+				continue
+			}
+			output, otherOutput := filepath.Clean(f.OutputPath), filepath.Clean(other.OutputPath)
+			if output == otherOutput {
+				if i < j {
+					return fmt.Errorf("folders %q and %q both write to %s", name, folderName(other), output)
+				}
+				continue
+			}
+			if writesSubdirectories(other) && strings.HasPrefix(output, strings.TrimSuffix(otherOutput, "/")+"/") {
+				return fmt.Errorf("folder %q writes to %s, inside %s where folder %q writes subdirectories",
+					name, output, otherOutput, folderName(other))
+			}
+		}
+		if f.Format != "" && !slices.Contains(folderFormats, f.Format) {
+			return fmt.Errorf("folder %q: unsupported format %q (supported: %s)", name, f.Format, strings.Join(folderFormats, ", "))
+		}
+		if f.Template != "" && f.FilenamePattern == "" {
+			return fmt.Errorf("folder %q: filename is required with template", name)
+		}
+		if _, err := path.Match(f.Filter.Title, ""); err != nil {
+			return fmt.Errorf("folder %q: invalid filter.title %q: %w", name, f.Filter.Title, err)
+		}
+		if _, err := regexp.Compile(f.Filter.TitleRegex); err != nil {
+			return fmt.Errorf("folder %q: invalid filter.titleRegex: %w", name, err)
+		}
+		for _, t := range f.Filter.Types {
+			if strings.TrimSpace(t) == "" {
+				return fmt.Errorf("folder %q: empty record type in filter.types", name)
+			}
+		}
+		for label := range f.Filter.Labels {
+			if label == "" {
+				return fmt.Errorf("folder %q: empty label in filter.labels", name)
+			}
+		}
+	}
+	return nil
+}
+
+// folderName names a folder in validation errors
+func folderName(f FolderRef) string {
+	if f.alias != "" {
+		return f.alias
+	}
+	if f.FolderUID != "" {
+		return f.FolderUID
+	}
+	return f.FolderPath
+}
+
+// writesSubdirectories reports whether a folder sync may create directories
+// below its output path
+func writesSubdirectories(f FolderRef) bool {
+	return f.Recursive || strings.Contains(f.FilenamePattern, "/")
+}
+
+// validateTemplateSource checks that a templateRef is complete and not combined with an inline template
+func validateTemplateSource(inline string, src *TemplateSource) error {
+	if src == nil {
+		return nil
+	}
+	switch {
+	case inline != "":
+		return fmt.Errorf("template and templateRef are mutually exclusive")
+	case src.ConfigMap == "":
+		return fmt.Errorf("templateRef.configMap is required")
+	case len(validation.IsDNS1123Subdomain(src.ConfigMap)) > 0:
+		return fmt.Errorf("invalid templateRef.configMap %q", src.ConfigMap)
+	case len(validation.IsConfigMapKey(src.Key)) > 0:
+		return fmt.Errorf("invalid templateRef.key %q", src.Key)
+	}
+	return nil
+}
+
+// validateHooks checks pod-level, per-secret and per-template hooks
+func validateHooks(cfg *InjectionConfig) error {
+	check := func(h HookRef) error {
+		if err := ValidateHook(h); err != nil {
+			if h.Name != "" {
+				return fmt.Errorf("hook %q: %w", h.Name, err)
+			}
+			return fmt.Errorf("hook: %w", err)
+		}
+		return nil
+	}
+	for _, h := range cfg.Hooks {
+		if err := check(h); err != nil {
+			return err
+		}
+	}
+	for _, s := range cfg.Secrets {
+		for _, h := range s.Hooks {
+			if err := check(h); err != nil {
+				return err
+			}
+		}
+	}
+	for _, t := range cfg.Templates {
+		for _, h := range t.Hooks {
+			if err := check(h); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateHook checks that a hook has exactly one action, that HTTP hooks
+// target localhost and that the timeout and retries are valid.
+func ValidateHook(h HookRef) error {
+	switch {
+	case h.URL == "" && len(h.Command) == 0:
+		return fmt.Errorf("either url or command is required")
+	case h.URL != "" && len(h.Command) > 0:
+		return fmt.Errorf("url and command are mutually exclusive")
+	}
+
+	if h.URL != "" {
+		u, err := url.Parse(h.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url %q: %w", h.URL, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("url %q must use http or https", h.URL)
+		}
+		switch u.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+		default:
+			return fmt.Errorf("url %q must target localhost", h.URL)
+		}
+	}
+
+	if h.Timeout != "" {
+		d, err := time.ParseDuration(h.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid timeout %q", h.Timeout)
+		}
+	}
+	if h.Retries != nil && *h.Retries < 0 {
+		return fmt.Errorf("retries must not be negative")
+	}
+	return nil
+}
+
+// SupportedSignals lists the signals the sidecar can deliver on secret refresh
+var SupportedSignals = []string{"SIGHUP", "SIGINT", "SIGQUIT", "SIGTERM", "SIGUSR1", "SIGUSR2", "SIGWINCH"}
+
+// IsSupportedSignal reports whether name (with or without the SIG prefix) is a supported signal
+func IsSupportedSignal(name string) bool {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	for _, s := range SupportedSignals {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNameList splits a comma-separated list of names, dropping empty entries
+func parseNameList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// TargetsContainer reports whether secrets are injected into the named app container
+func (c *InjectionConfig) TargetsContainer(name string) bool {
+	if len(c.Containers) > 0 && !slices.Contains(c.Containers, name) {
+		return false
+	}
+	return !slices.Contains(c.ExcludeContainers, name)
+}
+
+// TargetsContainer reports whether the secret reaches the named app container.
+// The pod-wide selection (InjectionConfig.TargetsContainer) applies as well.
+func (s SecretRef) TargetsContainer(name string) bool {
+	return len(s.Containers) == 0 || slices.Contains(s.Containers, name)
 }
 
 // sanitizeName converts a secret name to a safe filename
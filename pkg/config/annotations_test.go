@@ -211,6 +211,52 @@ func TestParseAnnotations_BehaviorAnnotations(t *testing.T) {
 	}
 }
 
+func TestParseAnnotations_SignalTarget(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"keeper.security/inject":           "true",
+				"keeper.security/ksm-config":       "keeper-auth",
+				"keeper.security/secret":           "test-secret",
+				"keeper.security/signal":           "sighup",
+				"keeper.security/signal-container": "pooler",
+				"keeper.security/signal-process":   "pgbouncer",
+			},
+		},
+	}
+
+	cfg, err := ParseAnnotations(pod)
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if cfg.Signal != "SIGHUP" {
+		t.Errorf("Signal = %v, want SIGHUP", cfg.Signal)
+	}
+	if cfg.SignalContainer != "pooler" {
+		t.Errorf("SignalContainer = %v, want pooler", cfg.SignalContainer)
+	}
+	if cfg.SignalProcess != "pgbouncer" {
+		t.Errorf("SignalProcess = %v, want pgbouncer", cfg.SignalProcess)
+	}
+}
+
+func TestParseAnnotations_UnsupportedSignal(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"keeper.security/inject":     "true",
+				"keeper.security/ksm-config": "keeper-auth",
+				"keeper.security/secret":     "test-secret",
+				"keeper.security/signal":     "SIGKILL",
+			},
+		},
+	}
+
+	if _, err := ParseAnnotations(pod); err == nil {
+		t.Error("ParseAnnotations() expected error for unsupported signal")
+	}
+}
+
 func TestParseAnnotations_MissingAuthSecret(t *testing.T) {
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -727,3 +773,686 @@ folders:
 		t.Errorf("K8sSecretNamePrefix = %v, want api-", folder.K8sSecretNamePrefix)
 	}
 }
+
+func TestParseAnnotations_Hooks(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"keeper.security/inject":         "true",
+				"keeper.security/ksm-config":     "keeper-auth",
+				"keeper.security/reload-url":     "http://127.0.0.1:9000/-/reload",
+				"keeper.security/reload-command": "/bin/reload --graceful",
+				"keeper.security/hook-timeout":   "5s",
+				"keeper.security/hook-retries":   "1",
+				"keeper.security/config": `
+secrets:
+  - record: db
+    path: /keeper/secrets/db.json
+    hooks:
+      - name: pgbouncer
+        url: http://localhost:6432/reload
+`,
+			},
+		},
+	}
+
+	cfg, err := ParseAnnotations(pod)
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+
+	if len(cfg.Hooks) != 2 {
+		t.Fatalf("len(Hooks) = %d, want 2", len(cfg.Hooks))
+	}
+	if cfg.Hooks[0].URL != "http://127.0.0.1:9000/-/reload" || cfg.Hooks[0].Timeout != "5s" || *cfg.Hooks[0].Retries != 1 {
+		t.Errorf("reload-url hook = %+v", cfg.Hooks[0])
+	}
+	if len(cfg.Hooks[1].Command) != 2 || cfg.Hooks[1].Command[0] != "/bin/reload" {
+		t.Errorf("reload-command hook = %+v", cfg.Hooks[1])
+	}
+	if len(cfg.Secrets) != 1 || len(cfg.Secrets[0].Hooks) != 1 || cfg.Secrets[0].Hooks[0].Name != "pgbouncer" {
+		t.Errorf("per-secret hooks = %+v", cfg.Secrets)
+	}
+}
+
+func TestValidateHook(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name    string
+		hook    HookRef
+		wantErr bool
+	}{
+		{"localhost url", HookRef{URL: "http://localhost:8080/reload"}, false},
+		{"ipv6 loopback", HookRef{URL: "http://[::1]:8080/reload"}, false},
+		{"command", HookRef{Command: []string{"nginx", "-s", "reload"}}, false},
+		{"remote url", HookRef{URL: "http://example.com/reload"}, true},
+		{"bad scheme", HookRef{URL: "ftp://127.0.0.1/reload"}, true},
+		{"no action", HookRef{Name: "empty"}, true},
+		{"both actions", HookRef{URL: "http://localhost/", Command: []string{"true"}}, true},
+		{"bad timeout", HookRef{URL: "http://localhost/", Timeout: "soon"}, true},
+		{"negative retries", HookRef{URL: "http://localhost/", Retries: &negative}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHook(tt.hook)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAnnotations_Templates(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"keeper.security/inject":     "true",
+				"keeper.security/ksm-config": "keeper-auth",
+				"keeper.security/config": `
+templates:
+  - path: /keeper/secrets/application.yaml
+    records:
+      db: postgres-prod
+      redis: XyZ123abc456DEF789ghi0
+    template: |
+      password: {{ .db.password }}
+      redis: {{ .redis.url }}
+`,
+			},
+		},
+	}
+
+	cfg, err := ParseAnnotations(pod)
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+
+	if len(cfg.Secrets) != 0 {
+		t.Errorf("len(Secrets) = %d, want 0", len(cfg.Secrets))
+	}
+	if len(cfg.Templates) != 1 {
+		t.Fatalf("len(Templates) = %d, want 1", len(cfg.Templates))
+	}
+	tmpl := cfg.Templates[0]
+	if tmpl.Name != "application" {
+		t.Errorf("Name = %q, want %q", tmpl.Name, "application")
+	}
+	if tmpl.Records["db"] != "postgres-prod" || tmpl.Records["redis"] != "XyZ123abc456DEF789ghi0" {
+		t.Errorf("Records = %v", tmpl.Records)
+	}
+}
+
+func TestParseAnnotations_InvalidTemplates(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"missing path", "templates:\n  - records: {db: pg}\n    template: x\n"},
+		{"relative path", "templates:\n  - path: app.yaml\n    records: {db: pg}\n    template: x\n"},
+		{"missing template", "templates:\n  - path: /keeper/secrets/app.yaml\n    records: {db: pg}\n"},
+		{"no records", "templates:\n  - path: /keeper/secrets/app.yaml\n    template: x\n"},
+		{"invalid alias", "templates:\n  - path: /keeper/secrets/app.yaml\n    records: {my-db: pg}\n    template: x\n"},
+		{"empty record", "templates:\n  - path: /keeper/secrets/app.yaml\n    records: {db: \"\"}\n    template: x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"keeper.security/inject":     "true",
+						"keeper.security/ksm-config": "keeper-auth",
+						"keeper.security/config":     tt.config,
+					},
+				},
+			}
+			if _, err := ParseAnnotations(pod); err == nil {
+				t.Error("ParseAnnotations() expected error")
+			}
+		})
+	}
+}
+
+func TestParseAnnotations_TemplateRef(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"keeper.security/inject":     "true",
+				"keeper.security/ksm-config": "keeper-auth",
+				"keeper.security/config": `
+secrets:
+  - record: db
+    path: /keeper/secrets/db.conf
+    templateRef:
+      configMap: app-templates
+      key: db.conf.tmpl
+templates:
+  - path: /keeper/secrets/application.yaml
+    records:
+      db: postgres-prod
+    templateRef:
+      configMap: app-templates
+      key: application.yaml.tmpl
+`,
+			},
+		},
+	}
+
+	cfg, err := ParseAnnotations(pod)
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+
+	src := cfg.Secrets[0].TemplateSource
+	if src == nil || src.ConfigMap != "app-templates" || src.Key != "db.conf.tmpl" {
+		t.Fatalf("secret TemplateSource = %+v", src)
+	}
+	if got, want := src.FilePath(), "/keeper/templates/app-templates/db.conf.tmpl"; got != want {
+		t.Errorf("FilePath() = %q, want %q", got, want)
+	}
+	if cfg.Templates[0].TemplateSource == nil || cfg.Templates[0].TemplateSource.Key != "application.yaml.tmpl" {
+		t.Errorf("template TemplateSource = %+v", cfg.Templates[0].TemplateSource)
+	}
+}
+
+func TestParseAnnotations_InvalidTemplateRef(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"inline and ref", "secrets:\n  - record: db\n    template: x\n    templateRef: {configMap: tmpl, key: db.tmpl}\n"},
+		{"missing configMap", "secrets:\n  - record: db\n    templateRef: {key: db.tmpl}\n"},
+		{"invalid configMap", "secrets:\n  - record: db\n    templateRef: {configMap: ../etc, key: db.tmpl}\n"},
+		{"invalid key", "secrets:\n  - record: db\n    templateRef: {configMap: tmpl, key: a/b}\n"},
+		{"template without source", "templates:\n  - path: /keeper/secrets/app.yaml\n    records: {db: pg}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"keeper.security/inject":     "true",
+						"keeper.security/ksm-config": "keeper-auth",
+						"keeper.security/config":     tt.config,
+					},
+				},
+			}
+			if _, err := ParseAnnotations(pod); err == nil {
+				t.Error("ParseAnnotations() expected error")
+			}
+		})
+	}
+}
+
+func TestParseAnnotations_PersistentCache(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantCache   bool
+		wantVolume  string
+		wantMaxAge  string
+		wantErr     bool
+	}{
+		{
+			name:        "disabled by default",
+			annotations: map[string]string{},
+		},
+		{
+			name:        "emptyDir cache",
+			annotations: map[string]string{"keeper.security/persistent-cache": "true", "keeper.security/cache-max-age": "2h"},
+			wantCache:   true,
+			wantMaxAge:  "2h",
+		},
+		{
+			name:        "cache volume implies persistent cache",
+			annotations: map[string]string{"keeper.security/cache-volume": "keeper-state"},
+			wantCache:   true,
+			wantVolume:  "keeper-state",
+		},
+		{
+			name:        "invalid max age",
+			annotations: map[string]string{"keeper.security/persistent-cache": "true", "keeper.security/cache-max-age": "-1h"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid volume name",
+			annotations: map[string]string{"keeper.security/cache-volume": "Keeper_State"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{
+				"keeper.security/inject":     "true",
+				"keeper.security/ksm-config": "keeper-auth",
+				"keeper.security/secret":     "db",
+			}
+			for k, v := range tt.annotations {
+				annotations[k] = v
+			}
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+
+			cfg, err := ParseAnnotations(pod)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("ParseAnnotations() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAnnotations() error = %v", err)
+			}
+			if cfg.PersistentCache != tt.wantCache {
+				t.Errorf("PersistentCache = %v, want %v", cfg.PersistentCache, tt.wantCache)
+			}
+			if cfg.CacheVolume != tt.wantVolume {
+				t.Errorf("CacheVolume = %q, want %q", cfg.CacheVolume, tt.wantVolume)
+			}
+			if cfg.CacheMaxAge != tt.wantMaxAge {
+				t.Errorf("CacheMaxAge = %q, want %q", cfg.CacheMaxAge, tt.wantMaxAge)
+			}
+		})
+	}
+}
+
+func TestParseAnnotations_SecretsAPI(t *testing.T) {
+	annotations := map[string]string{
+		"keeper.security/inject":      "true",
+		"keeper.security/ksm-config":  "keeper-auth",
+		"keeper.security/secret":      "db",
+		"keeper.security/secrets-api": "true",
+	}
+	cfg, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if !cfg.SecretsAPI {
+		t.Error("SecretsAPI = false, want true")
+	}
+
+	annotations["keeper.security/init-only"] = "true"
+	if _, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}); err == nil {
+		t.Error("ParseAnnotations() expected error for secrets-api with init-only")
+	}
+}
+
+func TestParseAnnotations_FolderPrune(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantPrune   []bool
+	}{
+		{
+			name:        "enabled by default",
+			annotations: map[string]string{"keeper.security/folder": "Production/Databases"},
+			wantPrune:   []bool{true},
+		},
+		{
+			name: "disabled by annotation",
+			annotations: map[string]string{
+				"keeper.security/folder":       "Production/Databases",
+				"keeper.security/folder-prune": "false",
+			},
+			wantPrune: []bool{false},
+		},
+		{
+			name: "per folder in YAML config",
+			annotations: map[string]string{
+				"keeper.security/config": `
+folders:
+  - uid: "FOLDER1"
+    outputPath: /app/a
+  - uid: "FOLDER2"
+    outputPath: /app/b
+    prune: false
+`,
+			},
+			wantPrune: []bool{true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{
+				"keeper.security/inject":     "true",
+				"keeper.security/ksm-config": "keeper-auth",
+			}
+			for k, v := range tt.annotations {
+				annotations[k] = v
+			}
+			cfg, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+			if err != nil {
+				t.Fatalf("ParseAnnotations() error = %v", err)
+			}
+			if len(cfg.Folders) != len(tt.wantPrune) {
+				t.Fatalf("Expected %d folders, got %d", len(tt.wantPrune), len(cfg.Folders))
+			}
+			for i, want := range tt.wantPrune {
+				if cfg.Folders[i].Prune != want {
+					t.Errorf("Folders[%d].Prune = %v, want %v", i, cfg.Folders[i].Prune, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAnnotations_FolderOutput(t *testing.T) {
+	configYAML := `
+folders:
+  - folderPath: "Production/Services"
+    outputPath: /app/services
+    format: env
+    filename: "{{ .Type }}/{{ .Title | lower }}.env"
+  - uid: "FOLDER2"
+    outputPath: /app/db
+    template: "DB_PASSWORD={{ .password }}"
+    filename: "{{ .Title }}.conf"
+`
+	annotations := map[string]string{
+		"keeper.security/inject":          "true",
+		"keeper.security/ksm-config":      "keeper-auth",
+		"keeper.security/folder":          "Legacy",
+		"keeper.security/folder-format":   "properties",
+		"keeper.security/folder-filename": "{{ .Title }}.properties",
+		"keeper.security/config":          configYAML,
+	}
+	cfg, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if len(cfg.Folders) != 3 {
+		t.Fatalf("Expected 3 folders, got %d", len(cfg.Folders))
+	}
+	if cfg.Folders[0].Format != "properties" || cfg.Folders[0].FilenamePattern != "{{ .Title }}.properties" {
+		t.Errorf("annotation folder = %+v", cfg.Folders[0])
+	}
+	if cfg.Folders[1].Format != "env" || cfg.Folders[1].FilenamePattern != "{{ .Type }}/{{ .Title | lower }}.env" {
+		t.Errorf("YAML folder = %+v", cfg.Folders[1])
+	}
+	if cfg.Folders[2].Template != "DB_PASSWORD={{ .password }}" {
+		t.Errorf("Template = %q", cfg.Folders[2].Template)
+	}
+
+	invalid := map[string]string{
+		"unsupported format":        "folders:\n  - uid: F1\n    format: raw\n",
+		"template without filename": "folders:\n  - uid: F1\n    template: \"{{ .password }}\"\n",
+	}
+	for name, yamlConfig := range invalid {
+		annotations := map[string]string{
+			"keeper.security/inject":     "true",
+			"keeper.security/ksm-config": "keeper-auth",
+			"keeper.security/config":     yamlConfig,
+		}
+		if _, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}); err == nil {
+			t.Errorf("%s: ParseAnnotations() expected error", name)
+		}
+	}
+}
+
+func TestParseAnnotations_FolderRecursiveFilter(t *testing.T) {
+	configYAML := `
+folders:
+  - folderPath: "Production/Payments"
+    outputPath: /app/payments
+    recursive: true
+    filter:
+      types: [login, databaseCredentials]
+      title: "stripe-*"
+      labels:
+        env: prod
+`
+	annotations := map[string]string{
+		"keeper.security/inject":           "true",
+		"keeper.security/ksm-config":       "keeper-auth",
+		"keeper.security/folder":           "Production",
+		"keeper.security/folder-recursive": "true",
+		"keeper.security/config":           configYAML,
+	}
+	cfg, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if len(cfg.Folders) != 2 {
+		t.Fatalf("Expected 2 folders, got %d", len(cfg.Folders))
+	}
+	if !cfg.Folders[0].Recursive || !cfg.Folders[0].Filter.IsEmpty() {
+		t.Errorf("annotation folder = %+v, want recursive without filter", cfg.Folders[0])
+	}
+	folder := cfg.Folders[1]
+	if !folder.Recursive {
+		t.Error("Recursive = false, want true")
+	}
+	if len(folder.Filter.Types) != 2 || folder.Filter.Title != "stripe-*" || folder.Filter.Labels["env"] != "prod" {
+		t.Errorf("Filter = %+v", folder.Filter)
+	}
+
+	invalid := map[string]string{
+		"bad glob":  "folders:\n  - uid: F1\n    filter:\n      title: \"[\"\n",
+		"bad regex": "folders:\n  - uid: F1\n    filter:\n      titleRegex: \"(\"\n",
+	}
+	for name, yamlConfig := range invalid {
+		annotations := map[string]string{
+			"keeper.security/inject":     "true",
+			"keeper.security/ksm-config": "keeper-auth",
+			"keeper.security/config":     yamlConfig,
+		}
+		if _, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}); err == nil {
+			t.Errorf("%s: ParseAnnotations() expected error", name)
+		}
+	}
+}
+
+func TestParseAnnotations_FolderAliases(t *testing.T) {
+	annotations := map[string]string{
+		"keeper.security/inject":                    "true",
+		"keeper.security/ksm-config":                "keeper-auth",
+		"keeper.security/folder-format":             "env",
+		"keeper.security/folder-db":                 "Production/DB:/keeper/secrets/db",
+		"keeper.security/folder-payments":           "Production/Payments",
+		"keeper.security/folder-payments.recursive": "true",
+		"keeper.security/folder-payments.format":    "yaml",
+		"keeper.security/folder-legacy":             "uid:AbCdEfGhIjKlMnOpQrStUv:/app/legacy",
+		"keeper.security/folder-legacy.prune":       "false",
+	}
+	cfg, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if len(cfg.Folders) != 3 {
+		t.Fatalf("Expected 3 folders, got %d", len(cfg.Folders))
+	}
+
+	// Sorted by alias: db, legacy, payments
+	db, legacy, payments := cfg.Folders[0], cfg.Folders[1], cfg.Folders[2]
+	if db.FolderPath != "Production/DB" || db.OutputPath != "/keeper/secrets/db" || db.Format != "env" || !db.Prune {
+		t.Errorf("db folder = %+v", db)
+	}
+	if legacy.FolderUID != "AbCdEfGhIjKlMnOpQrStUv" || legacy.FolderPath != "" || legacy.OutputPath != "/app/legacy" || legacy.Prune {
+		t.Errorf("legacy folder = %+v", legacy)
+	}
+	if payments.FolderPath != "Production/Payments" || payments.OutputPath != "/keeper/secrets/payments" || !payments.Recursive || payments.Format != "yaml" {
+		t.Errorf("payments folder = %+v", payments)
+	}
+}
+
+func TestParseAnnotations_SharedFolderOutput(t *testing.T) {
+	tests := map[string]map[string]string{
+		"yaml folders with default path": {
+			"keeper.security/config": `
+folders:
+  - folderPath: Production/A
+  - folderPath: Production/B
+`,
+		},
+		"alias inside non-recursive folder": {
+			"keeper.security/folder":   "Production",
+			"keeper.security/folder-a": "Production/A",
+		},
+	}
+
+	for name, extra := range tests {
+		t.Run(name, func(t *testing.T) {
+			annotations := map[string]string{
+				"keeper.security/inject":     "true",
+				"keeper.security/ksm-config": "keeper-auth",
+			}
+			for k, v := range extra {
+				annotations[k] = v
+			}
+			cfg, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+			if err != nil {
+				t.Fatalf("ParseAnnotations() error = %v", err)
+			}
+			if len(cfg.Folders) != 2 {
+				t.Errorf("Expected 2 folders, got %d", len(cfg.Folders))
+			}
+		})
+	}
+}
+
+func TestParseAnnotations_FolderAliasErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"duplicate output path": {
+			"keeper.security/folder-a": "Production/A:/keeper/secrets/shared",
+			"keeper.security/folder-b": "Production/B:/keeper/secrets/shared/",
+		},
+		"duplicate with single folder": {
+			"keeper.security/folder":   "Production",
+			"keeper.security/folder-a": "Production/A:/keeper/secrets",
+		},
+		"inside recursive folder": {
+			"keeper.security/folder":           "Production",
+			"keeper.security/folder-recursive": "true",
+			"keeper.security/folder-a":         "Production/A",
+		},
+		"inside folder with subdirectory pattern": {
+			"keeper.security/folder-a":          "Production/A:/app/secrets",
+			"keeper.security/folder-a.filename": "{{ .Type }}/{{ .Title }}.json",
+			"keeper.security/folder-b":          "Production/B:/app/secrets/login",
+		},
+		"invalid alias":         {"keeper.security/folder-My_DB": "Production/DB"},
+		"option without folder": {"keeper.security/folder-db.format": "env"},
+		"unknown option":        {"keeper.security/folder-db": "Production/DB", "keeper.security/folder-db.color": "blue"},
+		"empty folder":          {"keeper.security/folder-db": ":/keeper/secrets/db"},
+	}
+
+	for name, extra := range tests {
+		t.Run(name, func(t *testing.T) {
+			annotations := map[string]string{
+				"keeper.security/inject":     "true",
+				"keeper.security/ksm-config": "keeper-auth",
+			}
+			for k, v := range extra {
+				annotations[k] = v
+			}
+			if _, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}); err == nil {
+				t.Error("ParseAnnotations() expected error")
+			}
+		})
+	}
+}
+
+func TestParseAnnotations_EnvSource(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "", want: EnvSourceSecret},
+		{value: "literal", want: EnvSourceLiteral},
+		{value: " Secret ", want: EnvSourceSecret},
+		{value: "exec", want: EnvSourceExec},
+		{value: "configmap", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			annotations := map[string]string{
+				"keeper.security/inject":          "true",
+				"keeper.security/ksm-config":      "keeper-auth",
+				"keeper.security/inject-env-vars": "true",
+				"keeper.security/secret":          "db",
+			}
+			if tt.value != "" {
+				annotations["keeper.security/env-source"] = tt.value
+			}
+			cfg, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("ParseAnnotations() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAnnotations() error = %v", err)
+			}
+			if cfg.EnvSource != tt.want {
+				t.Errorf("EnvSource = %q, want %q", cfg.EnvSource, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAnnotations_ContainerTargeting(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		"keeper.security/inject":             "true",
+		"keeper.security/ksm-config":         "keeper-auth",
+		"keeper.security/containers":         "app, worker,",
+		"keeper.security/exclude-containers": "worker",
+		"keeper.security/config": `
+secrets:
+  - record: db
+    containers: [app]
+  - record: telemetry
+`,
+	}}}
+
+	cfg, err := ParseAnnotations(pod)
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if len(cfg.Containers) != 2 || cfg.Containers[0] != "app" || cfg.Containers[1] != "worker" {
+		t.Errorf("Containers = %v, want [app worker]", cfg.Containers)
+	}
+
+	for name, want := range map[string]bool{"app": true, "worker": false, "istio-proxy": false} {
+		if got := cfg.TargetsContainer(name); got != want {
+			t.Errorf("TargetsContainer(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	if len(cfg.Secrets) != 2 {
+		t.Fatalf("got %d secrets, want 2", len(cfg.Secrets))
+	}
+	if cfg.Secrets[0].TargetsContainer("sidecar") || !cfg.Secrets[0].TargetsContainer("app") {
+		t.Errorf("db Containers = %v, want only app", cfg.Secrets[0].Containers)
+	}
+	if !cfg.Secrets[1].TargetsContainer("sidecar") {
+		t.Error("a secret without containers should reach every targeted container")
+	}
+}
+
+func TestParseAnnotations_InitContainers(t *testing.T) {
+	annotations := map[string]string{
+		"keeper.security/inject":          "true",
+		"keeper.security/ksm-config":      "keeper-auth",
+		"keeper.security/secret":          "db",
+		"keeper.security/init-containers": "migrate",
+	}
+
+	cfg, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+	if err != nil {
+		t.Fatalf("ParseAnnotations() error = %v", err)
+	}
+	if !cfg.InitFirst {
+		t.Error("InitFirst = false, want init-containers to imply it")
+	}
+
+	annotations["keeper.security/init-first"] = "false"
+	if _, err := ParseAnnotations(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}); err == nil {
+		t.Error("ParseAnnotations() expected error for init-containers with init-first=false")
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ParseAnnotationsWithProfile extracts injection configuration from pod
+// annotations on top of the defaults of an injection profile.
+//
+// Precedence, highest first:
+//  1. pod annotations
+//  2. typed profile fields (ksmConfig, secrets, folders, ...)
+//  3. the profile's annotations map
+//  4. built-in defaults
+//
+// A pod annotation replaces the profile's value of the same annotation only;
+// for example a pod keeper.security/secrets replaces the profile's secrets,
+// while a pod keeper.security/secret-<name> is added to them.
+func ParseAnnotationsWithProfile(pod *corev1.Pod, profile *v1alpha1.KeeperInjectionProfileSpec) (*InjectionConfig, error) {
+	if profile == nil {
+		return ParseAnnotations(pod)
+	}
+
+	annotations, err := profileAnnotations(profile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid injection profile: %w", err)
+	}
+	for key, value := range pod.Annotations {
+		annotations[key] = value
+	}
+
+	config, err := parseAnnotations(annotations)
+	if err != nil {
+		return nil, err
+	}
+	if config.Enabled && profile.Resources != nil {
+		config.Resources = profile.Resources.DeepCopy()
+	}
+	return config, nil
+}
+
+// profileAnnotations converts a profile into the annotations it stands for
+func profileAnnotations(profile *v1alpha1.KeeperInjectionProfileSpec) (map[string]string, error) {
+	annotations := make(map[string]string, len(profile.Annotations)+8)
+	for key, value := range profile.Annotations {
+		if !strings.HasPrefix(key, AnnotationPrefix) {
+			return nil, fmt.Errorf("annotation %q does not start with %s", key, AnnotationPrefix)
+		}
+		if key == AnnotationInject || key == AnnotationProfile {
+			return nil, fmt.Errorf("annotation %s must be set on the pod", key)
+		}
+		annotations[key] = value
+	}
+
+	set := func(key, value string) {
+		if value != "" {
+			annotations[key] = value
+		}
+	}
+	set(AnnotationKSMConfig, profile.KSMConfig)
+	set(AnnotationAuthMethod, profile.AuthMethod)
+	set(AnnotationSecrets, strings.Join(profile.Secrets, ","))
+	set(AnnotationFolderFormat, profile.FolderFormat)
+	set(AnnotationFolderFilename, profile.FolderFilename)
+	set(AnnotationConfig, profile.Config)
+	set(AnnotationSignal, profile.Signal)
+	set(AnnotationSignalContainer, profile.SignalContainer)
+	if profile.RefreshInterval != nil {
+		set(AnnotationRefreshInterval, profile.RefreshInterval.Duration.String())
+	}
+
+	for _, folder := range profile.Folders {
+		if !folderAliasPattern.MatchString(folder.Name) || reservedFolderAliases[folder.Name] {
+			return nil, fmt.Errorf("invalid folder name %q (lowercase letters, digits and '-')", folder.Name)
+		}
+		if (folder.Path == "") == (folder.UID == "") {
+			return nil, fmt.Errorf("folder %q needs exactly one of path or uid", folder.Name)
+		}
+		value := folder.Path
+		if folder.UID != "" {
+			value = "uid:" + folder.UID
+		}
+		if folder.OutputPath != "" {
+			value += ":" + folder.OutputPath
+		}
+		annotations[AnnotationFolderAliasPrefix+folder.Name] = value
+	}
+	return annotations, nil
+}
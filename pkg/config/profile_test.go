@@ -0,0 +1,135 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testProfile() *v1alpha1.KeeperInjectionProfileSpec {
+	return &v1alpha1.KeeperInjectionProfileSpec{
+		KSMConfig:       "team-auth",
+		Secrets:         []string{"shared-ca", "telemetry"},
+		Folders:         []v1alpha1.KeeperInjectionProfileFolder{{Name: "db", Path: "Production/Databases"}},
+		FolderFormat:    "env",
+		RefreshInterval: &metav1.Duration{Duration: 10 * time.Minute},
+		Signal:          "SIGHUP",
+		Resources: &corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+		},
+		Annotations: map[string]string{
+			AnnotationFailOnError:     "false",
+			AnnotationRefreshInterval: "1h",
+		},
+	}
+}
+
+func TestParseAnnotationsWithProfile(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		AnnotationInject:                "true",
+		AnnotationProfile:               "team",
+		AnnotationSignal:                "SIGUSR1",
+		AnnotationPrefix + "secret-app": "app-credentials",
+	}}}
+
+	cfg, err := ParseAnnotationsWithProfile(pod, testProfile())
+	if err != nil {
+		t.Fatalf("ParseAnnotationsWithProfile() error = %v", err)
+	}
+
+	if cfg.AuthSecretName != "team-auth" {
+		t.Errorf("AuthSecretName = %q, want team-auth", cfg.AuthSecretName)
+	}
+	if cfg.Signal != "SIGUSR1" {
+		t.Errorf("Signal = %q, want the pod's SIGUSR1", cfg.Signal)
+	}
+	if cfg.RefreshInterval != "10m0s" {
+		t.Errorf("RefreshInterval = %q, want the typed field over the profile's annotations", cfg.RefreshInterval)
+	}
+	if cfg.FailOnError {
+		t.Error("FailOnError = true, want false from the profile's annotations")
+	}
+	if len(cfg.Secrets) != 3 {
+		t.Errorf("got %d secrets, want the profile's two plus the pod's one", len(cfg.Secrets))
+	}
+	if len(cfg.Folders) != 1 || cfg.Folders[0].FolderPath != "Production/Databases" || cfg.Folders[0].Format != "env" {
+		t.Errorf("Folders = %+v, want Production/Databases in env format", cfg.Folders)
+	}
+	if cfg.Resources == nil || cfg.Resources.Limits.Memory().String() != "128Mi" {
+		t.Errorf("Resources = %v, want the profile's", cfg.Resources)
+	}
+
+	// A pod annotation replaces the profile's value of the same annotation
+	pod.Annotations[AnnotationSecrets] = "only-this"
+	delete(pod.Annotations, AnnotationPrefix+"secret-app")
+	cfg, err = ParseAnnotationsWithProfile(pod, testProfile())
+	if err != nil {
+		t.Fatalf("ParseAnnotationsWithProfile() error = %v", err)
+	}
+	if len(cfg.Secrets) != 1 || cfg.Secrets[0].Name != "only-this" {
+		t.Errorf("Secrets = %+v, want only the pod's", cfg.Secrets)
+	}
+}
+
+func TestParseAnnotationsWithProfile_NotEnabled(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		AnnotationProfile: "team",
+	}}}
+
+	cfg, err := ParseAnnotationsWithProfile(pod, testProfile())
+	if err != nil {
+		t.Fatalf("ParseAnnotationsWithProfile() error = %v", err)
+	}
+	if cfg.Enabled {
+		t.Error("Enabled = true, want the pod's keeper.security/inject to be required")
+	}
+}
+
+func TestParseAnnotationsWithProfile_InvalidProfile(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*v1alpha1.KeeperInjectionProfileSpec)
+	}{
+		{
+			name: "foreign annotation",
+			modify: func(p *v1alpha1.KeeperInjectionProfileSpec) {
+				p.Annotations = map[string]string{"example.com/x": "y"}
+			},
+		},
+		{
+			name: "inject in profile",
+			modify: func(p *v1alpha1.KeeperInjectionProfileSpec) {
+				p.Annotations = map[string]string{AnnotationInject: "true"}
+			},
+		},
+		{
+			name: "folder with path and uid",
+			modify: func(p *v1alpha1.KeeperInjectionProfileSpec) {
+				p.Folders[0].UID = "abc"
+			},
+		},
+		{
+			name: "reserved folder name",
+			modify: func(p *v1alpha1.KeeperInjectionProfileSpec) {
+				p.Folders[0].Name = "path"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := testProfile()
+			tt.modify(profile)
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+				AnnotationInject: "true",
+			}}}
+			if _, err := ParseAnnotationsWithProfile(pod, profile); err == nil {
+				t.Error("ParseAnnotationsWithProfile() error = nil, want an invalid profile error")
+			}
+		})
+	}
+}
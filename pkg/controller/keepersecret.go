@@ -0,0 +1,278 @@
+// Package controller implements the controllers run by the webhook's manager.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/apis/v1alpha1"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// DefaultRefreshInterval applies when a KeeperSecret sets no refresh interval
+	DefaultRefreshInterval = 5 * time.Minute
+
+	// retryInterval is the longest wait before retrying a failed reconciliation
+	retryInterval = time.Minute
+
+	// KeeperSecretLabel names the KeeperSecret that owns a Secret
+	KeeperSecretLabel = "keeper.security/keeper-secret"
+)
+
+// recordReader is the part of the KSM client the controller uses
+type recordReader interface {
+	ListSecrets(ctx context.Context) ([]*ksm.SecretData, error)
+	GetNotation(ctx context.Context, notation string) ([]byte, error)
+}
+
+// KeeperSecretReconciler keeps the Secret of each KeeperSecret in sync with
+// Keeper. The Secret is owned by its KeeperSecret, so it lives as long as the
+// KeeperSecret and not as long as any pod.
+type KeeperSecretReconciler struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+	logger *zap.Logger
+
+	// newReader opens Keeper with the KeeperSecret's KSM config (replaced in tests)
+	newReader func(ctx context.Context, ks *v1alpha1.KeeperSecret) (recordReader, func(), error)
+}
+
+// NewKeeperSecretReconciler creates a KeeperSecret reconciler
+func NewKeeperSecretReconciler(c client.Client, scheme *runtime.Scheme, logger *zap.Logger) *KeeperSecretReconciler {
+	r := &KeeperSecretReconciler{
+		Client: c,
+		Scheme: scheme,
+		logger: logger,
+	}
+	r.newReader = r.openKeeper
+	return r
+}
+
+// SetupWithManager registers the controller. Status-only updates of a
+// KeeperSecret are ignored; changes to its Secret trigger a reconciliation.
+func (r *KeeperSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("keepersecret").
+		For(&v1alpha1.KeeperSecret{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&corev1.Secret{}).
+		Complete(r)
+}
+
+// Reconcile writes the KeeperSecret's Secret from Keeper, reports the result
+// in the Ready condition and requeues after the refresh interval
+func (r *KeeperSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ks := &v1alpha1.KeeperSecret{}
+	if err := r.Client.Get(ctx, req.NamespacedName, ks); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	interval := DefaultRefreshInterval
+	if ks.Spec.RefreshInterval != nil && ks.Spec.RefreshInterval.Duration > 0 {
+		interval = ks.Spec.RefreshInterval.Duration
+	}
+	secretName := ks.Spec.Target.Name
+	if secretName == "" {
+		secretName = ks.Name
+	}
+
+	reason, syncErr := r.sync(ctx, ks, secretName)
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.ReasonSynced,
+		Message:            fmt.Sprintf("Secret %s is up to date", secretName),
+		ObservedGeneration: ks.Generation,
+	}
+	if syncErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reason
+		condition.Message = syncErr.Error()
+	} else {
+		now := metav1.Now()
+		ks.Status.LastSyncTime = &now
+	}
+	meta.SetStatusCondition(&ks.Status.Conditions, condition)
+	ks.Status.ObservedGeneration = ks.Generation
+	ks.Status.SecretName = secretName
+	if err := r.Client.Status().Update(ctx, ks); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update KeeperSecret status: %w", err)
+	}
+
+	if syncErr != nil {
+		r.logger.Warn("failed to reconcile KeeperSecret",
+			zap.String("namespace", ks.Namespace),
+			zap.String("name", ks.Name),
+			zap.String("reason", reason),
+			zap.Error(syncErr))
+		return ctrl.Result{RequeueAfter: min(interval, retryInterval)}, nil
+	}
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// sync writes the Secret and returns the condition reason of a failure
+func (r *KeeperSecretReconciler) sync(ctx context.Context, ks *v1alpha1.KeeperSecret, secretName string) (string, error) {
+	if err := validateSpec(ks.Spec); err != nil {
+		return v1alpha1.ReasonInvalidSpec, err
+	}
+
+	reader, closeReader, err := r.newReader(ctx, ks)
+	if err != nil {
+		return v1alpha1.ReasonAuthFailed, err
+	}
+	defer closeReader()
+
+	data, err := fetchSecretData(ctx, reader, ks.Spec.Records)
+	if err != nil {
+		return v1alpha1.ReasonFetchFailed, err
+	}
+
+	// Never take over a Secret someone else created
+	existing := &corev1.Secret{}
+	err = r.Client.Get(ctx, client.ObjectKey{Namespace: ks.Namespace, Name: secretName}, existing)
+	switch {
+	case err == nil && !metav1.IsControlledBy(existing, ks):
+		return v1alpha1.ReasonSecretConflict, fmt.Errorf("secret %s exists and is not managed by this KeeperSecret", secretName)
+	case err != nil && !apierrors.IsNotFound(err):
+		return v1alpha1.ReasonWriteFailed, fmt.Errorf("failed to get secret %s: %w", secretName, err)
+	}
+
+	secretType := corev1.SecretTypeOpaque
+	if ks.Spec.Target.Type != "" {
+		secretType = corev1.SecretType(ks.Spec.Target.Type)
+	}
+	if err == nil && existing.Type != secretType {
+		return v1alpha1.ReasonWriteFailed, fmt.Errorf("secret %s has type %s; the type cannot change to %s", secretName, existing.Type, secretType)
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: ks.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		labels := make(map[string]string, len(ks.Spec.Target.Labels)+2)
+		for k, v := range ks.Spec.Target.Labels {
+			labels[k] = v
+		}
+		labels["app.kubernetes.io/managed-by"] = "keeper-injector"
+		labels[KeeperSecretLabel] = ks.Name
+		secret.Labels = labels
+		secret.Annotations = ks.Spec.Target.Annotations
+		secret.Type = secretType
+		secret.Data = data
+		return controllerutil.SetControllerReference(ks, secret, r.Scheme)
+	})
+	if err != nil {
+		return v1alpha1.ReasonWriteFailed, fmt.Errorf("failed to write secret %s: %w", secretName, err)
+	}
+
+	if op != controllerutil.OperationResultNone {
+		r.logger.Info("reconciled Secret from KeeperSecret",
+			zap.String("namespace", ks.Namespace),
+			zap.String("keeperSecret", ks.Name),
+			zap.String("secret", secretName),
+			zap.String("operation", string(op)))
+	}
+	return "", nil
+}
+
+// validateSpec checks what the CRD schema cannot express
+func validateSpec(spec v1alpha1.KeeperSecretSpec) error {
+	if len(spec.Records) == 0 {
+		return fmt.Errorf("at least one record is required")
+	}
+	for i, record := range spec.Records {
+		if (record.Name == "") == (record.Notation == "") {
+			return fmt.Errorf("records[%d] needs exactly one of name or notation", i)
+		}
+	}
+	return nil
+}
+
+// fetchSecretData reads the records and builds the Secret data. Records are
+// listed once; a key set by two records is an error.
+func fetchSecretData(ctx context.Context, reader recordReader, records []v1alpha1.KeeperSecretRecord) (map[string][]byte, error) {
+	var byUID, byTitle map[string]*ksm.SecretData
+	for _, record := range records {
+		if record.Name == "" || byUID != nil {
+			continue
+		}
+		listed, err := reader.ListSecrets(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list records: %w", err)
+		}
+		byUID = make(map[string]*ksm.SecretData, len(listed))
+		byTitle = make(map[string]*ksm.SecretData, len(listed))
+		for _, secret := range listed {
+			byUID[secret.RecordUID] = secret
+			byTitle[secret.Title] = secret
+		}
+	}
+
+	data := make(map[string][]byte)
+	source := make(map[string]string)
+	for _, record := range records {
+		var fields map[string]interface{}
+		name := record.Name
+		if record.Notation != "" {
+			value, err := reader.GetNotation(ctx, record.Notation)
+			if err != nil {
+				return nil, fmt.Errorf("notation %s: %w", record.Notation, err)
+			}
+			fields = map[string]interface{}{"value": string(value)}
+			name = record.Notation
+		} else {
+			secret, ok := byUID[record.Name]
+			if !ok {
+				secret, ok = byTitle[record.Name]
+			}
+			if !ok {
+				return nil, fmt.Errorf("record %q not found", record.Name)
+			}
+			fields = secret.Fields
+		}
+
+		selected := sidecar.K8sSecretData(sidecar.SecretConfig{Fields: record.Fields, K8sSecretKeys: record.Keys}, fields)
+		for key, value := range selected {
+			if other, ok := source[key]; ok {
+				return nil, fmt.Errorf("key %q is set by both %q and %q", key, other, name)
+			}
+			source[key] = name
+			data[key] = value
+		}
+	}
+	return data, nil
+}
+
+// openKeeper creates a KSM client from the KeeperSecret's KSM config Secret
+func (r *KeeperSecretReconciler) openKeeper(ctx context.Context, ks *v1alpha1.KeeperSecret) (recordReader, func(), error) {
+	authSecret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: ks.Namespace, Name: ks.Spec.KSMConfig}
+	if err := r.Client.Get(ctx, key, authSecret); err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch KSM config secret %s: %w", ks.Spec.KSMConfig, err)
+	}
+	configData, ok := authSecret.Data["config"]
+	if !ok {
+		return nil, nil, fmt.Errorf("KSM config secret %s does not contain 'config' key", ks.Spec.KSMConfig)
+	}
+
+	ksmClient, err := ksm.NewClient(ctx, ksm.Config{
+		ConfigJSON: string(configData),
+		Logger:     r.logger,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create KSM client: %w", err)
+	}
+	return ksmClient, func() { _ = ksmClient.Close() }, nil
+}
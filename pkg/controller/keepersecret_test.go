@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/apis/v1alpha1"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeKeeper serves records from memory
+type fakeKeeper struct {
+	records []*ksm.SecretData
+}
+
+func (f *fakeKeeper) ListSecrets(context.Context) ([]*ksm.SecretData, error) {
+	return f.records, nil
+}
+
+func (f *fakeKeeper) GetNotation(_ context.Context, notation string) ([]byte, error) {
+	return []byte("value of " + notation), nil
+}
+
+func newTestReconciler(t *testing.T, keeper *fakeKeeper, objects ...client.Object) (*KeeperSecretReconciler, client.Client) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&v1alpha1.KeeperSecret{}).
+		Build()
+	r := NewKeeperSecretReconciler(fakeClient, scheme, zap.NewNop())
+	r.newReader = func(context.Context, *v1alpha1.KeeperSecret) (recordReader, func(), error) {
+		return keeper, func() {}, nil
+	}
+	return r, fakeClient
+}
+
+func newKeeperSecret(records ...v1alpha1.KeeperSecretRecord) *v1alpha1.KeeperSecret {
+	return &v1alpha1.KeeperSecret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "apps", UID: "ks-uid", Generation: 1},
+		Spec: v1alpha1.KeeperSecretSpec{
+			KSMConfig:       "keeper-auth",
+			Records:         records,
+			RefreshInterval: &metav1.Duration{Duration: 10 * time.Minute},
+		},
+	}
+}
+
+func reconcileKeeperSecret(t *testing.T, r *KeeperSecretReconciler) (ctrl.Result, *v1alpha1.KeeperSecret) {
+	t.Helper()
+	key := client.ObjectKey{Namespace: "apps", Name: "db"}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	ks := &v1alpha1.KeeperSecret{}
+	require.NoError(t, r.Client.Get(context.Background(), key, ks))
+	return result, ks
+}
+
+func TestReconcile_CreatesAndUpdatesSecret(t *testing.T) {
+	keeper := &fakeKeeper{records: []*ksm.SecretData{
+		{RecordUID: "AAAAAAAAAAAAAAAAAAAAAA", Title: "postgres", Fields: map[string]interface{}{"login": "admin", "password": "v1"}},
+	}}
+	r, c := newTestReconciler(t, keeper, newKeeperSecret(
+		v1alpha1.KeeperSecretRecord{Name: "postgres", Keys: map[string]string{"login": "DB_USER", "password": "DB_PASSWORD"}},
+		v1alpha1.KeeperSecretRecord{Notation: "keeper://AAAAAAAAAAAAAAAAAAAAAA/field/url", Keys: map[string]string{"value": "DB_URL"}},
+	))
+
+	result, ks := reconcileKeeperSecret(t, r)
+	assert.Equal(t, 10*time.Minute, result.RequeueAfter)
+	assert.True(t, meta.IsStatusConditionTrue(ks.Status.Conditions, v1alpha1.ConditionReady))
+	assert.Equal(t, "db", ks.Status.SecretName)
+	assert.Equal(t, int64(1), ks.Status.ObservedGeneration)
+	assert.NotNil(t, ks.Status.LastSyncTime)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "db"}, secret))
+	assert.Equal(t, "admin", string(secret.Data["DB_USER"]))
+	assert.Equal(t, "v1", string(secret.Data["DB_PASSWORD"]))
+	assert.Equal(t, "value of keeper://AAAAAAAAAAAAAAAAAAAAAA/field/url", string(secret.Data["DB_URL"]))
+	assert.Equal(t, corev1.SecretTypeOpaque, secret.Type)
+	assert.Equal(t, "db", secret.Labels[KeeperSecretLabel])
+	assert.True(t, metav1.IsControlledBy(secret, ks), "Secret is owned by the KeeperSecret, not a pod")
+
+	// The record changed in Keeper
+	keeper.records[0].Fields["password"] = "v2"
+	reconcileKeeperSecret(t, r)
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "db"}, secret))
+	assert.Equal(t, "v2", string(secret.Data["DB_PASSWORD"]))
+}
+
+func TestReconcile_ReportsFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		records    []v1alpha1.KeeperSecretRecord
+		existing   *corev1.Secret
+		wantReason string
+	}{
+		{
+			name:       "missing record",
+			records:    []v1alpha1.KeeperSecretRecord{{Name: "missing"}},
+			wantReason: v1alpha1.ReasonFetchFailed,
+		},
+		{
+			name:       "name and notation",
+			records:    []v1alpha1.KeeperSecretRecord{{Name: "postgres", Notation: "keeper://x/field/y"}},
+			wantReason: v1alpha1.ReasonInvalidSpec,
+		},
+		{
+			name:       "duplicate key",
+			records:    []v1alpha1.KeeperSecretRecord{{Name: "postgres"}, {Name: "postgres"}},
+			wantReason: v1alpha1.ReasonFetchFailed,
+		},
+		{
+			name:    "secret owned by someone else",
+			records: []v1alpha1.KeeperSecretRecord{{Name: "postgres"}},
+			existing: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "apps"},
+				Data:       map[string][]byte{"password": []byte("manual")},
+			},
+			wantReason: v1alpha1.ReasonSecretConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keeper := &fakeKeeper{records: []*ksm.SecretData{
+				{RecordUID: "AAAAAAAAAAAAAAAAAAAAAA", Title: "postgres", Fields: map[string]interface{}{"password": "v1"}},
+			}}
+			objects := []client.Object{newKeeperSecret(tt.records...)}
+			if tt.existing != nil {
+				objects = append(objects, tt.existing)
+			}
+			r, c := newTestReconciler(t, keeper, objects...)
+
+			result, ks := reconcileKeeperSecret(t, r)
+			assert.Equal(t, time.Minute, result.RequeueAfter, "failures are retried sooner")
+			condition := meta.FindStatusCondition(ks.Status.Conditions, v1alpha1.ConditionReady)
+			require.NotNil(t, condition)
+			assert.Equal(t, metav1.ConditionFalse, condition.Status)
+			assert.Equal(t, tt.wantReason, condition.Reason)
+			assert.Nil(t, ks.Status.LastSyncTime)
+
+			if tt.existing != nil {
+				secret := &corev1.Secret{}
+				require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(tt.existing), secret))
+				assert.Equal(t, "manual", string(secret.Data["password"]), "unowned Secret is left alone")
+			}
+		})
+	}
+}
@@ -66,6 +66,8 @@ type SecretData struct {
 	Fields map[string]interface{} `json:"fields"`
 	// Files contains file attachment metadata
 	Files []FileInfo `json:"files,omitempty"`
+	// FolderUID is the folder directly containing the record
+	FolderUID string `json:"folderUid,omitempty"`
 }
 
 // FileInfo represents a file attachment
@@ -257,6 +259,7 @@ func (c *Client) recordToSecretData(record *ksm.Record) (*SecretData, error) {
 		Title:     record.Title(),
 		Type:      record.Type(),
 		Fields:    make(map[string]interface{}),
+		FolderUID: recordFolderUID(record),
 	}
 
 	// Extract ALL standard fields from RecordDict["fields"]
@@ -340,6 +343,15 @@ func (c *Client) recordToSecretData(record *ksm.Record) (*SecretData, error) {
 	return data, nil
 }
 
+// recordFolderUID returns the folder directly containing a record: the
+// subfolder for records below a shared folder, otherwise the shared folder
+func recordFolderUID(record *ksm.Record) string {
+	if uid := record.InnerFolderUid(); uid != "" {
+		return uid
+	}
+	return record.FolderUid()
+}
+
 // looksLikeUID checks if a string looks like a KSM record UID
 func looksLikeUID(s string) bool {
 	// KSM UIDs are typically 22 characters, base64-like
@@ -379,6 +391,60 @@ func (c *Client) ListSecrets(ctx context.Context) ([]*SecretData, error) {
 	return secrets, nil
 }
 
+// GetSecretsByRefs returns the records referenced by UID or title. When only
+// UIDs are referenced, just those records are requested; titles can only be
+// matched against the full record list. Only matching records are converted.
+func (c *Client) GetSecretsByRefs(ctx context.Context, refs []string) ([]*SecretData, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(refs))
+	var uids []string
+	byTitle := false
+	for _, ref := range refs {
+		if wanted[ref] {
+			continue
+		}
+		wanted[ref] = true
+		if looksLikeUID(ref) {
+			uids = append(uids, ref)
+		} else {
+			byTitle = true
+		}
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+
+	c.logger.Debug("fetching secrets by reference",
+		zap.Int("refs", len(wanted)),
+		zap.Bool("byTitle", byTitle))
+
+	filter := uids
+	if byTitle {
+		filter = []string{}
+	}
+	records, err := c.sm.GetSecrets(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets: %w", err)
+	}
+
+	var secrets []*SecretData
+	for _, record := range records {
+		if !wanted[record.Uid] && !wanted[record.Title()] {
+			continue
+		}
+		secret, err := c.recordToSecretData(record)
+		if err != nil {
+			c.logger.Warn("failed to convert record", zap.String("uid", record.Uid), zap.Error(err))
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
+
 // GetNotation retrieves data using Keeper notation format
 // Notation format: keeper://UID/field/password or UID/field/password
 // Now supports folder paths: keeper://Production/Databases/mysql/field/password
@@ -598,6 +664,40 @@ func (c *Client) GetSecretsInFolder(ctx context.Context, folderUID string) ([]*S
 	return secrets, nil
 }
 
+// GetSecretsInFolders returns all secrets directly contained in any of the given folders.
+// Used with FolderTree.Subtree to list a folder and its subfolders in one API call.
+func (c *Client) GetSecretsInFolders(ctx context.Context, folderUIDs []string) ([]*SecretData, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	c.logger.Debug("fetching secrets in folders", zap.Int("folders", len(folderUIDs)))
+
+	wanted := make(map[string]bool, len(folderUIDs))
+	for _, uid := range folderUIDs {
+		wanted[uid] = true
+	}
+
+	records, err := c.sm.GetSecrets([]string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets: %w", err)
+	}
+
+	var secrets []*SecretData
+	for _, record := range records {
+		if !wanted[recordFolderUID(record)] {
+			continue
+		}
+		secret, err := c.recordToSecretData(record)
+		if err != nil {
+			c.logger.Warn("failed to convert record", zap.String("uid", record.Uid), zap.Error(err))
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
+
 // BuildFolderTree fetches all folders and builds a hierarchical tree
 func (c *Client) BuildFolderTree(ctx context.Context) (*FolderTree, error) {
 	c.mu.RLock()
@@ -771,6 +871,15 @@ func parseNotationPath(notation string) *notationParts {
 	return np
 }
 
+// GetTOTPCode returns the current code for a oneTimeCode field value (otpauth:// URL)
+func GetTOTPCode(totpURL string) (string, error) {
+	code, err := ksm.GetTotpCode(totpURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate TOTP code: %w", err)
+	}
+	return code.Code, nil
+}
+
 // Close releases any resources held by the client
 func (c *Client) Close() error {
 	// KSM SDK doesn't require explicit cleanup
@@ -150,3 +150,36 @@ func (t *FolderTree) ListPaths() []string {
 	}
 	return paths
 }
+
+// Subtree returns the UID of a folder followed by the UIDs of all its descendants.
+// Returns nil if the UID is not in the tree.
+func (t *FolderTree) Subtree(folderUID string) []string {
+	node, ok := t.folders[folderUID]
+	if !ok {
+		return nil
+	}
+
+	var uids []string
+	stack := []*FolderNode{node}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		uids = append(uids, current.UID)
+		stack = append(stack, current.Children...)
+	}
+	return uids
+}
+
+// RelativePath returns the path of a folder relative to one of its ancestors
+// (e.g., "Databases/MySQL" below "Production"). Returns "" with ok=true when
+// both UIDs are the same folder, and ok=false when ancestorUID is not an ancestor.
+func (t *FolderTree) RelativePath(ancestorUID, folderUID string) (string, bool) {
+	var pathParts []string
+	for current := t.folders[folderUID]; current != nil; current = current.Parent {
+		if current.UID == ancestorUID {
+			return strings.Join(pathParts, "/"), true
+		}
+		pathParts = append([]string{current.Name}, pathParts...) // Prepend
+	}
+	return "", false
+}
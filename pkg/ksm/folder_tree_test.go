@@ -261,3 +261,26 @@ func TestResolvePath_SpecialCharacters(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "child2", uid)
 }
+
+func TestSubtree(t *testing.T) {
+	tree := BuildFolderTree(createTestFolders())
+
+	assert.ElementsMatch(t, []string{"root1", "child1", "child2", "grandchild1", "grandchild2"}, tree.Subtree("root1"))
+	assert.Equal(t, []string{"grandchild1"}, tree.Subtree("grandchild1"))
+	assert.Nil(t, tree.Subtree("nonexistent"))
+}
+
+func TestRelativePath(t *testing.T) {
+	tree := BuildFolderTree(createTestFolders())
+
+	rel, ok := tree.RelativePath("root1", "grandchild1")
+	assert.True(t, ok)
+	assert.Equal(t, "Databases/MySQL", rel)
+
+	rel, ok = tree.RelativePath("root1", "root1")
+	assert.True(t, ok)
+	assert.Equal(t, "", rel)
+
+	_, ok = tree.RelativePath("root2", "grandchild1")
+	assert.False(t, ok, "Development is not an ancestor of MySQL")
+}
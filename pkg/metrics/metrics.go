@@ -91,6 +91,94 @@ var (
 		},
 	)
 
+	// SecretChangesTotal counts detected secret content changes
+	SecretChangesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sidecar",
+			Name:      "secret_changes_total",
+			Help:      "Total number of secret content changes detected during refresh",
+		},
+		[]string{"secret"},
+	)
+
+	// SecretLastChangedTimestamp tracks when each secret last changed
+	SecretLastChangedTimestamp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "sidecar",
+			Name:      "secret_last_changed_timestamp",
+			Help:      "Unix timestamp of the last detected content change per secret",
+		},
+		[]string{"secret"},
+	)
+
+	// HookExecutionsTotal counts post-refresh hook executions
+	HookExecutionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sidecar",
+			Name:      "hook_executions_total",
+			Help:      "Total number of post-refresh hook executions",
+		},
+		[]string{"hook", "result"},
+	)
+
+	// HookDuration tracks hook latency including retries
+	HookDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "sidecar",
+			Name:      "hook_duration_seconds",
+			Help:      "Time spent running post-refresh hooks, including retries",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"hook"},
+	)
+
+	// CacheLookupsTotal counts last-known-good cache lookups (hit ratio = hit / total)
+	CacheLookupsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sidecar",
+			Name:      "cache_lookups_total",
+			Help:      "Total number of last-known-good cache lookups",
+		},
+		[]string{"backend", "result"},
+	)
+
+	// CachePersistErrorsTotal counts failed writes to the on-disk cache
+	CachePersistErrorsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sidecar",
+			Name:      "cache_persist_errors_total",
+			Help:      "Total number of failed writes to the persistent secret cache",
+		},
+	)
+
+	// FolderFilesPrunedTotal counts files removed because their record left a synced folder
+	FolderFilesPrunedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sidecar",
+			Name:      "folder_files_pruned_total",
+			Help:      "Total number of folder output files removed because their record left the folder",
+		},
+		[]string{"folder"},
+	)
+
+	// WorkloadRestartsTotal counts rollouts started because a workload's Keeper data changed
+	WorkloadRestartsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "injector",
+			Name:      "workload_restarts_total",
+			Help:      "Total number of workload rollouts started because their Keeper data changed",
+		},
+		[]string{"namespace", "kind"},
+	)
+
 	// RefreshCyclesTotal counts refresh cycles
 	RefreshCyclesTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -126,6 +214,22 @@ func RecordSecretFetch(secretName string, success bool, duration float64) {
 	SecretFetchDuration.WithLabelValues(secretName).Observe(duration)
 }
 
+// RecordSecretChange records a detected secret content change
+func RecordSecretChange(secretName string) {
+	SecretChangesTotal.WithLabelValues(secretName).Inc()
+	SecretLastChangedTimestamp.WithLabelValues(secretName).SetToCurrentTime()
+}
+
+// RecordHookExecution records a post-refresh hook execution
+func RecordHookExecution(hookName string, success bool, duration float64) {
+	result := "success"
+	if !success {
+		result = "error"
+	}
+	HookExecutionsTotal.WithLabelValues(hookName, result).Inc()
+	HookDuration.WithLabelValues(hookName).Observe(duration)
+}
+
 // RecordRefreshCycle records a refresh cycle completion
 func RecordRefreshCycle(success bool) {
 	result := "success"
@@ -134,3 +238,27 @@ func RecordRefreshCycle(success bool) {
 	}
 	RefreshCyclesTotal.WithLabelValues(result).Inc()
 }
+
+// RecordCacheLookup records a last-known-good cache lookup
+func RecordCacheLookup(backend string, hit bool) {
+	result := "hit"
+	if !hit {
+		result = "miss"
+	}
+	CacheLookupsTotal.WithLabelValues(backend, result).Inc()
+}
+
+// RecordCachePersistError records a failed write to the persistent cache
+func RecordCachePersistError() {
+	CachePersistErrorsTotal.Inc()
+}
+
+// RecordFolderFilePruned records the removal of an orphaned folder output file
+func RecordFolderFilePruned(folder string) {
+	FolderFilesPrunedTotal.WithLabelValues(folder).Inc()
+}
+
+// RecordWorkloadRestart records a rollout started by restart-on-change
+func RecordWorkloadRestart(namespace, kind string) {
+	WorkloadRestartsTotal.WithLabelValues(namespace, kind).Inc()
+}
@@ -3,12 +3,14 @@ package sidecar
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"sync"
 	"syscall"
 	"time"
@@ -19,7 +21,6 @@ import (
 	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar/retry"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -34,19 +35,24 @@ const (
 
 // SecretConfig represents a single secret to fetch
 type SecretConfig struct {
-	Name     string   `json:"name"`
-	Path     string   `json:"path"`
-	Format   string   `json:"format"`
-	Template string   `json:"template,omitempty"` // Go template string for custom formatting
-	Fields   []string `json:"fields,omitempty"`
-	Notation string   `json:"notation,omitempty"` // Keeper notation (e.g., keeper://UID/field/password)
-	FileName string   `json:"fileName,omitempty"` // For file attachments
-	IsFile   bool     `json:"isFile,omitempty"`   // Whether this is a file attachment
+	Name         string   `json:"name"`
+	Path         string   `json:"path"`
+	Format       string   `json:"format"`
+	Template     string   `json:"template,omitempty"`     // Go template string for custom formatting
+	TemplateFile string   `json:"templateFile,omitempty"` // Mounted template file (overrides Template)
+	Fields       []string `json:"fields,omitempty"`
+	Notation     string   `json:"notation,omitempty"`  // Keeper notation (e.g., keeper://UID/field/password)
+	FileName     string   `json:"fileName,omitempty"`  // For file attachments
+	IsFile       bool     `json:"isFile,omitempty"`    // Whether this is a file attachment
+	EnvPrefix    string   `json:"envPrefix,omitempty"` // Env var name prefix (exec mode)
 
 	// K8s Secret injection (v0.9.0)
 	InjectAsK8sSecret bool              `json:"injectAsK8sSecret,omitempty"` // Enable K8s Secret injection
 	K8sSecretName     string            `json:"k8sSecretName,omitempty"`     // K8s Secret name
 	K8sSecretKeys     map[string]string `json:"k8sSecretKeys,omitempty"`     // Keeper field → Secret key mapping
+
+	// Hooks run after this secret's content changes
+	Hooks []HookConfig `json:"hooks,omitempty"`
 }
 
 // FolderConfig represents a folder to fetch all secrets from
@@ -54,6 +60,18 @@ type FolderConfig struct {
 	FolderUID  string `json:"folderUid,omitempty"`
 	FolderPath string `json:"folderPath,omitempty"`
 	OutputPath string `json:"outputPath"`
+	Prune      bool   `json:"prune"` // Remove files of records that left the folder
+
+	Format          string `json:"format,omitempty"`          // json (default), env, yaml, properties, ini
+	Template        string `json:"template,omitempty"`        // Per-record Go template (overrides Format)
+	FilenamePattern string `json:"filenamePattern,omitempty"` // File name template relative to OutputPath
+
+	Recursive bool         `json:"recursive,omitempty"` // Include subfolders, mirrored as subdirectories
+	Filter    RecordFilter `json:"filter,omitempty"`    // Records to include
+
+	// K8s Secret injection: one Secret per record named <prefix><sanitized title>
+	InjectAsK8sSecret   bool   `json:"injectAsK8sSecret,omitempty"`
+	K8sSecretNamePrefix string `json:"k8sSecretNamePrefix,omitempty"`
 }
 
 // AgentConfig holds the agent configuration
@@ -61,30 +79,51 @@ type AgentConfig struct {
 	Mode            Mode
 	Secrets         []SecretConfig
 	Folders         []FolderConfig
+	Templates       []TemplateConfig // Files rendered from several records
 	RefreshInterval time.Duration
 	FailOnError     bool
 	StrictLookup    bool
 	RefreshSignal   string
-	KSMConfig       string // Base64-encoded KSM config (for secret auth)
-	AuthMethod      string // Auth method: "secret" (default) or "oidc"
+	SignalProcess   string        // Process name that receives RefreshSignal (shared process namespace)
+	KSMConfig       string        // Base64-encoded KSM config (for secret auth)
+	AuthMethod      string        // Auth method: "secret" (default) or "oidc"
+	OutputRoots     []string      // Directories published atomically (default: /keeper/secrets)
+	SecretsAPI      bool          // Serve the local secrets API on a Unix socket (sidecar mode)
+	Hooks           []HookConfig  // Pod-level hooks run after any secret content changes
+	CacheDir        string        // Directory for the encrypted persistent cache (empty = memory only)
+	CacheMaxAge     time.Duration // Maximum age of cached values (0 = 24h)
 	Logger          *zap.Logger
 
+	// Template sandbox: env, expandenv and getHostByName are disabled unless allowed
+	AllowUnsafeTemplateFuncs bool
+
 	// K8s Secret rotation (v0.9.0)
 	K8sSecretRotation  bool   // Enable K8s Secret updates during rotation
 	K8sSecretNamespace string // Namespace for K8s Secrets (defaults to pod namespace)
+	K8sSecretMode      string // Conflict resolution mode of the Secrets (overwrite|merge|skip-if-exists|fail)
 }
 
 // Agent manages secret fetching and rotation
 type Agent struct {
-	config      *AgentConfig
-	ksmClient   *ksm.Client
-	k8sClient   kubernetes.Interface // For K8s Secret updates (v0.9.0)
-	logger      *zap.Logger
-	secretCache *cache.SecretCache
-	mu          sync.RWMutex
-	lastFetch   map[string]time.Time
-	healthy     bool
-	ready       bool
+	config          *AgentConfig
+	ksmClient       *ksm.Client
+	k8sClient       kubernetes.Interface // For K8s Secret updates (v0.9.0)
+	logger          *zap.Logger
+	secretCache     *cache.SecretCache
+	mu              sync.RWMutex
+	lastFetch       map[string]time.Time
+	digests         map[string][sha256.Size]byte // Content digest per output path
+	changes         []ChangeEvent                // Content changes detected during the current refresh
+	snapshots       map[string]map[string][]byte // Published files per output root (root → relative path → data)
+	dirtyRoots      map[string]bool              // Output roots that need a new generation
+	procRoot        string                       // Override for /proc (tests)
+	templateDigests map[string][sha256.Size]byte // Content digest per mounted template file
+	api             *secretsAPI                  // Local secrets API state (nil when disabled)
+	published       map[string]bool              // Paths published during the current refresh
+	folderFiles     map[string]map[string]string // Files owned per folder sync (path → record title)
+	orphans         []orphanedFile               // Folder files to remove in the current refresh
+	healthy         bool
+	ready           bool
 }
 
 // NewAgent creates a new secrets agent
@@ -107,15 +146,45 @@ func NewAgent(cfg *AgentConfig) (*Agent, error) {
 		cfg.Logger.Info("K8s Secret rotation enabled", zap.String("namespace", cfg.K8sSecretNamespace))
 	}
 
-	return &Agent{
+	agent := &Agent{
 		config:      cfg,
 		k8sClient:   k8sClient,
 		logger:      cfg.Logger,
-		secretCache: cache.NewSecretCache(24 * time.Hour),
+		secretCache: newSecretCache(cfg),
 		lastFetch:   make(map[string]time.Time),
+		digests:     make(map[string][sha256.Size]byte),
 		healthy:     true,
 		ready:       false,
-	}, nil
+	}
+	if cfg.SecretsAPI && cfg.Mode == ModeSidecar {
+		agent.api = newSecretsAPI(DefaultOutputRoot)
+	}
+	return agent, nil
+}
+
+// newSecretCache creates the last-known-good cache. A persistent cache is used when
+// CacheDir is set; if it cannot be opened the agent falls back to memory only.
+func newSecretCache(cfg *AgentConfig) *cache.SecretCache {
+	if cfg.CacheDir == "" {
+		return cache.NewSecretCache(cfg.CacheMaxAge)
+	}
+
+	key, err := cache.DeriveKey(cfg.KSMConfig)
+	if err == nil {
+		var persistent *cache.SecretCache
+		persistent, err = cache.NewPersistentSecretCache(cfg.CacheDir, key, cfg.CacheMaxAge)
+		if err == nil {
+			cfg.Logger.Info("using persistent secret cache",
+				zap.String("dir", cfg.CacheDir),
+				zap.Int("entries", persistent.Size()))
+			return persistent
+		}
+	}
+
+	cfg.Logger.Warn("persistent secret cache unavailable, using memory only",
+		zap.String("dir", cfg.CacheDir),
+		zap.Error(err))
+	return cache.NewSecretCache(cfg.CacheMaxAge)
 }
 
 // Run starts the agent in the configured mode
@@ -167,6 +236,11 @@ func (a *Agent) runSidecarMode(ctx context.Context) error {
 	// Start health server
 	go a.startHealthServer()
 
+	// Start the local secrets API next to it
+	if a.api != nil {
+		go a.startSecretsAPI(ctx)
+	}
+
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
@@ -175,8 +249,18 @@ func (a *Agent) runSidecarMode(ctx context.Context) error {
 	ticker := time.NewTicker(a.config.RefreshInterval)
 	defer ticker.Stop()
 
+	// Re-render when a mounted template file changes, without waiting for the next refresh
+	var templateWatch <-chan time.Time
+	if len(a.templateFiles()) > 0 {
+		a.templateFilesChanged()
+		watchTicker := time.NewTicker(templateWatchInterval)
+		defer watchTicker.Stop()
+		templateWatch = watchTicker.C
+	}
+
 	a.logger.Info("starting sidecar mode",
-		zap.Duration("refreshInterval", a.config.RefreshInterval))
+		zap.Duration("refreshInterval", a.config.RefreshInterval),
+		zap.String("refreshSignal", a.config.RefreshSignal))
 
 	for {
 		select {
@@ -189,21 +273,34 @@ func (a *Agent) runSidecarMode(ctx context.Context) error {
 			return nil
 
 		case <-ticker.C:
-			if err := a.fetchAllSecrets(ctx); err != nil {
-				a.logger.Error("secret refresh failed", zap.Error(err))
-				// Don't mark unhealthy on refresh failure - keep last good values
-			} else {
-				a.logger.Debug("secrets refreshed successfully")
-			}
+			a.refresh(ctx)
 
-			// Update K8s Secrets if rotation enabled (v0.9.0)
-			if err := a.updateK8sSecrets(ctx); err != nil {
-				a.logger.Error("K8s secret update failed", zap.Error(err))
+		case <-templateWatch:
+			if a.templateFilesChanged() {
+				a.refresh(ctx)
 			}
 		}
 	}
 }
 
+// refresh fetches all secrets, notifies the app of changes and updates K8s Secrets
+func (a *Agent) refresh(ctx context.Context) {
+	if err := a.fetchAllSecrets(ctx); err != nil {
+		a.logger.Error("secret refresh failed", zap.Error(err))
+		// Don't mark unhealthy on refresh failure - keep last good values
+	} else {
+		a.logger.Debug("secrets refreshed successfully")
+	}
+
+	// Notify the app if any secret content changed
+	a.handleChanges(ctx, a.takeChanges())
+
+	// Update K8s Secrets if rotation enabled (v0.9.0)
+	if err := a.updateK8sSecrets(ctx); err != nil {
+		a.logger.Error("K8s secret update failed", zap.Error(err))
+	}
+}
+
 // fetchAllSecrets fetches all configured secrets and folders
 func (a *Agent) fetchAllSecrets(ctx context.Context) error {
 	a.mu.Lock()
@@ -211,6 +308,8 @@ func (a *Agent) fetchAllSecrets(ctx context.Context) error {
 
 	var errors []error
 	totalSecrets := 0
+	a.changes = nil
+	a.published = make(map[string]bool)
 
 	// Fetch individual secrets
 	for _, secretCfg := range a.config.Secrets {
@@ -245,6 +344,20 @@ func (a *Agent) fetchAllSecrets(ctx context.Context) error {
 		}
 	}
 
+	// Render multi-record templates
+	count, templateErrs := a.fetchTemplates(ctx)
+	errors = append(errors, templateErrs...)
+	totalSecrets += count
+
+	// Remove files of records that left a synced folder
+	a.pruneOrphanedFiles()
+
+	// Publish all changed files in one atomic step per output root
+	if err := a.commitSnapshots(); err != nil {
+		a.logger.Error("failed to publish secrets", zap.Error(err))
+		errors = append(errors, err)
+	}
+
 	// Update metrics
 	metrics.SecretsActive.Set(float64(totalSecrets))
 	if len(errors) == 0 {
@@ -270,9 +383,17 @@ func (a *Agent) fetchSecret(ctx context.Context, cfg SecretConfig) error {
 		zap.String("notation", cfg.Notation),
 		zap.Bool("isFile", cfg.IsFile))
 
+	// Load the template from its mounted file, if any
+	template, err := loadTemplate(cfg.Template, cfg.TemplateFile)
+	if err != nil {
+		return err
+	}
+	cfg.Template = template
+
 	// Try to fetch with retry
 	var data []byte
-	err := retry.WithRetry(ctx, retry.DefaultConfig(), func() error {
+	var fields map[string]interface{} // Record fields served by the secrets API
+	err = retry.WithRetry(ctx, retry.DefaultConfig(), func() error {
 		var fetchErr error
 
 		// Handle different fetch modes
@@ -294,6 +415,7 @@ func (a *Agent) fetchSecret(ctx context.Context, cfg SecretConfig) error {
 			if fetchErr != nil {
 				return fmt.Errorf("failed to fetch field %s: %w", cfg.Fields[0], fetchErr)
 			}
+			fields = map[string]interface{}{cfg.Fields[0]: string(data)}
 
 		default:
 			secret, fetchErr := a.ksmClient.GetSecret(ctx, cfg.Name)
@@ -302,16 +424,21 @@ func (a *Agent) fetchSecret(ctx context.Context, cfg SecretConfig) error {
 			}
 
 			// Filter fields if specified
+			fields = secret.Fields
 			if len(cfg.Fields) > 0 {
-				filtered := make(map[string]interface{})
+				fields = make(map[string]interface{})
 				for _, f := range cfg.Fields {
 					if v, ok := secret.Fields[f]; ok {
-						filtered[f] = v
+						fields[f] = v
 					}
 				}
-				data, fetchErr = formatSecret(filtered, cfg)
+			}
+
+			if cfg.Template != "" {
+				rc := a.newRenderContext(ctx, cfg.Path, []*ksm.SecretData{secret})
+				data, fetchErr = renderTemplateWithContext(fields, cfg.Template, rc)
 			} else {
-				data, fetchErr = formatSecret(secret.Fields, cfg)
+				data, fetchErr = formatSecret(fields, cfg)
 			}
 
 			if fetchErr != nil {
@@ -331,7 +458,8 @@ func (a *Agent) fetchSecret(ctx context.Context, cfg SecretConfig) error {
 				zap.Duration("cache_age", age),
 				zap.Error(err))
 
-			return a.writeSecretFile(cfg.Path, cached.Data)
+			_, err := a.publishSecret(cfg.Name, cfg.Path, cached.Data)
+			return err
 		}
 
 		// No cache available
@@ -348,9 +476,13 @@ func (a *Agent) fetchSecret(ctx context.Context, cfg SecretConfig) error {
 
 	// Success - cache the data
 	a.secretCache.Set(cfg.Name, data)
+	if a.api != nil {
+		a.api.set(cfg.Name, fields, data)
+	}
 
-	// Write to file
-	return a.writeSecretFile(cfg.Path, data)
+	// Write to file (skipped when content is unchanged)
+	_, err = a.publishSecret(cfg.Name, cfg.Path, data)
+	return err
 }
 
 // fetchSecretsFromFolder fetches all secrets from a folder
@@ -360,52 +492,54 @@ func (a *Agent) fetchSecretsFromFolder(ctx context.Context, cfg FolderConfig) (i
 		zap.String("folderPath", cfg.FolderPath),
 		zap.String("outputPath", cfg.OutputPath))
 
-	// Resolve folder UID from path if needed
-	folderUID := cfg.FolderUID
-	if folderUID == "" && cfg.FolderPath != "" {
-		// Build folder tree to resolve path
-		tree, err := a.ksmClient.BuildFolderTree(ctx)
-		if err != nil {
-			return 0, fmt.Errorf("failed to build folder tree: %w", err)
-		}
-
-		// Resolve folder path to UID
-		resolvedUID, err := tree.ResolvePath(cfg.FolderPath)
-		if err != nil {
-			return 0, fmt.Errorf("failed to resolve folder path '%s': %w", cfg.FolderPath, err)
-		}
-
-		folderUID = resolvedUID
-		a.logger.Debug("resolved folder path to UID",
-			zap.String("folderPath", cfg.FolderPath),
-			zap.String("folderUID", folderUID))
-	}
-
-	if folderUID == "" {
-		return 0, fmt.Errorf("either folderUid or folderPath must be specified")
+	filenames, err := parseFilenamePattern(cfg.FilenamePattern, cfg.Format)
+	if err != nil {
+		return 0, err
 	}
 
-	secrets, err := a.ksmClient.GetSecretsInFolder(ctx, folderUID)
+	listing, err := ListFolderRecords(ctx, a.ksmClient, cfg)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get secrets from folder: %w", err)
+		return 0, err
 	}
+	a.logger.Debug("listed folder",
+		zap.String("folderPath", cfg.FolderPath),
+		zap.String("folderUID", listing.FolderUID),
+		zap.Int("records", len(listing.Records)))
 
 	// Write each secret to a file
 	count := 0
-	for _, secret := range secrets {
-		// Generate filename from title
-		filename := sanitizeFilename(secret.Title) + ".json"
-		path := filepath.Join(cfg.OutputPath, filename)
+	owned := make(map[string]string, len(listing.Records))
+	for _, secret := range listing.Records {
+		filename, err := folderFilename(filenames, secret)
+		if err != nil {
+			a.logger.Warn("failed to build file name for secret from folder",
+				zap.String("title", secret.Title),
+				zap.Error(err))
+			continue
+		}
+		subdir := ""
+		if cfg.Recursive {
+			subdir = folderSubdir(listing.Tree, listing.FolderUID, secret)
+		}
+		path := filepath.Join(cfg.OutputPath, subdir, filename)
+		if other, ok := owned[path]; ok {
+			a.logger.Warn("skipping secret from folder with duplicate file name",
+				zap.String("title", secret.Title),
+				zap.String("conflictsWith", other),
+				zap.String("path", path))
+			continue
+		}
+		owned[path] = secret.Title
 
-		data, err := json.MarshalIndent(secret.Fields, "", "  ")
+		data, err := a.formatFolderRecord(ctx, cfg, path, secret)
 		if err != nil {
-			a.logger.Warn("failed to marshal secret from folder",
+			a.logger.Warn("failed to format secret from folder",
 				zap.String("title", secret.Title),
 				zap.Error(err))
 			continue
 		}
 
-		if err := a.writeSecretFile(path, data); err != nil {
+		if _, err := a.publishSecret(secret.Title, path, data); err != nil {
 			a.logger.Warn("failed to write secret from folder",
 				zap.String("title", secret.Title),
 				zap.String("path", path),
@@ -414,6 +548,7 @@ func (a *Agent) fetchSecretsFromFolder(ctx context.Context, cfg FolderConfig) (i
 		}
 		count++
 	}
+	a.trackFolderFiles(cfg, owned)
 
 	a.logger.Debug("fetched secrets from folder",
 		zap.String("folderUID", cfg.FolderUID),
@@ -503,16 +638,23 @@ func formatSecret(data map[string]interface{}, cfg SecretConfig) ([]byte, error)
 
 // formatAsEnv formats data as environment variable file
 func formatAsEnv(data map[string]interface{}) []byte {
+	// Sort keys so unchanged data renders identically
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
 	var result []byte
-	for k, v := range data {
+	for _, k := range keys {
 		var value string
-		switch val := v.(type) {
+		switch val := data[k].(type) {
 		case string:
 			value = val
 		case []byte:
 			value = string(val)
 		default:
-			jsonBytes, _ := json.Marshal(v)
+			jsonBytes, _ := json.Marshal(val)
 			value = string(jsonBytes)
 		}
 		// Escape quotes and newlines for env format
@@ -564,122 +706,6 @@ func escapeEnvValue(value string) string {
 	return value
 }
 
-// updateK8sSecrets updates K8s Secrets during rotation (v0.9.0)
-func (a *Agent) updateK8sSecrets(ctx context.Context) error {
-	if a.k8sClient == nil {
-		return nil // Rotation not enabled
-	}
-
-	namespace := a.config.K8sSecretNamespace
-	if namespace == "" {
-		// Try to get namespace from pod environment
-		namespace = os.Getenv("POD_NAMESPACE")
-		if namespace == "" {
-			namespace = "default"
-		}
-	}
-
-	// Update K8s Secrets for each secret configured for K8s Secret injection
-	for _, secretCfg := range a.config.Secrets {
-		if !secretCfg.InjectAsK8sSecret || secretCfg.K8sSecretName == "" {
-			continue
-		}
-
-		// Fetch updated secret from KSM
-		var data *ksm.SecretData
-		var err error
-
-		if secretCfg.Notation != "" {
-			// Handle notation
-			notationData, notationErr := a.ksmClient.GetNotation(ctx, secretCfg.Notation)
-			if notationErr != nil {
-				a.logger.Error("failed to fetch notation for K8s Secret update",
-					zap.String("notation", secretCfg.Notation),
-					zap.Error(notationErr))
-				continue
-			}
-			data = &ksm.SecretData{
-				Fields: map[string]interface{}{
-					"value": string(notationData),
-				},
-			}
-		} else if secretCfg.IsFile {
-			// Handle file
-			fileData, fileErr := a.ksmClient.GetFileContent(ctx, secretCfg.Name, secretCfg.FileName)
-			if fileErr != nil {
-				a.logger.Error("failed to fetch file for K8s Secret update",
-					zap.String("name", secretCfg.Name),
-					zap.String("fileName", secretCfg.FileName),
-					zap.Error(fileErr))
-				continue
-			}
-			data = &ksm.SecretData{
-				Fields: map[string]interface{}{
-					secretCfg.FileName: fileData,
-				},
-			}
-		} else {
-			// Fetch regular secret
-			data, err = a.ksmClient.GetSecret(ctx, secretCfg.Name)
-			if err != nil {
-				a.logger.Error("failed to fetch secret for K8s Secret update",
-					zap.String("name", secretCfg.Name),
-					zap.Error(err))
-				continue
-			}
-		}
-
-		// Get existing K8s Secret
-		secret, err := a.k8sClient.CoreV1().Secrets(namespace).Get(ctx, secretCfg.K8sSecretName, metav1.GetOptions{})
-		if err != nil {
-			a.logger.Error("failed to get K8s Secret",
-				zap.String("name", secretCfg.K8sSecretName),
-				zap.Error(err))
-			continue
-		}
-
-		// Update Secret data
-		if secret.Data == nil {
-			secret.Data = make(map[string][]byte)
-		}
-
-		if len(secretCfg.K8sSecretKeys) > 0 {
-			// Custom key mapping
-			for keeperField, k8sKey := range secretCfg.K8sSecretKeys {
-				if value, ok := data.Fields[keeperField]; ok {
-					secret.Data[k8sKey] = valueToBytes(value)
-				}
-			}
-		} else if len(secretCfg.Fields) > 0 {
-			// Selected fields
-			for _, field := range secretCfg.Fields {
-				if value, ok := data.Fields[field]; ok {
-					secret.Data[field] = valueToBytes(value)
-				}
-			}
-		} else {
-			// All fields
-			for field, value := range data.Fields {
-				secret.Data[field] = valueToBytes(value)
-			}
-		}
-
-		// Update K8s Secret
-		_, err = a.k8sClient.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{})
-		if err != nil {
-			a.logger.Error("failed to update K8s Secret",
-				zap.String("name", secretCfg.K8sSecretName),
-				zap.Error(err))
-		} else {
-			a.logger.Info("updated K8s Secret",
-				zap.String("name", secretCfg.K8sSecretName),
-				zap.String("namespace", namespace))
-		}
-	}
-
-	return nil
-}
-
 // valueToBytes converts a value to []byte for K8s Secret
 func valueToBytes(value interface{}) []byte {
 	switch v := value.(type) {
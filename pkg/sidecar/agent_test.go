@@ -30,6 +30,22 @@ func TestFormatAsEnv(t *testing.T) {
 	}
 }
 
+func TestFormatAsEnv_Deterministic(t *testing.T) {
+	data := map[string]interface{}{
+		"username": "admin",
+		"password": "secret123",
+		"host":     "db.internal",
+		"port":     5432,
+	}
+
+	want := "HOST=db.internal\nPASSWORD=secret123\nPORT=5432\nUSERNAME=admin\n"
+	for i := 0; i < 20; i++ {
+		if got := string(formatAsEnv(data)); got != want {
+			t.Fatalf("formatAsEnv() = %q, want %q", got, want)
+		}
+	}
+}
+
 func TestToEnvKey(t *testing.T) {
 	tests := []struct {
 		input string
@@ -1,18 +1,25 @@
 package cache
 
 import (
+	"crypto/cipher"
 	"sync"
 	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/metrics"
 )
 
 // SecretCache stores last known good secret values in memory.
 // Thread-safe for concurrent access.
 // Secrets automatically expire after maxAge.
-// No disk persistence - cleared on pod restart.
+// Created with NewSecretCache it has no disk persistence and is cleared on restart;
+// NewPersistentSecretCache also keeps entries encrypted on disk.
 type SecretCache struct {
 	mu      sync.RWMutex
 	secrets map[string]*CachedSecret
 	maxAge  time.Duration
+	backend string      // "memory" or "disk" (metrics label)
+	dir     string      // Directory for encrypted entries (persistent caches only)
+	aead    cipher.AEAD // Entry encryption (persistent caches only)
 }
 
 // CachedSecret represents a secret value with timestamp.
@@ -31,14 +38,22 @@ func NewSecretCache(maxAge time.Duration) *SecretCache {
 	return &SecretCache{
 		secrets: make(map[string]*CachedSecret),
 		maxAge:  maxAge,
+		backend: "memory",
 	}
 }
 
 // Get retrieves a cached secret if it exists and is not expired.
 // Returns (secret, true) if found and valid, (nil, false) otherwise.
+// Each lookup is counted as a hit or miss in metrics.
 //
 // Thread-safe for concurrent reads.
 func (c *SecretCache) Get(name string) (*CachedSecret, bool) {
+	cached, ok := c.get(name)
+	metrics.RecordCacheLookup(c.backend, ok)
+	return cached, ok
+}
+
+func (c *SecretCache) get(name string) (*CachedSecret, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -56,15 +71,24 @@ func (c *SecretCache) Get(name string) (*CachedSecret, bool) {
 }
 
 // Set stores a secret in the cache with current timestamp.
+// Persistent caches also write the entry to disk; write failures are
+// counted in metrics and the in-memory entry is kept.
 // Thread-safe for concurrent writes.
 func (c *SecretCache) Set(name string, data []byte) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.secrets[name] = &CachedSecret{
+	cached := &CachedSecret{
 		Data:      data,
 		FetchedAt: time.Now(),
 	}
+	c.secrets[name] = cached
+
+	if c.aead != nil {
+		if err := c.persist(name, cached); err != nil {
+			metrics.RecordCachePersistError()
+		}
+	}
 }
 
 // Age returns how long ago a secret was fetched.
@@ -93,4 +117,7 @@ func (c *SecretCache) Clear() {
 	defer c.mu.Unlock()
 
 	c.secrets = make(map[string]*CachedSecret)
+	if c.aead != nil {
+		c.removeAll()
+	}
 }
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// entryExt is the file extension of persisted cache entries
+	entryExt = ".cache"
+	// keyInfo binds derived keys to this use of the KSM config
+	keyInfo = "keeper-injector secret cache v1"
+)
+
+// persistedEntry is the plaintext of an encrypted cache file
+type persistedEntry struct {
+	Name      string    `json:"name"`
+	Data      []byte    `json:"data"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// DeriveKey derives the 256-bit cache encryption key from the KSM config.
+// Every container with the same KSM config derives the same key, so a restarted
+// init container or sidecar can read entries written before the restart.
+func DeriveKey(ksmConfig string) ([]byte, error) {
+	if ksmConfig == "" {
+		return nil, fmt.Errorf("KSM config is empty")
+	}
+	return hkdf.Key(sha256.New, []byte(ksmConfig), nil, keyInfo, 32)
+}
+
+// NewPersistentSecretCache creates a secret cache that also keeps every entry
+// encrypted with AES-256-GCM under dir, so last known good values survive
+// container and pod restarts. Entries already in dir are loaded; entries older
+// than maxAge or encrypted with a different key are discarded.
+// maxAge: maximum age before secrets are considered stale (0 = 24 hours default).
+func NewPersistentSecretCache(dir string, key []byte, maxAge time.Duration) (*SecretCache, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache cipher: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	c := NewSecretCache(maxAge)
+	c.dir = dir
+	c.aead = aead
+	c.backend = "disk"
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load reads every valid entry from the cache directory into memory
+func (c *SecretCache) load() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), entryExt) {
+			continue
+		}
+		path := filepath.Join(c.dir, e.Name())
+		entry, err := c.readEntry(path)
+		if err != nil || time.Since(entry.FetchedAt) > c.maxAge {
+			// Unreadable (e.g. the KSM config changed) or stale: drop it
+			_ = os.Remove(path)
+			continue
+		}
+		c.secrets[entry.Name] = &CachedSecret{Data: entry.Data, FetchedAt: entry.FetchedAt}
+	}
+	return nil
+}
+
+// readEntry decrypts a single cache file
+func (c *SecretCache) readEntry(path string) (*persistedEntry, error) {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, fmt.Errorf("cache entry too short")
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(filepath.Base(path)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt cache entry: %w", err)
+	}
+
+	var entry persistedEntry
+	if err := json.Unmarshal(plaintext, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry: %w", err)
+	}
+	if filepath.Base(path) != entryFileName(entry.Name) {
+		return nil, fmt.Errorf("cache entry name mismatch")
+	}
+	return &entry, nil
+}
+
+// persist encrypts an entry and writes it atomically (temp file + rename)
+func (c *SecretCache) persist(name string, cached *CachedSecret) error {
+	plaintext, err := json.Marshal(persistedEntry{Name: name, Data: cached.Data, FetchedAt: cached.FetchedAt})
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	fileName := entryFileName(name)
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(fileName))
+
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(sealed); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.dir, fileName))
+}
+
+// removeAll deletes every persisted entry
+func (c *SecretCache) removeAll() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), entryExt) {
+			_ = os.Remove(filepath.Join(c.dir, e.Name()))
+		}
+	}
+}
+
+// entryFileName hashes the secret name so names with "/" or ":" map to flat file names
+func entryFileName(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:]) + entryExt
+}
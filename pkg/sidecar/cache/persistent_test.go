@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T, ksmConfig string) []byte {
+	t.Helper()
+	key, err := DeriveKey(ksmConfig)
+	if err != nil {
+		t.Fatalf("DeriveKey() error = %v", err)
+	}
+	return key
+}
+
+func TestDeriveKey(t *testing.T) {
+	a := testKey(t, "config-a")
+	if len(a) != 32 {
+		t.Errorf("key length = %d, want 32", len(a))
+	}
+	if !bytes.Equal(a, testKey(t, "config-a")) {
+		t.Error("same KSM config should derive the same key")
+	}
+	if bytes.Equal(a, testKey(t, "config-b")) {
+		t.Error("different KSM configs should derive different keys")
+	}
+	if _, err := DeriveKey(""); err == nil {
+		t.Error("expected error for empty KSM config")
+	}
+}
+
+func TestPersistentSecretCache_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	key := testKey(t, "config")
+
+	cache, err := NewPersistentSecretCache(dir, key, time.Hour)
+	if err != nil {
+		t.Fatalf("NewPersistentSecretCache() error = %v", err)
+	}
+	cache.Set("template:app", []byte("db-password"))
+
+	// A restarted container opens the same directory
+	restarted, err := NewPersistentSecretCache(dir, key, time.Hour)
+	if err != nil {
+		t.Fatalf("NewPersistentSecretCache() error = %v", err)
+	}
+	cached, ok := restarted.Get("template:app")
+	if !ok {
+		t.Fatal("expected secret to survive restart")
+	}
+	if string(cached.Data) != "db-password" {
+		t.Errorf("cached data = %q, want %q", cached.Data, "db-password")
+	}
+	if restarted.Age("template:app") <= 0 {
+		t.Error("fetch time should be preserved across restart")
+	}
+}
+
+func TestPersistentSecretCache_EncryptedAtRest(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewPersistentSecretCache(dir, testKey(t, "config"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewPersistentSecretCache() error = %v", err)
+	}
+	cache.Set("db", []byte("super-secret-value"))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+entryExt))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one cache file, got %v (err %v)", files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(content, []byte("super-secret-value")) || bytes.Contains(content, []byte(`"db"`)) {
+		t.Error("cache file should not contain plaintext")
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("cache file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestPersistentSecretCache_WrongKeyDiscarded(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewPersistentSecretCache(dir, testKey(t, "old-config"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewPersistentSecretCache() error = %v", err)
+	}
+	cache.Set("db", []byte("value"))
+
+	rotated, err := NewPersistentSecretCache(dir, testKey(t, "new-config"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewPersistentSecretCache() error = %v", err)
+	}
+	if _, ok := rotated.Get("db"); ok {
+		t.Error("entry encrypted with another key should not be readable")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+entryExt))
+	if len(files) != 0 {
+		t.Errorf("unreadable entries should be removed, found %v", files)
+	}
+}
+
+func TestPersistentSecretCache_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+	key := testKey(t, "config")
+
+	cache, err := NewPersistentSecretCache(dir, key, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewPersistentSecretCache() error = %v", err)
+	}
+	cache.Set("db", []byte("value"))
+	time.Sleep(150 * time.Millisecond)
+
+	restarted, err := NewPersistentSecretCache(dir, key, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewPersistentSecretCache() error = %v", err)
+	}
+	if restarted.Size() != 0 {
+		t.Errorf("expired entries should not be loaded, got size %d", restarted.Size())
+	}
+}
+
+func TestPersistentSecretCache_Clear(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewPersistentSecretCache(dir, testKey(t, "config"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewPersistentSecretCache() error = %v", err)
+	}
+	cache.Set("db", []byte("value"))
+	cache.Clear()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+entryExt))
+	if len(files) != 0 {
+		t.Errorf("Clear() should remove persisted entries, found %v", files)
+	}
+}
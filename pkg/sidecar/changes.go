@@ -0,0 +1,115 @@
+package sidecar
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// ChangeEvent records that the content of a secret output changed
+type ChangeEvent struct {
+	// Secret is the record name/title the output was rendered from
+	Secret string
+	// Path is the output file that changed
+	Path string
+	// Time is when the change was detected
+	Time time.Time
+}
+
+// publishSecret stages data for path in the next snapshot and marks the
+// snapshot for writing only when the content differs from what was last
+// published there. Records a ChangeEvent for real changes.
+// Returns true if the content changed.
+//
+// The first publish of a path compares against the file on disk so that the
+// sidecar does not rewrite files the init container has just written.
+// A file that did not exist before is a new output, not a change.
+func (a *Agent) publishSecret(secret, path string, data []byte) (bool, error) {
+	if a.digests == nil {
+		a.digests = make(map[string][sha256.Size]byte)
+	}
+	if a.published == nil {
+		a.published = make(map[string]bool)
+	}
+	a.published[path] = true
+
+	digest := sha256.Sum256(data)
+	prev, seen := a.digests[path]
+	if !seen {
+		existing, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			seen = true
+			prev = sha256.Sum256(existing)
+		case !os.IsNotExist(err):
+			return false, fmt.Errorf("failed to read existing file %s: %w", path, err)
+		}
+	}
+
+	if seen && prev == digest {
+		a.digests[path] = digest
+		a.stageSecret(path, data, false)
+		a.logger.Debug("secret unchanged, skipping write", zap.String("path", path))
+		return false, nil
+	}
+
+	a.stageSecret(path, data, true)
+	a.digests[path] = digest
+
+	if !seen {
+		return false, nil
+	}
+
+	a.recordChange(ChangeEvent{Secret: secret, Path: path, Time: time.Now()})
+	return true, nil
+}
+
+// recordChange queues a change event for the current refresh cycle
+func (a *Agent) recordChange(event ChangeEvent) {
+	a.changes = append(a.changes, event)
+	metrics.RecordSecretChange(event.Secret)
+	a.logger.Info("secret content changed",
+		zap.String("secret", event.Secret),
+		zap.String("path", event.Path))
+}
+
+// takeChanges returns and clears the change events of the last refresh
+func (a *Agent) takeChanges() []ChangeEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	changes := a.changes
+	a.changes = nil
+	return changes
+}
+
+// handleChanges reacts to secret content changes after a refresh cycle
+func (a *Agent) handleChanges(ctx context.Context, changes []ChangeEvent) {
+	if len(changes) == 0 {
+		return
+	}
+
+	a.logger.Info("secrets changed during refresh",
+		zap.Int("changed", len(changes)),
+		zap.Strings("paths", changedPaths(changes)))
+
+	if err := a.signalApplication(); err != nil {
+		a.logger.Error("failed to signal application", zap.Error(err))
+	}
+
+	a.runHooks(ctx, changes)
+}
+
+// changedPaths lists the output paths of the given events
+func changedPaths(changes []ChangeEvent) []string {
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	return paths
+}
@@ -0,0 +1,94 @@
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPublishSecret_NewFileIsNotAChange(t *testing.T) {
+	agent := &Agent{logger: zap.NewNop()}
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	changed, err := agent.publishSecret("db", path, []byte("v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	assert.False(t, changed)
+	assert.Empty(t, agent.changes)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+}
+
+func TestPublishSecret_UnchangedSkipsWrite(t *testing.T) {
+	agent := &Agent{logger: zap.NewNop()}
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	_, err := agent.publishSecret("db", path, []byte("v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	changed, err := agent.publishSecret("db", path, []byte("v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	assert.False(t, changed)
+	assert.Empty(t, agent.changes)
+
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(before, after), "unchanged content must not replace the file")
+	assert.Equal(t, before.ModTime(), after.ModTime())
+}
+
+func TestPublishSecret_ChangeRecordsEvent(t *testing.T) {
+	agent := &Agent{logger: zap.NewNop()}
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	_, err := agent.publishSecret("db", path, []byte("v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+
+	changed, err := agent.publishSecret("db", path, []byte("v2"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	assert.True(t, changed)
+	require.Len(t, agent.changes, 1)
+	assert.Equal(t, "db", agent.changes[0].Secret)
+	assert.Equal(t, path, agent.changes[0].Path)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+
+	// takeChanges drains the queue
+	assert.Len(t, agent.takeChanges(), 1)
+	assert.Empty(t, agent.changes)
+}
+
+func TestPublishSecret_ComparesAgainstExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	require.NoError(t, os.WriteFile(path, []byte("from-init"), 0400))
+
+	// Fresh agent (sidecar start) with identical content: no rewrite, no change
+	agent := &Agent{logger: zap.NewNop()}
+	changed, err := agent.publishSecret("db", path, []byte("from-init"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	assert.False(t, changed)
+
+	// Fresh agent with different content: rotated between init and sidecar start
+	agent = &Agent{logger: zap.NewNop()}
+	changed, err = agent.publishSecret("db", path, []byte("rotated"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	assert.True(t, changed)
+}
@@ -0,0 +1,366 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar/retry"
+	"go.uber.org/zap"
+)
+
+// execGracePeriod is how long the app may take to stop before it is killed on restart
+const execGracePeriod = 10 * time.Second
+
+// wrapperEnv holds the wrapper's own variables, which the app does not inherit
+var wrapperEnv = []string{"KEEPER_CONFIG", "KEEPER_AUTH_CONFIG"}
+
+// execve replaces the current process; a variable so tests can stub it
+var execve = syscall.Exec
+
+// ExecConfig holds configuration for exec mode
+type ExecConfig struct {
+	Command         []string       // Original command and args of the app container
+	Secrets         []SecretConfig // Secrets loaded into the environment
+	FailOnError     bool
+	StrictLookup    bool
+	KSMConfig       string
+	AuthMethod      string
+	RestartOnChange bool          // Stay the parent and restart the app when values change
+	RefreshInterval time.Duration // How often values are checked with RestartOnChange
+	Logger          *zap.Logger
+}
+
+// envReader is the part of the KSM client exec mode reads from
+type envReader interface {
+	GetSecret(ctx context.Context, nameOrUID string) (*ksm.SecretData, error)
+	GetNotation(ctx context.Context, notation string) ([]byte, error)
+}
+
+// RunExec loads the secrets into the environment and runs the command. Without
+// RestartOnChange the wrapper is replaced by the command and RunExec only
+// returns on error. Otherwise the wrapper stays the parent: it forwards
+// signals, restarts the command when values change and returns its exit code.
+func RunExec(ctx context.Context, cfg ExecConfig) (int, error) {
+	if len(cfg.Command) == 0 {
+		return 0, fmt.Errorf("exec mode requires a command after --")
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	authMethod := ksm.AuthMethodSecret
+	if cfg.AuthMethod == "oidc" {
+		authMethod = ksm.AuthMethodOIDC
+	}
+	client, err := ksm.NewClient(ctx, ksm.Config{
+		ConfigJSON:  cfg.KSMConfig,
+		AuthMethod:  authMethod,
+		StrictMatch: cfg.StrictLookup,
+		Logger:      logger,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create KSM client: %w", err)
+	}
+
+	load := func(ctx context.Context) (map[string]string, error) {
+		return buildExecEnv(ctx, client, cfg.Secrets, cfg.FailOnError, logger)
+	}
+
+	var values map[string]string
+	err = retry.WithRetry(ctx, retry.DefaultConfig(), func() error {
+		var loadErr error
+		values, loadErr = load(ctx)
+		return loadErr
+	})
+	if err != nil {
+		_ = client.Close()
+		return 0, fmt.Errorf("failed to load secrets: %w", err)
+	}
+	logger.Info("loaded secrets into the environment", zap.Int("varCount", len(values)))
+
+	if !cfg.RestartOnChange {
+		_ = client.Close()
+		return 0, execCommand(cfg.Command, childEnv(os.Environ(), values))
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	interval := cfg.RefreshInterval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	s := &execSupervisor{
+		command:  cfg.Command,
+		load:     load,
+		interval: interval,
+		grace:    execGracePeriod,
+		logger:   logger,
+	}
+	return s.run(ctx, values)
+}
+
+// buildExecEnv fetches the secrets and names their values the way env var
+// injection in the webhook does
+func buildExecEnv(ctx context.Context, reader envReader, secrets []SecretConfig, failOnError bool, logger *zap.Logger) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, s := range secrets {
+		if s.Notation != "" {
+			value, err := reader.GetNotation(ctx, s.Notation)
+			if err != nil {
+				if failOnError {
+					return nil, fmt.Errorf("notation query for %s failed: %w", s.Name, err)
+				}
+				logger.Warn("notation query failed, skipping secret", zap.String("secret", s.Name), zap.Error(err))
+				continue
+			}
+			env[toEnvKey(s.EnvPrefix+s.Name)] = string(value)
+			continue
+		}
+
+		secret, err := reader.GetSecret(ctx, s.Name)
+		if err != nil {
+			if failOnError {
+				return nil, fmt.Errorf("failed to fetch secret %s: %w", s.Name, err)
+			}
+			logger.Warn("failed to fetch secret, skipping", zap.String("secret", s.Name), zap.Error(err))
+			continue
+		}
+
+		if len(s.Fields) == 0 {
+			for field, value := range secret.Fields {
+				env[toEnvKey(s.EnvPrefix+field)] = string(valueToBytes(value))
+			}
+			continue
+		}
+		for _, field := range s.Fields {
+			value, ok := secret.Fields[field]
+			if !ok {
+				if failOnError {
+					return nil, fmt.Errorf("field %s not found in secret %s", field, s.Name)
+				}
+				logger.Warn("field not found in secret", zap.String("field", field), zap.String("secret", s.Name))
+				continue
+			}
+			env[toEnvKey(s.EnvPrefix+field)] = string(valueToBytes(value))
+		}
+	}
+	return env, nil
+}
+
+// childEnv returns the app's environment: the wrapper's own minus its
+// configuration, with the secret values taking precedence
+func childEnv(base []string, values map[string]string) []string {
+	env := make([]string, 0, len(base)+len(values))
+	for _, kv := range base {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, ok := values[name]; ok {
+			continue
+		}
+		isWrapperEnv := false
+		for _, w := range wrapperEnv {
+			if name == w {
+				isWrapperEnv = true
+				break
+			}
+		}
+		if !isWrapperEnv {
+			env = append(env, kv)
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+values[name])
+	}
+	return env
+}
+
+// execCommand replaces the wrapper with the command
+func execCommand(command []string, env []string) error {
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return fmt.Errorf("command %s not found: %w", command[0], err)
+	}
+	if err := execve(path, command, env); err != nil {
+		return fmt.Errorf("failed to exec %s: %w", path, err)
+	}
+	return nil
+}
+
+// execSupervisor runs the command as a child and restarts it when the
+// secret values change
+type execSupervisor struct {
+	command  []string
+	load     func(ctx context.Context) (map[string]string, error)
+	interval time.Duration
+	grace    time.Duration
+	logger   *zap.Logger
+}
+
+// run supervises the command until it exits on its own or ctx is done and
+// returns its exit code
+func (s *execSupervisor) run(ctx context.Context, values map[string]string) (int, error) {
+	signals := make(chan os.Signal, 4)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		cmd := exec.Command(s.command[0], s.command[1:]...)
+		cmd.Env = childEnv(os.Environ(), values)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			return 0, fmt.Errorf("failed to start %s: %w", s.command[0], err)
+		}
+		exited := make(chan error, 1)
+		go func() {
+			exited <- cmd.Wait()
+		}()
+
+		restart := false
+		for !restart {
+			select {
+			case err := <-exited:
+				return exitCode(err), nil
+
+			case sig := <-signals:
+				// The app decides how to handle it; if it exits, so does the wrapper
+				_ = cmd.Process.Signal(sig)
+
+			case <-ctx.Done():
+				return exitCode(s.stop(cmd, exited)), nil
+
+			case <-ticker.C:
+				next, err := s.load(ctx)
+				if err != nil {
+					s.logger.Warn("failed to refresh secrets, keeping the running process", zap.Error(err))
+					continue
+				}
+				if maps.Equal(next, values) {
+					continue
+				}
+				s.logger.Info("secret values changed, restarting the process")
+				s.stop(cmd, exited)
+				values = next
+				restart = true
+			}
+		}
+	}
+}
+
+// stop sends SIGTERM to the child and kills it after the grace period
+func (s *execSupervisor) stop(cmd *exec.Cmd, exited <-chan error) error {
+	_ = cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(s.grace):
+		s.logger.Warn("process did not stop in time, killing it", zap.Duration("gracePeriod", s.grace))
+		_ = cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+// exitCode returns a shell-style exit code for the result of cmd.Wait
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+// InstallExecWrapper copies the running binary to path, for app containers
+// that use it as their entrypoint wrapper
+func InstallExecWrapper(path string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate own binary: %w", err)
+	}
+	src, err := os.Open(self)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", self, err)
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("failed to copy binary: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to install %s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteExecCredentials leaves the KSM config for the exec wrappers. Each file
+// lives in a directory of its own that only one app container mounts, and
+// is readable by any user because the wrapper runs as the app's user.
+func WriteExecCredentials(paths []string, ksmConfig string) error {
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := os.WriteFile(path, []byte(ksmConfig), 0444); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		// WriteFile applies the umask
+		if err := os.Chmod(path, 0444); err != nil {
+			return fmt.Errorf("failed to set permissions of %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// ReadExecCredentials reads the KSM config the init container left for the
+// wrapper. The file is kept, because init containers do not run again when
+// the app container restarts.
+func ReadExecCredentials(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read KSM credentials: %w", err)
+	}
+	return string(data), nil
+}
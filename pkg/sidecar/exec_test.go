@@ -0,0 +1,201 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeEnvReader struct {
+	secrets   map[string]*ksm.SecretData
+	notations map[string]string
+}
+
+func (f *fakeEnvReader) GetSecret(_ context.Context, name string) (*ksm.SecretData, error) {
+	if s, ok := f.secrets[name]; ok {
+		return s, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+func (f *fakeEnvReader) GetNotation(_ context.Context, notation string) ([]byte, error) {
+	if v, ok := f.notations[notation]; ok {
+		return []byte(v), nil
+	}
+	return nil, errors.New("notation not found")
+}
+
+func TestBuildExecEnv(t *testing.T) {
+	reader := &fakeEnvReader{
+		secrets: map[string]*ksm.SecretData{
+			"db": {Fields: map[string]interface{}{"login": "admin", "password": "s3cret", "port": 5432}},
+		},
+		notations: map[string]string{"keeper://abc/field/password": "api-key"},
+	}
+	secrets := []SecretConfig{
+		{Name: "db", EnvPrefix: "DB_"},
+		{Name: "api-token", Notation: "keeper://abc/field/password"},
+	}
+
+	env, err := buildExecEnv(context.Background(), reader, secrets, true, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"DB_LOGIN":    "admin",
+		"DB_PASSWORD": "s3cret",
+		"DB_PORT":     "5432",
+		"API_TOKEN":   "api-key",
+	}, env)
+
+	env, err = buildExecEnv(context.Background(), reader, []SecretConfig{{Name: "db", Fields: []string{"password"}}}, true, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"PASSWORD": "s3cret"}, env)
+}
+
+func TestBuildExecEnv_MissingSecret(t *testing.T) {
+	reader := &fakeEnvReader{}
+	secrets := []SecretConfig{{Name: "missing"}}
+
+	_, err := buildExecEnv(context.Background(), reader, secrets, true, zap.NewNop())
+	assert.ErrorContains(t, err, "failed to fetch secret missing")
+
+	env, err := buildExecEnv(context.Background(), reader, secrets, false, zap.NewNop())
+	require.NoError(t, err)
+	assert.Empty(t, env)
+}
+
+func TestChildEnv(t *testing.T) {
+	base := []string{"PATH=/usr/bin", "KEEPER_CONFIG={}", "KEEPER_AUTH_CONFIG=creds", "DB_PASSWORD=old"}
+
+	env := childEnv(base, map[string]string{"DB_PASSWORD": "new", "API_KEY": "k"})
+	assert.Equal(t, []string{"PATH=/usr/bin", "API_KEY=k", "DB_PASSWORD=new"}, env)
+}
+
+func TestExecCommand(t *testing.T) {
+	var gotPath string
+	var gotArgv, gotEnv []string
+	original := execve
+	execve = func(path string, argv []string, env []string) error {
+		gotPath, gotArgv, gotEnv = path, argv, env
+		return nil
+	}
+	t.Cleanup(func() { execve = original })
+
+	require.NoError(t, execCommand([]string{"sh", "-c", "true"}, []string{"A=b"}))
+	assert.True(t, filepath.IsAbs(gotPath), "command is resolved on PATH")
+	assert.Equal(t, []string{"sh", "-c", "true"}, gotArgv)
+	assert.Equal(t, []string{"A=b"}, gotEnv)
+
+	assert.ErrorContains(t, execCommand([]string{"no-such-command-keeper"}, nil), "not found")
+}
+
+func TestExecSupervisor_RestartsOnChange(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	var mu sync.Mutex
+	values := map[string]string{"APP_VALUE": "v1"}
+	s := &execSupervisor{
+		command: []string{"sh", "-c", `echo "$APP_VALUE" >> "$0"; exec sleep 30`, out},
+		load: func(context.Context) (map[string]string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return map[string]string{"APP_VALUE": values["APP_VALUE"]}, nil
+		},
+		interval: 20 * time.Millisecond,
+		grace:    time.Second,
+		logger:   zap.NewNop(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int, 1)
+	go func() {
+		code, err := s.run(ctx, map[string]string{"APP_VALUE": "v1"})
+		assert.NoError(t, err)
+		done <- code
+	}()
+
+	waitForLines(t, out, []string{"v1"})
+	mu.Lock()
+	values["APP_VALUE"] = "v2"
+	mu.Unlock()
+	waitForLines(t, out, []string{"v1", "v2"})
+
+	cancel()
+	select {
+	case code := <-done:
+		assert.Equal(t, 143, code, "terminated by SIGTERM")
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not stop")
+	}
+}
+
+func TestExecSupervisor_ExitCode(t *testing.T) {
+	s := &execSupervisor{
+		command: []string{"sh", "-c", "exit 3"},
+		load: func(context.Context) (map[string]string, error) {
+			return nil, nil
+		},
+		interval: time.Minute,
+		grace:    time.Second,
+		logger:   zap.NewNop(),
+	}
+
+	code, err := s.run(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+}
+
+func TestInstallExecWrapper(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bin", "keeper-agent")
+
+	require.NoError(t, InstallExecWrapper(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	assert.Positive(t, info.Size())
+}
+
+func TestExecCredentials(t *testing.T) {
+	root := t.TempDir()
+	app := filepath.Join(root, "app", "ksm-config")
+	worker := filepath.Join(root, "worker", "ksm-config")
+
+	require.NoError(t, WriteExecCredentials([]string{app, worker}, `{"clientId":"abc"}`))
+	info, err := os.Stat(app)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm(), "a wrapper running as any user can read the file")
+
+	// The app container restarts without the init container running again
+	for start := 1; start <= 2; start++ {
+		config, err := ReadExecCredentials(app)
+		require.NoError(t, err, "start %d", start)
+		assert.Equal(t, `{"clientId":"abc"}`, config)
+	}
+	_, err = os.Stat(worker)
+	assert.NoError(t, err)
+
+	_, err = ReadExecCredentials(filepath.Join(root, "missing", "ksm-config"))
+	assert.ErrorContains(t, err, "failed to read KSM credentials")
+}
+
+func waitForLines(t *testing.T, path string, want []string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(path)
+		if strings.Join(want, "\n")+"\n" == string(data) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	data, _ := os.ReadFile(path)
+	t.Fatalf("%s = %q, want lines %v", path, data, want)
+}
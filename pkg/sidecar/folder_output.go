@@ -0,0 +1,217 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+)
+
+// RecordFilter selects folder records. All set conditions must match.
+type RecordFilter struct {
+	Types      []string          `json:"types,omitempty"`      // Record types to include
+	Title      string            `json:"title,omitempty"`      // Glob matched against the title
+	TitleRegex string            `json:"titleRegex,omitempty"` // Regular expression matched against the title
+	Labels     map[string]string `json:"labels,omitempty"`     // Field labels and required values
+}
+
+// recordMatcher is a compiled RecordFilter
+type recordMatcher struct {
+	filter     RecordFilter
+	titleRegex *regexp.Regexp
+}
+
+// compile prepares the filter for matching
+func (f RecordFilter) compile() (*recordMatcher, error) {
+	m := &recordMatcher{filter: f}
+	if _, err := path.Match(f.Title, ""); err != nil {
+		return nil, fmt.Errorf("invalid title filter %q: %w", f.Title, err)
+	}
+	if f.TitleRegex != "" {
+		re, err := regexp.Compile(f.TitleRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title regex filter: %w", err)
+		}
+		m.titleRegex = re
+	}
+	return m, nil
+}
+
+// matches reports whether a record passes the filter
+func (m *recordMatcher) matches(secret *ksm.SecretData) bool {
+	if len(m.filter.Types) > 0 && !slices.Contains(m.filter.Types, secret.Type) {
+		return false
+	}
+	if m.filter.Title != "" {
+		if ok, _ := path.Match(m.filter.Title, secret.Title); !ok {
+			return false
+		}
+	}
+	if m.titleRegex != nil && !m.titleRegex.MatchString(secret.Title) {
+		return false
+	}
+	for label, want := range m.filter.Labels {
+		if value, ok := secret.Fields[label]; !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// FolderListing is the result of ListFolderRecords
+type FolderListing struct {
+	FolderUID string            // Resolved folder UID
+	Tree      *ksm.FolderTree   // Folder tree; nil unless the folder path or subfolders were resolved
+	Records   []*ksm.SecretData // Records passing the folder's filter
+}
+
+// ListFolderRecords resolves a folder by UID or path and returns its records
+// that pass the folder's filter, including subfolders when Recursive is set.
+// Shared by folder sync and the webhook's folder-to-Secret injection.
+func ListFolderRecords(ctx context.Context, client *ksm.Client, cfg FolderConfig) (*FolderListing, error) {
+	matcher, err := cfg.Filter.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	listing := &FolderListing{FolderUID: cfg.FolderUID}
+
+	// Resolve folder UID from path if needed; recursive syncs need the tree too
+	if (listing.FolderUID == "" && cfg.FolderPath != "") || cfg.Recursive {
+		listing.Tree, err = client.BuildFolderTree(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build folder tree: %w", err)
+		}
+	}
+	if listing.FolderUID == "" && cfg.FolderPath != "" {
+		listing.FolderUID, err = listing.Tree.ResolvePath(cfg.FolderPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve folder path '%s': %w", cfg.FolderPath, err)
+		}
+	}
+	if listing.FolderUID == "" {
+		return nil, fmt.Errorf("either folderUid or folderPath must be specified")
+	}
+
+	var secrets []*ksm.SecretData
+	if cfg.Recursive {
+		folderUIDs := listing.Tree.Subtree(listing.FolderUID)
+		if len(folderUIDs) == 0 {
+			return nil, fmt.Errorf("folder %s not found", listing.FolderUID)
+		}
+		secrets, err = client.GetSecretsInFolders(ctx, folderUIDs)
+	} else {
+		secrets, err = client.GetSecretsInFolder(ctx, listing.FolderUID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secrets from folder: %w", err)
+	}
+
+	for _, secret := range secrets {
+		if matcher.matches(secret) {
+			listing.Records = append(listing.Records, secret)
+		}
+	}
+	return listing, nil
+}
+
+// filenameData is the data available to folder file name patterns
+type filenameData struct {
+	Title string
+	Type  string
+	UID   string
+}
+
+// parseFilenamePattern parses a folder file name pattern. When pattern is
+// empty the default "{{ .Title }}.<format>" is used.
+func parseFilenamePattern(pattern, format string) (*template.Template, error) {
+	if pattern == "" {
+		if format == "" {
+			format = "json"
+		}
+		pattern = "{{ .Title }}." + format
+	}
+
+	// File names only need string functions; drop the ones that read the environment
+	funcs := sprig.TxtFuncMap()
+	for _, name := range unsafeTemplateFuncs {
+		delete(funcs, name)
+	}
+	tmpl, err := template.New("filename").Funcs(funcs).Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("filename pattern parse error: %w", err)
+	}
+	return tmpl, nil
+}
+
+// ValidateFilenamePattern checks that a folder file name pattern parses.
+// The webhook uses it to reject broken patterns at admission.
+func ValidateFilenamePattern(pattern string) error {
+	_, err := parseFilenamePattern(pattern, "")
+	return err
+}
+
+// folderFilename renders the file name of a folder record relative to the
+// output directory. Every path segment is sanitized, so a pattern can create
+// subdirectories but never leave the output directory.
+func folderFilename(tmpl *template.Template, secret *ksm.SecretData) (string, error) {
+	var b strings.Builder
+	// Slashes in record values never create directories; only the pattern's do
+	noSlash := func(s string) string { return strings.ReplaceAll(s, "/", "") }
+	data := filenameData{Title: noSlash(secret.Title), Type: noSlash(secret.Type), UID: noSlash(secret.RecordUID)}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("filename pattern execute error: %w", err)
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(b.String(), "/") {
+		segment = sanitizeFilename(segment)
+		if segment == "" || strings.Trim(segment, ".") == "" {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("filename pattern produced an empty name for %q", secret.Title)
+	}
+	return filepath.Join(segments...), nil
+}
+
+// folderSubdir returns the output subdirectory of a record in a recursive
+// folder sync: its folder's path below the synced folder, one sanitized
+// directory per level. Records outside the tree go to the top level.
+func folderSubdir(tree *ksm.FolderTree, rootUID string, secret *ksm.SecretData) string {
+	if tree == nil {
+		return ""
+	}
+	rel, ok := tree.RelativePath(rootUID, secret.FolderUID)
+	if !ok || rel == "" {
+		return ""
+	}
+
+	var segments []string
+	for _, name := range strings.Split(rel, "/") {
+		segment := sanitizeFilename(name)
+		if segment == "" || strings.Trim(segment, ".") == "" {
+			segment = "_"
+		}
+		segments = append(segments, segment)
+	}
+	return filepath.Join(segments...)
+}
+
+// formatFolderRecord renders a folder record with the folder's template or format
+func (a *Agent) formatFolderRecord(ctx context.Context, cfg FolderConfig, path string, secret *ksm.SecretData) ([]byte, error) {
+	if cfg.Template != "" {
+		rc := a.newRenderContext(ctx, path, []*ksm.SecretData{secret})
+		return renderTemplateWithContext(secret.Fields, cfg.Template, rc)
+	}
+	return formatSecret(secret.Fields, SecretConfig{Format: cfg.Format})
+}
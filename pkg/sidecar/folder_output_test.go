@@ -0,0 +1,126 @@
+package sidecar
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	ksmcore "github.com/keeper-security/secrets-manager-go/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFolderFilename(t *testing.T) {
+	secret := &ksm.SecretData{RecordUID: "UID1", Title: "Stripe API", Type: "login"}
+	slashed := &ksm.SecretData{Title: "Prod/DB"}
+
+	tests := []struct {
+		name    string
+		pattern string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{name: "default is json", want: "Stripe-API.json"},
+		{name: "default follows format", format: "env", want: "Stripe-API.env"},
+		{name: "subdirectory by type", pattern: "{{ .Type }}/{{ .Title | lower }}.env", want: "login/stripe-api.env"},
+		{name: "record UID", pattern: "{{ .UID }}.yaml", want: "UID1.yaml"},
+		{name: "traversal is stripped", pattern: "../../etc/{{ .Title }}", want: "etc/Stripe-API"},
+		{name: "slash in title", pattern: "{{ .Title }}/../x", want: "Stripe-API/x"},
+		{name: "empty name", pattern: "{{ if false }}x{{ end }}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseFilenamePattern(tt.pattern, tt.format)
+			require.NoError(t, err)
+			got, err := folderFilename(tmpl, secret)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	tmpl, err := parseFilenamePattern("", "")
+	require.NoError(t, err)
+	got, err := folderFilename(tmpl, slashed)
+	require.NoError(t, err)
+	assert.Equal(t, "ProdDB.json", got, "slashes in titles must not create directories")
+}
+
+func TestValidateFilenamePattern(t *testing.T) {
+	assert.NoError(t, ValidateFilenamePattern(""))
+	assert.NoError(t, ValidateFilenamePattern("{{ .Title | lower }}.env"))
+	assert.Error(t, ValidateFilenamePattern("{{ .Title "))
+	assert.Error(t, ValidateFilenamePattern(`{{ env "HOME" }}`))
+}
+
+func TestFormatFolderRecord(t *testing.T) {
+	agent := &Agent{config: &AgentConfig{}, logger: zap.NewNop()}
+	secret := &ksm.SecretData{Title: "db", Fields: map[string]interface{}{"password": "s3cret"}}
+
+	data, err := agent.formatFolderRecord(context.Background(), FolderConfig{Format: "env"}, "/keeper/secrets/db.env", secret)
+	require.NoError(t, err)
+	assert.Equal(t, "PASSWORD=s3cret\n", string(data))
+
+	cfg := FolderConfig{Template: "DB_PASSWORD={{ .password }}", FilenamePattern: "{{ .Title }}.env"}
+	data, err = agent.formatFolderRecord(context.Background(), cfg, "/keeper/secrets/db.env", secret)
+	require.NoError(t, err)
+	assert.Equal(t, "DB_PASSWORD=s3cret", string(data))
+}
+
+func TestRecordFilter(t *testing.T) {
+	stripe := &ksm.SecretData{Title: "stripe-live", Type: "login", Fields: map[string]interface{}{"env": "prod"}}
+	twilio := &ksm.SecretData{Title: "twilio", Type: "login", Fields: map[string]interface{}{"env": "staging"}}
+	db := &ksm.SecretData{Title: "stripe-db", Type: "databaseCredentials", Fields: map[string]interface{}{"env": "prod"}}
+
+	tests := []struct {
+		name   string
+		filter RecordFilter
+		want   []string
+	}{
+		{name: "empty matches all", want: []string{"stripe-live", "twilio", "stripe-db"}},
+		{name: "type", filter: RecordFilter{Types: []string{"databaseCredentials"}}, want: []string{"stripe-db"}},
+		{name: "title glob", filter: RecordFilter{Title: "stripe-*"}, want: []string{"stripe-live", "stripe-db"}},
+		{name: "title regex", filter: RecordFilter{TitleRegex: "^twi"}, want: []string{"twilio"}},
+		{name: "label", filter: RecordFilter{Labels: map[string]string{"env": "prod"}}, want: []string{"stripe-live", "stripe-db"}},
+		{name: "all conditions", filter: RecordFilter{Types: []string{"login"}, Labels: map[string]string{"env": "prod"}}, want: []string{"stripe-live"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := tt.filter.compile()
+			require.NoError(t, err)
+			var got []string
+			for _, s := range []*ksm.SecretData{stripe, twilio, db} {
+				if matcher.matches(s) {
+					got = append(got, s.Title)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := RecordFilter{TitleRegex: "("}.compile()
+	assert.Error(t, err)
+	_, err = RecordFilter{Title: "["}.compile()
+	assert.Error(t, err)
+}
+
+func TestFolderSubdir(t *testing.T) {
+	tree := ksm.BuildFolderTree([]*ksmcore.KeeperFolder{
+		{FolderUid: "payments", Name: "Payments"},
+		{FolderUid: "stripe", ParentUid: "payments", Name: "Stripe Live"},
+		{FolderUid: "webhooks", ParentUid: "stripe", Name: "Webhooks"},
+		{FolderUid: "other", Name: "Other"},
+	})
+
+	assert.Equal(t, "", folderSubdir(tree, "payments", &ksm.SecretData{FolderUID: "payments"}))
+	assert.Equal(t, "Stripe-Live/Webhooks", folderSubdir(tree, "payments", &ksm.SecretData{FolderUID: "webhooks"}))
+	assert.Equal(t, "", folderSubdir(tree, "payments", &ksm.SecretData{FolderUID: "other"}))
+	assert.Equal(t, "", folderSubdir(nil, "payments", &ksm.SecretData{FolderUID: "webhooks"}))
+}
@@ -0,0 +1,139 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// folderManifestName is the file in each generation that records which
+// folder sync owns which files (folder key → relative path → record title)
+const folderManifestName = "..folders.json"
+
+// orphanedFile is a folder output file whose record left the folder
+type orphanedFile struct {
+	folder string
+	title  string
+	path   string
+}
+
+// folderKey identifies a folder sync for file ownership tracking
+func folderKey(cfg FolderConfig) string {
+	return cfg.FolderUID + "|" + cfg.FolderPath + "|" + cfg.OutputPath
+}
+
+// folderLabel names a folder in logs and metrics
+func folderLabel(cfg FolderConfig) string {
+	if cfg.FolderUID != "" {
+		return cfg.FolderUID
+	}
+	return cfg.FolderPath
+}
+
+// trackFolderFiles records the files (path → record title) a folder sync owns
+// and queues the files it owned on the previous refresh but no longer does.
+// On the first refresh after a start the previous files come from the
+// manifest of the generation on disk. Only called after the folder was listed
+// successfully, so a Keeper outage never removes files.
+func (a *Agent) trackFolderFiles(cfg FolderConfig, current map[string]string) {
+	if a.folderFiles == nil {
+		a.folderFiles = make(map[string]map[string]string)
+	}
+
+	key := folderKey(cfg)
+	previous, ok := a.folderFiles[key]
+	if !ok {
+		previous = a.loadFolderFiles(cfg, current)
+	}
+	a.folderFiles[key] = current
+
+	if !cfg.Prune {
+		return
+	}
+	for path, title := range previous {
+		if _, ok := current[path]; !ok {
+			a.orphans = append(a.orphans, orphanedFile{folder: folderLabel(cfg), title: title, path: path})
+		}
+	}
+}
+
+// pruneOrphanedFiles drops queued orphaned folder files from the next snapshot.
+// A path published by another output during this refresh is kept.
+func (a *Agent) pruneOrphanedFiles() {
+	for _, o := range a.orphans {
+		if a.published[o.path] || !a.unstageSecret(o.path) {
+			continue
+		}
+
+		metrics.RecordFolderFilePruned(o.folder)
+		a.logger.Info("removed file of record no longer in folder",
+			zap.String("folder", o.folder),
+			zap.String("title", o.title),
+			zap.String("path", o.path))
+		a.recordChange(ChangeEvent{Secret: o.title, Path: o.path, Time: time.Now()})
+	}
+	a.orphans = nil
+}
+
+// loadFolderFiles reads the files a folder sync owned from the manifests of
+// the generations on disk, in the output roots of its current files and its
+// output path
+func (a *Agent) loadFolderFiles(cfg FolderConfig, current map[string]string) map[string]string {
+	roots := map[string]bool{a.outputRoot(filepath.Join(cfg.OutputPath, "_")): true}
+	for path := range current {
+		roots[a.outputRoot(path)] = true
+	}
+
+	key := folderKey(cfg)
+	files := make(map[string]string)
+	for root := range roots {
+		data, err := os.ReadFile(filepath.Join(root, dataDirName, folderManifestName))
+		if err != nil {
+			continue
+		}
+		var manifest map[string]map[string]string
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			a.logger.Warn("ignoring unreadable folder manifest", zap.String("root", root), zap.Error(err))
+			continue
+		}
+		for rel, title := range manifest[key] {
+			files[filepath.Join(root, rel)] = title
+		}
+	}
+	return files
+}
+
+// writeFolderManifest records in a new generation which folder sync owns
+// which of its files, so pruning continues after a restart
+func (a *Agent) writeFolderManifest(root, generation string) error {
+	manifest := make(map[string]map[string]string)
+	for key, files := range a.folderFiles {
+		for path, title := range files {
+			if a.outputRoot(path) != root {
+				continue
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				continue
+			}
+			if manifest[key] == nil {
+				manifest[key] = make(map[string]string)
+			}
+			manifest[key][rel] = title
+		}
+	}
+	if len(manifest) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal folder manifest: %w", err)
+	}
+	return a.writeSecretFile(filepath.Join(generation, folderManifestName), data)
+}
@@ -0,0 +1,131 @@
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncFolder simulates one refresh of a folder sync that lists the given titles
+func syncFolder(t *testing.T, agent *Agent, cfg FolderConfig, titles ...string) {
+	t.Helper()
+	agent.published = make(map[string]bool)
+	agent.changes = nil
+
+	owned := make(map[string]string)
+	for _, title := range titles {
+		path := filepath.Join(cfg.OutputPath, sanitizeFilename(title)+".json")
+		owned[path] = title
+		_, err := agent.publishSecret(title, path, []byte(`{"title":"`+title+`"}`))
+		require.NoError(t, err)
+	}
+	agent.trackFolderFiles(cfg, owned)
+}
+
+func TestPruneOrphanedFiles(t *testing.T) {
+	root := t.TempDir()
+	agent := newSnapshotAgent(root)
+	cfg := FolderConfig{FolderUID: "folder-1", OutputPath: root, Prune: true}
+
+	syncFolder(t, agent, cfg, "stripe", "twilio")
+	agent.pruneOrphanedFiles()
+	require.NoError(t, agent.commitSnapshots())
+
+	// twilio was removed from the Keeper folder
+	syncFolder(t, agent, cfg, "stripe")
+	agent.pruneOrphanedFiles()
+	require.NoError(t, agent.commitSnapshots())
+
+	_, err := os.Stat(filepath.Join(root, "stripe.json"))
+	assert.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(root, "twilio.json"))
+	assert.True(t, os.IsNotExist(err), "orphaned file should be removed")
+
+	changes := agent.takeChanges()
+	require.Len(t, changes, 1)
+	assert.Equal(t, "twilio", changes[0].Secret)
+	assert.Equal(t, filepath.Join(root, "twilio.json"), changes[0].Path)
+}
+
+func TestPruneOrphanedFiles_OptOut(t *testing.T) {
+	root := t.TempDir()
+	agent := newSnapshotAgent(root)
+	cfg := FolderConfig{FolderUID: "folder-1", OutputPath: root, Prune: false}
+
+	syncFolder(t, agent, cfg, "stripe", "twilio")
+	agent.pruneOrphanedFiles()
+	require.NoError(t, agent.commitSnapshots())
+
+	syncFolder(t, agent, cfg, "stripe")
+	agent.pruneOrphanedFiles()
+	require.NoError(t, agent.commitSnapshots())
+
+	_, err := os.Stat(filepath.Join(root, "twilio.json"))
+	assert.NoError(t, err, "file should be kept when pruning is disabled")
+}
+
+func TestPruneOrphanedFiles_KeepsPathPublishedByOtherOutput(t *testing.T) {
+	root := t.TempDir()
+	agent := newSnapshotAgent(root)
+	cfg := FolderConfig{FolderUID: "folder-1", OutputPath: root, Prune: true}
+
+	syncFolder(t, agent, cfg, "db")
+	agent.pruneOrphanedFiles()
+	require.NoError(t, agent.commitSnapshots())
+
+	// The record left the folder, but a single-secret output now writes the same path
+	syncFolder(t, agent, cfg)
+	_, err := agent.publishSecret("db", filepath.Join(root, "db.json"), []byte(`{"from":"secret"}`))
+	require.NoError(t, err)
+	agent.pruneOrphanedFiles()
+	require.NoError(t, agent.commitSnapshots())
+
+	content, err := os.ReadFile(filepath.Join(root, "db.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"from":"secret"}`, string(content))
+}
+
+func TestPruneOrphanedFiles_AfterRestart(t *testing.T) {
+	root := t.TempDir()
+	cfg := FolderConfig{FolderUID: "folder-1", OutputPath: root, Prune: true}
+
+	// The init container writes both records
+	initAgent := newSnapshotAgent(root)
+	syncFolder(t, initAgent, cfg, "stripe", "twilio")
+	initAgent.pruneOrphanedFiles()
+	require.NoError(t, initAgent.commitSnapshots())
+
+	// twilio was removed before the sidecar's first refresh
+	sidecar := newSnapshotAgent(root)
+	syncFolder(t, sidecar, cfg, "stripe")
+	sidecar.pruneOrphanedFiles()
+	require.NoError(t, sidecar.commitSnapshots())
+
+	_, err := os.Stat(filepath.Join(root, "stripe.json"))
+	assert.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(root, "twilio.json"))
+	assert.True(t, os.IsNotExist(err), "orphaned file should be removed")
+}
+
+func TestSnapshot_CarriesFilesAcrossRestart(t *testing.T) {
+	root := t.TempDir()
+	cfg := FolderConfig{FolderUID: "folder-1", OutputPath: root, Prune: false}
+
+	first := newSnapshotAgent(root)
+	syncFolder(t, first, cfg, "stripe", "twilio")
+	require.NoError(t, first.commitSnapshots())
+
+	// After a restart, twilio is gone from Keeper and a new record arrives
+	second := newSnapshotAgent(root)
+	syncFolder(t, second, cfg, "stripe", "sendgrid")
+	second.pruneOrphanedFiles()
+	require.NoError(t, second.commitSnapshots())
+
+	for _, name := range []string{"stripe.json", "twilio.json", "sendgrid.json"} {
+		_, err := os.Stat(filepath.Join(root, name))
+		assert.NoError(t, err, "%s should be kept when pruning is disabled", name)
+	}
+}
@@ -0,0 +1,82 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// FolderSecretName returns the K8s Secret name for a folder record:
+// prefix + title lowercased, with characters not allowed in Secret names
+// replaced by '-'. Returns "" if the title has no letters or digits or no
+// valid name can be built.
+func FolderSecretName(prefix, title string) string {
+	if !strings.ContainsFunc(strings.ToLower(title), func(c rune) bool {
+		return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+	}) {
+		return ""
+	}
+
+	var b strings.Builder
+	lastDash := false
+	for _, c := range strings.ToLower(prefix + title) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '.':
+			b.WriteRune(c)
+			lastDash = false
+		case !lastDash:
+			b.WriteByte('-')
+			lastDash = true
+		}
+	}
+
+	name := strings.Trim(b.String(), "-.")
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength], "-.")
+	}
+	if len(validation.IsDNS1123Subdomain(name)) > 0 {
+		return ""
+	}
+	return name
+}
+
+// updateFolderK8sSecrets refreshes the Secrets created from a folder's records.
+// Secrets are created by the webhook at admission, and the sidecar may only
+// update those; records added to the folder later are skipped until the pod
+// is recreated.
+func (a *Agent) updateFolderK8sSecrets(ctx context.Context, namespace string, cfg FolderConfig) {
+	listing, err := ListFolderRecords(ctx, a.ksmClient, cfg)
+	if err != nil {
+		a.logger.Error("failed to list folder for K8s Secret update",
+			zap.String("folder", folderLabel(cfg)),
+			zap.Error(err))
+		return
+	}
+
+	for _, record := range listing.Records {
+		name := FolderSecretName(cfg.K8sSecretNamePrefix, record.Title)
+		if name == "" {
+			continue
+		}
+
+		err := a.applyK8sSecret(ctx, namespace, name, K8sSecretData(SecretConfig{}, record.Fields))
+		switch {
+		case errors.Is(err, errK8sSecretNotManaged) || isMissingK8sSecret(err):
+			a.logger.Debug("no K8s Secret to update for folder record",
+				zap.String("name", name),
+				zap.String("title", record.Title),
+				zap.Error(err))
+		case err != nil:
+			a.logger.Error("failed to update K8s Secret",
+				zap.String("name", name),
+				zap.Error(err))
+		default:
+			a.logger.Info("updated K8s Secret",
+				zap.String("name", name),
+				zap.String("namespace", namespace))
+		}
+	}
+}
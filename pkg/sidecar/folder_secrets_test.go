@@ -0,0 +1,31 @@
+package sidecar
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolderSecretName(t *testing.T) {
+	tests := []struct {
+		prefix string
+		title  string
+		want   string
+	}{
+		{prefix: "api-", title: "Stripe", want: "api-stripe"},
+		{prefix: "", title: "Stripe Live API Key", want: "stripe-live-api-key"},
+		{prefix: "db-", title: "Postgres (prod)", want: "db-postgres-prod"},
+		{prefix: "", title: "  --weird__name!! ", want: "weird-name"},
+		{prefix: "", title: "api.example.com", want: "api.example.com"},
+		{prefix: "", title: "日本語", want: ""},
+		{prefix: "api-", title: "!!!", want: ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FolderSecretName(tt.prefix, tt.title), "prefix %q title %q", tt.prefix, tt.title)
+	}
+
+	long := FolderSecretName("", strings.Repeat("a", 300))
+	assert.Len(t, long, 253)
+}
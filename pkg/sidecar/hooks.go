@@ -0,0 +1,181 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/metrics"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar/retry"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultHookTimeout applies when a hook has no timeout configured
+	defaultHookTimeout = 10 * time.Second
+	// maxHookOutput limits how much command output is logged
+	maxHookOutput = 1024
+)
+
+// HookConfig is an action run after secret content changes.
+// Exactly one of URL or Command is set.
+type HookConfig struct {
+	Name    string        `json:"name"`
+	URL     string        `json:"url,omitempty"`     // Localhost endpoint that receives a POST
+	Command []string      `json:"command,omitempty"` // Command executed inside the sidecar
+	Timeout time.Duration `json:"-"`                 // Per-attempt timeout
+	Retries int           `json:"retries,omitempty"` // Retries after a failed attempt
+}
+
+// hookPayload is the JSON body posted to HTTP hooks
+type hookPayload struct {
+	Secrets []string `json:"secrets"`
+	Paths   []string `json:"paths"`
+}
+
+// runHooks runs per-secret and per-template hooks for every changed output, then pod-level hooks once.
+// Failures are logged and counted but never stop the refresh loop.
+func (a *Agent) runHooks(ctx context.Context, changes []ChangeEvent) {
+	if len(changes) == 0 {
+		return
+	}
+
+	changedSecrets := make(map[string][]ChangeEvent)
+	for _, c := range changes {
+		changedSecrets[c.Secret] = append(changedSecrets[c.Secret], c)
+	}
+
+	for _, secretCfg := range a.config.Secrets {
+		secretChanges, ok := changedSecrets[secretCfg.Name]
+		if !ok {
+			continue
+		}
+		for _, hook := range secretCfg.Hooks {
+			a.runHook(ctx, hook, secretChanges)
+		}
+	}
+
+	for _, tmpl := range a.config.Templates {
+		tmplChanges, ok := changedSecrets[tmpl.Name]
+		if !ok {
+			continue
+		}
+		for _, hook := range tmpl.Hooks {
+			a.runHook(ctx, hook, tmplChanges)
+		}
+	}
+
+	for _, hook := range a.config.Hooks {
+		a.runHook(ctx, hook, changes)
+	}
+}
+
+// runHook executes a single hook with per-attempt timeout and retries
+func (a *Agent) runHook(ctx context.Context, hook HookConfig, changes []ChangeEvent) {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	retryCfg := retry.Config{
+		MaxAttempts: hook.Retries + 1,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+	}
+
+	startTime := time.Now()
+	err := retry.WithRetry(ctx, retryCfg, func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		if hook.URL != "" {
+			return postHook(attemptCtx, hook.URL, changes)
+		}
+		return a.execHook(attemptCtx, hook, changes)
+	})
+	metrics.RecordHookExecution(hook.Name, err == nil, time.Since(startTime).Seconds())
+
+	if err != nil {
+		a.logger.Error("post-refresh hook failed",
+			zap.String("hook", hook.Name),
+			zap.Error(err))
+		return
+	}
+	a.logger.Info("post-refresh hook succeeded",
+		zap.String("hook", hook.Name),
+		zap.Duration("duration", time.Since(startTime)))
+}
+
+// postHook sends the changed secrets to an HTTP endpoint
+func postHook(ctx context.Context, url string, changes []ChangeEvent) error {
+	body, err := json.Marshal(newHookPayload(changes))
+	if err != nil {
+		return fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("hook request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHookOutput))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// execHook runs a command inside the sidecar container.
+// The command gets a minimal environment so agent credentials are not inherited.
+func (a *Agent) execHook(ctx context.Context, hook HookConfig, changes []ChangeEvent) error {
+	payload := newHookPayload(changes)
+
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"KEEPER_CHANGED_SECRETS=" + strings.Join(payload.Secrets, ","),
+		"KEEPER_CHANGED_PATHS=" + strings.Join(payload.Paths, ","),
+	}
+
+	output, err := cmd.CombinedOutput()
+	if len(output) > maxHookOutput {
+		output = output[:maxHookOutput]
+	}
+	if err != nil {
+		return fmt.Errorf("command failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+
+	a.logger.Debug("hook command output",
+		zap.String("hook", hook.Name),
+		zap.String("output", strings.TrimSpace(string(output))))
+	return nil
+}
+
+// newHookPayload lists the distinct secrets and paths that changed
+func newHookPayload(changes []ChangeEvent) hookPayload {
+	payload := hookPayload{Secrets: []string{}, Paths: []string{}}
+	seen := make(map[string]bool)
+	for _, c := range changes {
+		if !seen[c.Secret] {
+			seen[c.Secret] = true
+			payload.Secrets = append(payload.Secrets, c.Secret)
+		}
+		payload.Paths = append(payload.Paths, c.Path)
+	}
+	return payload
+}
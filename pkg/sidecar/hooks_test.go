@@ -0,0 +1,115 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRunHooks_HTTP(t *testing.T) {
+	var calls int32
+	var received hookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	agent := &Agent{
+		config: &AgentConfig{
+			Hooks: []HookConfig{{Name: "reload", URL: server.URL + "/-/reload", Timeout: time.Second}},
+		},
+		logger: zap.NewNop(),
+	}
+
+	agent.runHooks(context.Background(), []ChangeEvent{
+		{Secret: "db", Path: "/keeper/secrets/db.json"},
+		{Secret: "db", Path: "/keeper/secrets/db.env"},
+	})
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, []string{"db"}, received.Secrets)
+	assert.Equal(t, []string{"/keeper/secrets/db.json", "/keeper/secrets/db.env"}, received.Paths)
+}
+
+func TestRunHooks_HTTPRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	agent := &Agent{
+		config: &AgentConfig{
+			Hooks: []HookConfig{{Name: "reload", URL: server.URL, Timeout: time.Second, Retries: 2}},
+		},
+		logger: zap.NewNop(),
+	}
+
+	agent.runHooks(context.Background(), []ChangeEvent{{Secret: "db", Path: "/keeper/secrets/db.json"}})
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRunHooks_PerSecretOnlyForChangedSecret(t *testing.T) {
+	dir := t.TempDir()
+	dbMarker := filepath.Join(dir, "db")
+	apiMarker := filepath.Join(dir, "api")
+
+	agent := &Agent{
+		config: &AgentConfig{
+			Secrets: []SecretConfig{
+				{Name: "db", Hooks: []HookConfig{{Name: "db-hook", Command: []string{"touch", dbMarker}}}},
+				{Name: "api", Hooks: []HookConfig{{Name: "api-hook", Command: []string{"touch", apiMarker}}}},
+			},
+		},
+		logger: zap.NewNop(),
+	}
+
+	agent.runHooks(context.Background(), []ChangeEvent{{Secret: "db", Path: "/keeper/secrets/db.json"}})
+
+	_, err := os.Stat(dbMarker)
+	assert.NoError(t, err, "hook of changed secret should run")
+	_, err = os.Stat(apiMarker)
+	assert.True(t, os.IsNotExist(err), "hook of unchanged secret should not run")
+}
+
+func TestExecHook_MinimalEnvironment(t *testing.T) {
+	t.Setenv("KEEPER_AUTH_CONFIG", "do-not-leak")
+	out := filepath.Join(t.TempDir(), "env")
+
+	agent := &Agent{logger: zap.NewNop()}
+	hook := HookConfig{Name: "env", Command: []string{"sh", "-c", "env > " + out}}
+	err := agent.execHook(context.Background(), hook, []ChangeEvent{{Secret: "db", Path: "/keeper/secrets/db.json"}})
+	require.NoError(t, err)
+
+	env, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(env), "KEEPER_CHANGED_SECRETS=db")
+	assert.Contains(t, string(env), "KEEPER_CHANGED_PATHS=/keeper/secrets/db.json")
+	assert.NotContains(t, string(env), "do-not-leak")
+}
+
+func TestExecHook_Timeout(t *testing.T) {
+	agent := &Agent{logger: zap.NewNop()}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := agent.execHook(ctx, HookConfig{Name: "slow", Command: []string{"sleep", "5"}}, nil)
+	assert.Error(t, err)
+}
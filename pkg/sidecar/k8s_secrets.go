@@ -0,0 +1,183 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+const (
+	// k8sSecretFieldManager is the server-side apply field manager of the
+	// Secret fields the sidecar keeps in sync
+	k8sSecretFieldManager = "keeper-sidecar"
+
+	// Labels the injector sets on the Secrets it creates
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "keeper-injector"
+	injectedLabel  = "keeper.security/injected"
+)
+
+// errK8sSecretNotManaged is returned when skip-if-exists protects a Secret
+// the injector did not create
+var errK8sSecretNotManaged = errors.New("secret was not created by keeper-injector")
+
+// updateK8sSecrets applies the current Keeper data to the K8s Secrets the
+// webhook created at admission (v0.9.0)
+func (a *Agent) updateK8sSecrets(ctx context.Context) error {
+	if a.k8sClient == nil {
+		return nil // Rotation not enabled
+	}
+
+	namespace := a.k8sSecretNamespace()
+
+	// Update K8s Secrets for each secret configured for K8s Secret injection
+	for _, secretCfg := range a.config.Secrets {
+		if !secretCfg.InjectAsK8sSecret || secretCfg.K8sSecretName == "" {
+			continue
+		}
+
+		data, err := a.fetchK8sSecretRecord(ctx, secretCfg)
+		if err != nil {
+			a.logger.Error("failed to fetch secret for K8s Secret update",
+				zap.String("name", secretCfg.Name),
+				zap.Error(err))
+			continue
+		}
+
+		err = a.applyK8sSecret(ctx, namespace, secretCfg.K8sSecretName, K8sSecretData(secretCfg, data.Fields))
+		switch {
+		case errors.Is(err, errK8sSecretNotManaged):
+			a.logger.Debug("skipping K8s Secret not created by the injector",
+				zap.String("name", secretCfg.K8sSecretName))
+		case err != nil:
+			a.logger.Error("failed to update K8s Secret",
+				zap.String("name", secretCfg.K8sSecretName),
+				zap.Error(err))
+		default:
+			a.logger.Info("updated K8s Secret",
+				zap.String("name", secretCfg.K8sSecretName),
+				zap.String("namespace", namespace))
+		}
+	}
+
+	// Update K8s Secrets created from folder records
+	for _, folderCfg := range a.config.Folders {
+		if folderCfg.InjectAsK8sSecret {
+			a.updateFolderK8sSecrets(ctx, namespace, folderCfg)
+		}
+	}
+
+	return nil
+}
+
+// k8sSecretNamespace returns the namespace of the K8s Secrets: the configured
+// one, else the pod's namespace from the downward API
+func (a *Agent) k8sSecretNamespace() string {
+	if a.config.K8sSecretNamespace != "" {
+		return a.config.K8sSecretNamespace
+	}
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	return "default"
+}
+
+// fetchK8sSecretRecord fetches the Keeper data of a K8s Secret
+func (a *Agent) fetchK8sSecretRecord(ctx context.Context, secretCfg SecretConfig) (*ksm.SecretData, error) {
+	switch {
+	case secretCfg.Notation != "":
+		notationData, err := a.ksmClient.GetNotation(ctx, secretCfg.Notation)
+		if err != nil {
+			return nil, fmt.Errorf("notation %s: %w", secretCfg.Notation, err)
+		}
+		return &ksm.SecretData{
+			Fields: map[string]interface{}{
+				"value": string(notationData),
+			},
+		}, nil
+
+	case secretCfg.IsFile:
+		fileData, err := a.ksmClient.GetFileContent(ctx, secretCfg.Name, secretCfg.FileName)
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %w", secretCfg.FileName, err)
+		}
+		return &ksm.SecretData{
+			Fields: map[string]interface{}{
+				secretCfg.FileName: fileData,
+			},
+		}, nil
+
+	default:
+		return a.ksmClient.GetSecret(ctx, secretCfg.Name)
+	}
+}
+
+// K8sSecretData selects the Secret keys of a record: the custom key mapping,
+// else the selected fields, else all fields. Shared with the KeeperSecret controller.
+func K8sSecretData(secretCfg SecretConfig, fields map[string]interface{}) map[string][]byte {
+	data := make(map[string][]byte)
+	switch {
+	case len(secretCfg.K8sSecretKeys) > 0:
+		for keeperField, k8sKey := range secretCfg.K8sSecretKeys {
+			if value, ok := fields[keeperField]; ok {
+				data[k8sKey] = valueToBytes(value)
+			}
+		}
+	case len(secretCfg.Fields) > 0:
+		for _, field := range secretCfg.Fields {
+			if value, ok := fields[field]; ok {
+				data[field] = valueToBytes(value)
+			}
+		}
+	default:
+		for field, value := range fields {
+			data[field] = valueToBytes(value)
+		}
+	}
+	return data
+}
+
+// applyK8sSecret writes Secret data with server-side apply. The sidecar owns
+// only the keys it applies, so keys written by others are left alone, and
+// keys it stops applying are removed. The Secret mode decides the rest:
+//   - overwrite, fail: the injector labels are applied along with the data
+//   - merge: only the data is applied
+//   - skip-if-exists: Secrets the injector did not create are never modified
+func (a *Agent) applyK8sSecret(ctx context.Context, namespace, name string, data map[string][]byte) error {
+	secrets := a.k8sClient.CoreV1().Secrets(namespace)
+
+	if a.config.K8sSecretMode == "skip-if-exists" {
+		existing, err := secrets.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if existing.Labels[managedByLabel] != managedByValue {
+			return errK8sSecretNotManaged
+		}
+	}
+
+	secret := corev1ac.Secret(name, namespace).WithData(data)
+	if a.config.K8sSecretMode != "merge" {
+		secret.WithLabels(map[string]string{
+			managedByLabel: managedByValue,
+			injectedLabel:  "true",
+		})
+	}
+
+	// Force: the webhook created the Secret under another field manager
+	_, err := secrets.Apply(ctx, secret, metav1.ApplyOptions{FieldManager: k8sSecretFieldManager, Force: true})
+	return err
+}
+
+// isMissingK8sSecret reports whether an apply failed because the Secret does
+// not exist and the sidecar may not create it
+func isMissingK8sSecret(err error) bool {
+	return apierrors.IsNotFound(err) || apierrors.IsForbidden(err)
+}
@@ -0,0 +1,109 @@
+package sidecar
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newK8sSecretAgent(mode string, existing ...*corev1.Secret) *Agent {
+	clientset := fake.NewClientset()
+	for _, secret := range existing {
+		_, _ = clientset.CoreV1().Secrets(secret.Namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	}
+	return &Agent{
+		config:    &AgentConfig{K8sSecretRotation: true, K8sSecretMode: mode},
+		k8sClient: clientset,
+		logger:    zap.NewNop(),
+	}
+}
+
+func existingSecret(labels map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "apps", Labels: labels},
+		Data: map[string][]byte{
+			"password": []byte("old"),
+			"extra":    []byte("kept"),
+		},
+	}
+}
+
+func getSecret(t *testing.T, agent *Agent) *corev1.Secret {
+	t.Helper()
+	secret, err := agent.k8sClient.CoreV1().Secrets("apps").Get(context.Background(), "db", metav1.GetOptions{})
+	require.NoError(t, err)
+	return secret
+}
+
+func TestApplyK8sSecret_Overwrite(t *testing.T) {
+	agent := newK8sSecretAgent("overwrite", existingSecret(nil))
+
+	err := agent.applyK8sSecret(context.Background(), "apps", "db", map[string][]byte{"password": []byte("new")})
+	require.NoError(t, err)
+
+	secret := getSecret(t, agent)
+	assert.Equal(t, "new", string(secret.Data["password"]))
+	assert.Equal(t, "kept", string(secret.Data["extra"]), "keys owned by others are left alone")
+	assert.Equal(t, "keeper-injector", secret.Labels["app.kubernetes.io/managed-by"])
+
+	var managers []string
+	for _, entry := range secret.ManagedFields {
+		managers = append(managers, entry.Manager)
+	}
+	assert.Contains(t, managers, "keeper-sidecar")
+}
+
+func TestApplyK8sSecret_Merge(t *testing.T) {
+	agent := newK8sSecretAgent("merge", existingSecret(nil))
+
+	err := agent.applyK8sSecret(context.Background(), "apps", "db", map[string][]byte{"password": []byte("new")})
+	require.NoError(t, err)
+
+	secret := getSecret(t, agent)
+	assert.Equal(t, "new", string(secret.Data["password"]))
+	assert.Equal(t, "kept", string(secret.Data["extra"]))
+	assert.Empty(t, secret.Labels, "merge only applies data")
+}
+
+func TestApplyK8sSecret_SkipIfExists(t *testing.T) {
+	agent := newK8sSecretAgent("skip-if-exists", existingSecret(nil))
+
+	err := agent.applyK8sSecret(context.Background(), "apps", "db", map[string][]byte{"password": []byte("new")})
+	assert.ErrorIs(t, err, errK8sSecretNotManaged)
+	assert.Equal(t, "old", string(getSecret(t, agent).Data["password"]))
+
+	// Secrets the injector created are still rotated
+	agent = newK8sSecretAgent("skip-if-exists", existingSecret(map[string]string{
+		"app.kubernetes.io/managed-by": "keeper-injector",
+	}))
+	err = agent.applyK8sSecret(context.Background(), "apps", "db", map[string][]byte{"password": []byte("new")})
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(getSecret(t, agent).Data["password"]))
+}
+
+func TestK8sSecretData(t *testing.T) {
+	fields := map[string]interface{}{"login": "admin", "password": "s3cret", "port": float64(5432)}
+
+	assert.Equal(t, map[string][]byte{"DB_USER": []byte("admin")},
+		K8sSecretData(SecretConfig{K8sSecretKeys: map[string]string{"login": "DB_USER", "missing": "X"}}, fields))
+	assert.Equal(t, map[string][]byte{"password": []byte("s3cret")},
+		K8sSecretData(SecretConfig{Fields: []string{"password"}}, fields))
+	assert.Equal(t, map[string][]byte{"login": []byte("admin"), "password": []byte("s3cret"), "port": []byte("5432")},
+		K8sSecretData(SecretConfig{}, fields))
+}
+
+func TestK8sSecretNamespace(t *testing.T) {
+	agent := &Agent{config: &AgentConfig{}}
+
+	t.Setenv("POD_NAMESPACE", "apps")
+	assert.Equal(t, "apps", agent.k8sSecretNamespace())
+
+	agent.config.K8sSecretNamespace = "shared"
+	assert.Equal(t, "shared", agent.k8sSecretNamespace())
+}
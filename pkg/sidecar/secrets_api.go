@@ -0,0 +1,335 @@
+package sidecar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Local secrets API served on a Unix socket in the shared secrets volume.
+// Clients authenticate with the bearer token the sidecar writes next to the socket.
+const (
+	// SecretsAPISocketName is the socket file name under the secrets volume
+	SecretsAPISocketName = ".keeper-api.sock"
+	// SecretsAPITokenName is the token file name under the secrets volume
+	SecretsAPITokenName = ".keeper-api-token"
+
+	// defaultWatchTimeout applies when a watch request has no timeout
+	defaultWatchTimeout = 60 * time.Second
+	// maxWatchTimeout caps how long a watch request is held open
+	maxWatchTimeout = 5 * time.Minute
+)
+
+// apiSecret is the JSON representation of a secret returned by the API
+type apiSecret struct {
+	Name      string                 `json:"name"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Content   string                 `json:"content"`
+	UpdatedAt time.Time              `json:"updatedAt"`
+	Cached    bool                   `json:"cached,omitempty"` // Served from the last-known-good cache
+	version   uint64                 // API version at which the secret last changed
+}
+
+// watchResponse is returned by the watch endpoint
+type watchResponse struct {
+	Version uint64   `json:"version"`
+	Changed []string `json:"changed"`
+}
+
+// secretsAPI holds the data served by the local secrets API.
+// The refresh loop updates it; HTTP handlers read it concurrently.
+type secretsAPI struct {
+	socketPath string
+	tokenPath  string
+	token      string
+
+	mu      sync.RWMutex
+	secrets map[string]*apiSecret
+	version uint64
+	notify  chan struct{} // Closed and replaced whenever version changes
+}
+
+// newSecretsAPI creates the API state for a socket and token file in dir
+func newSecretsAPI(dir string) *secretsAPI {
+	return &secretsAPI{
+		socketPath: filepath.Join(dir, SecretsAPISocketName),
+		tokenPath:  filepath.Join(dir, SecretsAPITokenName),
+		secrets:    make(map[string]*apiSecret),
+		notify:     make(chan struct{}),
+	}
+}
+
+// set records the current data of a secret and wakes watchers if it changed
+func (s *secretsAPI) set(name string, fields map[string]interface{}, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prev, ok := s.secrets[name]; ok && prev.Content == string(content) {
+		return
+	}
+
+	s.version++
+	s.secrets[name] = &apiSecret{
+		Name:      name,
+		Fields:    fields,
+		Content:   string(content),
+		UpdatedAt: time.Now(),
+		version:   s.version,
+	}
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// get returns the current data of a secret
+func (s *secretsAPI) get(name string) (*apiSecret, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	secret, ok := s.secrets[name]
+	return secret, ok
+}
+
+// changedSince returns the current version, the names changed after version
+// and a channel that is closed on the next change
+func (s *secretsAPI) changedSince(version uint64) (uint64, []string, <-chan struct{}) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changed := []string{}
+	for name, secret := range s.secrets {
+		if secret.version > version {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return s.version, changed, s.notify
+}
+
+// startSecretsAPI serves the local secrets API until ctx is cancelled
+func (a *Agent) startSecretsAPI(ctx context.Context) {
+	listener, err := a.api.listen()
+	if err != nil {
+		a.logger.Error("failed to start secrets API", zap.Error(err))
+		return
+	}
+
+	server := &http.Server{
+		Handler:           a.secretsAPIHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	a.logger.Info("starting secrets API", zap.String("socket", a.api.socketPath))
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		a.logger.Error("secrets API error", zap.Error(err))
+	}
+}
+
+// listen writes a new token and opens the Unix socket.
+// The socket is world-writable so any container can connect; the token file
+// (same permissions as secret files) is what grants access.
+func (s *secretsAPI) listen() (net.Listener, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate API token: %w", err)
+	}
+	s.token = hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	tmpToken := s.tokenPath + ".tmp"
+	_ = os.Remove(tmpToken)
+	if err := os.WriteFile(tmpToken, []byte(s.token), 0400); err != nil {
+		return nil, fmt.Errorf("failed to write API token: %w", err)
+	}
+	if err := os.Rename(tmpToken, s.tokenPath); err != nil {
+		_ = os.Remove(tmpToken)
+		return nil, fmt.Errorf("failed to write API token: %w", err)
+	}
+
+	// Remove a socket left by a previous sidecar run
+	_ = os.Remove(s.socketPath)
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	if err := os.Chmod(s.socketPath, 0666); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// secretsAPIHandler returns the HTTP handler of the local secrets API
+func (a *Agent) secretsAPIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/secrets/{name}", a.handleGetSecret)
+	mux.HandleFunc("GET /v1/secrets/{name}/fields/{field}", a.handleGetSecretField)
+	mux.HandleFunc("GET /v1/watch", a.handleWatch)
+	return a.requireAPIToken(mux)
+}
+
+// requireAPIToken rejects requests without the bearer token
+func (a *Agent) requireAPIToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || a.api.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.api.token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleGetSecret serves GET /v1/secrets/{name}
+func (a *Agent) handleGetSecret(w http.ResponseWriter, r *http.Request) {
+	secret, ok := a.lookupAPISecret(r.PathValue("name"))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "secret not found")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, secret)
+}
+
+// handleGetSecretField serves GET /v1/secrets/{name}/fields/{field}.
+// String values are returned as text/plain, other values as JSON.
+func (a *Agent) handleGetSecretField(w http.ResponseWriter, r *http.Request) {
+	secret, ok := a.lookupAPISecret(r.PathValue("name"))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "secret not found")
+		return
+	}
+	value, ok := secret.Fields[r.PathValue("field")]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "field not found")
+		return
+	}
+
+	if s, isString := value.(string); isString {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(s))
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, value)
+}
+
+// handleWatch serves GET /v1/watch?version=N&timeout=60s.
+// Returns as soon as any secret changed after version N, or with an empty
+// change list when the timeout expires. Clients pass the returned version
+// to the next call. A version newer than the sidecar's (after a sidecar
+// restart) reports every secret as changed.
+func (a *Agent) handleWatch(w http.ResponseWriter, r *http.Request) {
+	var since uint64
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "invalid version")
+			return
+		}
+		since = n
+	}
+	timeout := defaultWatchTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeAPIError(w, http.StatusBadRequest, "invalid timeout")
+			return
+		}
+		timeout = min(d, maxWatchTimeout)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		version, changed, notify := a.api.changedSince(since)
+		if version < since {
+			since = 0
+			continue
+		}
+		if len(changed) > 0 {
+			writeAPIJSON(w, http.StatusOK, watchResponse{Version: version, Changed: changed})
+			return
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			writeAPIJSON(w, http.StatusOK, watchResponse{Version: version, Changed: changed})
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// lookupAPISecret returns the current data of a configured secret or template.
+// Falls back to the last-known-good cache (content only) when the sidecar has
+// not fetched the secret since it started.
+func (a *Agent) lookupAPISecret(name string) (*apiSecret, bool) {
+	if secret, ok := a.api.get(name); ok {
+		return secret, true
+	}
+
+	cacheKey, ok := a.apiCacheKey(name)
+	if !ok {
+		return nil, false
+	}
+	cached, ok := a.secretCache.Get(cacheKey)
+	if !ok {
+		return nil, false
+	}
+	return &apiSecret{
+		Name:      name,
+		Content:   string(cached.Data),
+		UpdatedAt: cached.FetchedAt,
+		Cached:    true,
+	}, true
+}
+
+// apiCacheKey maps an API secret name to its cache key; only configured
+// secrets and templates are served
+func (a *Agent) apiCacheKey(name string) (string, bool) {
+	for _, s := range a.config.Secrets {
+		if s.Name == name {
+			return name, true
+		}
+	}
+	for _, t := range a.config.Templates {
+		if t.Name == name {
+			return "template:" + name, true
+		}
+	}
+	return "", false
+}
+
+// writeAPIJSON writes v as a JSON response
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes a JSON error response
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,191 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newAPIAgent(t *testing.T) *Agent {
+	t.Helper()
+	agent := &Agent{
+		config: &AgentConfig{
+			Secrets:   []SecretConfig{{Name: "db"}, {Name: "api"}},
+			Templates: []TemplateConfig{{Name: "application"}},
+		},
+		logger:      zap.NewNop(),
+		secretCache: cache.NewSecretCache(time.Hour),
+		api:         newSecretsAPI(t.TempDir()),
+	}
+	agent.api.token = "test-token"
+	return agent
+}
+
+func apiGet(t *testing.T, handler http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSecretsAPI_RequiresToken(t *testing.T) {
+	agent := newAPIAgent(t)
+	agent.api.set("db", map[string]interface{}{"password": "s3cret"}, []byte(`{"password":"s3cret"}`))
+	handler := agent.secretsAPIHandler()
+
+	for _, header := range []string{"", "Bearer wrong", "test-token"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/secrets/db", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "Authorization %q", header)
+		assert.NotContains(t, rec.Body.String(), "s3cret")
+	}
+}
+
+func TestSecretsAPI_GetSecretAndField(t *testing.T) {
+	agent := newAPIAgent(t)
+	agent.api.set("db", map[string]interface{}{"password": "s3cret", "port": float64(5432)}, []byte(`{"password":"s3cret"}`))
+	handler := agent.secretsAPIHandler()
+
+	rec := apiGet(t, handler, "/v1/secrets/db")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var secret apiSecret
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &secret))
+	assert.Equal(t, "db", secret.Name)
+	assert.Equal(t, `{"password":"s3cret"}`, secret.Content)
+	assert.Equal(t, "s3cret", secret.Fields["password"])
+	assert.False(t, secret.Cached)
+
+	rec = apiGet(t, handler, "/v1/secrets/db/fields/password")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "s3cret", rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+
+	rec = apiGet(t, handler, "/v1/secrets/db/fields/port")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "5432", strings.TrimSpace(rec.Body.String()))
+
+	assert.Equal(t, http.StatusNotFound, apiGet(t, handler, "/v1/secrets/db/fields/missing").Code)
+	assert.Equal(t, http.StatusNotFound, apiGet(t, handler, "/v1/secrets/unknown").Code)
+}
+
+func TestSecretsAPI_FallsBackToCache(t *testing.T) {
+	agent := newAPIAgent(t)
+	agent.secretCache.Set("api", []byte("cached-content"))
+	agent.secretCache.Set("template:application", []byte("rendered"))
+	agent.secretCache.Set("unconfigured", []byte("hidden"))
+	handler := agent.secretsAPIHandler()
+
+	rec := apiGet(t, handler, "/v1/secrets/api")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var secret apiSecret
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &secret))
+	assert.Equal(t, "cached-content", secret.Content)
+	assert.True(t, secret.Cached)
+
+	rec = apiGet(t, handler, "/v1/secrets/application")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "rendered")
+
+	assert.Equal(t, http.StatusNotFound, apiGet(t, handler, "/v1/secrets/unconfigured").Code)
+}
+
+func TestSecretsAPI_Watch(t *testing.T) {
+	agent := newAPIAgent(t)
+	agent.api.set("db", nil, []byte("v1"))
+	handler := agent.secretsAPIHandler()
+
+	// Behind the current version: returns immediately
+	rec := apiGet(t, handler, "/v1/watch?version=0")
+	var resp watchResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, uint64(1), resp.Version)
+	assert.Equal(t, []string{"db"}, resp.Changed)
+
+	// Up to date: times out with no changes
+	rec = apiGet(t, handler, "/v1/watch?version=1&timeout=20ms")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, uint64(1), resp.Version)
+	assert.Empty(t, resp.Changed)
+
+	// Unchanged content does not wake watchers; a change does
+	done := make(chan watchResponse)
+	go func() {
+		rec := apiGet(t, handler, "/v1/watch?version=1&timeout=5s")
+		var resp watchResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		done <- resp
+	}()
+	time.Sleep(20 * time.Millisecond)
+	agent.api.set("db", nil, []byte("v1"))
+	agent.api.set("db", nil, []byte("v2"))
+
+	select {
+	case resp := <-done:
+		assert.Equal(t, uint64(2), resp.Version)
+		assert.Equal(t, []string{"db"}, resp.Changed)
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not return after change")
+	}
+
+	// Version from before a sidecar restart: everything is reported
+	rec = apiGet(t, handler, "/v1/watch?version=99")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, uint64(2), resp.Version)
+	assert.Equal(t, []string{"db"}, resp.Changed)
+
+	assert.Equal(t, http.StatusBadRequest, apiGet(t, handler, "/v1/watch?version=x").Code)
+}
+
+func TestSecretsAPI_UnixSocket(t *testing.T) {
+	// Unix socket paths are limited to ~108 bytes; t.TempDir() can be longer
+	dir, err := os.MkdirTemp("", "kapi")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	agent := newAPIAgent(t)
+	agent.api = newSecretsAPI(dir)
+	agent.api.set("db", map[string]interface{}{"password": "s3cret"}, []byte("content"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go agent.startSecretsAPI(ctx)
+
+	tokenPath := filepath.Join(dir, SecretsAPITokenName)
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, SecretsAPISocketName))
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	token, err := os.ReadFile(tokenPath)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", filepath.Join(dir, SecretsAPISocketName))
+		},
+	}}
+	req, err := http.NewRequest(http.MethodGet, "http://keeper/v1/secrets/db/fields/password", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+string(token))
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
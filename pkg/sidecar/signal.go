@@ -0,0 +1,151 @@
+package sidecar
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// defaultProcRoot is where the shared process namespace is visible.
+// Requires shareProcessNamespace: true on the pod (set by the webhook).
+const defaultProcRoot = "/proc"
+
+// signalsByName maps supported signal names to syscall signals
+var signalsByName = map[string]syscall.Signal{
+	"SIGHUP":   syscall.SIGHUP,
+	"SIGINT":   syscall.SIGINT,
+	"SIGQUIT":  syscall.SIGQUIT,
+	"SIGTERM":  syscall.SIGTERM,
+	"SIGUSR1":  syscall.SIGUSR1,
+	"SIGUSR2":  syscall.SIGUSR2,
+	"SIGWINCH": syscall.SIGWINCH,
+}
+
+// parseSignal converts a signal name ("SIGHUP", "HUP", "sighup") to a syscall.Signal
+func parseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, ok := signalsByName[name]
+	if !ok {
+		return 0, fmt.Errorf("unsupported signal: %s", name)
+	}
+	return sig, nil
+}
+
+// findProcesses returns the PIDs of processes named name, skipping selfPID.
+// Only the top-most match of a process tree is returned, so an nginx master
+// is signalled but its workers are not.
+func findProcesses(procRoot, name string, selfPID int) ([]int, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", procRoot, err)
+	}
+
+	matches := make(map[int]int) // pid → ppid
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == selfPID {
+			continue
+		}
+		procName, ppid, ok := readProcess(procRoot, pid)
+		if !ok || procName != name {
+			continue
+		}
+		matches[pid] = ppid
+	}
+
+	var pids []int
+	for pid, ppid := range matches {
+		if _, parentMatches := matches[ppid]; parentMatches {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// readProcess returns the executable name and parent PID of a process.
+// The name is taken from argv[0] so that names longer than the 15-byte
+// comm limit still match; comm is used when cmdline is empty.
+func readProcess(procRoot string, pid int) (string, int, bool) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return "", 0, false
+	}
+	// Format: pid (comm) state ppid ...; comm may contain spaces and parens
+	closeIdx := strings.LastIndexByte(string(stat), ')')
+	openIdx := strings.IndexByte(string(stat), '(')
+	if openIdx < 0 || closeIdx < openIdx {
+		return "", 0, false
+	}
+	fields := strings.Fields(string(stat[closeIdx+1:]))
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	name := string(stat[openIdx+1 : closeIdx])
+
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil && len(cmdline) > 0 {
+		argv0 := strings.SplitN(string(cmdline), "\x00", 2)[0]
+		// nginx rewrites argv[0] to "nginx: master process ..."
+		argv0 = strings.TrimSpace(strings.SplitN(argv0, ":", 2)[0])
+		if argv0 != "" {
+			name = filepath.Base(strings.Fields(argv0)[0])
+		}
+	}
+
+	return name, ppid, true
+}
+
+// signalApplication sends the configured refresh signal to the app process
+func (a *Agent) signalApplication() error {
+	if a.config.RefreshSignal == "" {
+		return nil
+	}
+
+	sig, err := parseSignal(a.config.RefreshSignal)
+	if err != nil {
+		return err
+	}
+
+	if a.config.SignalProcess == "" {
+		return fmt.Errorf("signal %s configured but no target process name set", a.config.RefreshSignal)
+	}
+
+	procRoot := a.procRoot
+	if procRoot == "" {
+		procRoot = defaultProcRoot
+	}
+
+	pids, err := findProcesses(procRoot, a.config.SignalProcess, os.Getpid())
+	if err != nil {
+		return err
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("no process named %q found (is shareProcessNamespace enabled?)", a.config.SignalProcess)
+	}
+
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, sig); err != nil {
+			return fmt.Errorf("failed to send %s to pid %d: %w", a.config.RefreshSignal, pid, err)
+		}
+		a.logger.Info("sent signal to app",
+			zap.String("signal", a.config.RefreshSignal),
+			zap.String("process", a.config.SignalProcess),
+			zap.Int("pid", pid))
+	}
+
+	return nil
+}
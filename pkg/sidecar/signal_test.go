@@ -0,0 +1,104 @@
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// writeFakeProc creates a /proc/<pid> entry with stat and cmdline files
+func writeFakeProc(t *testing.T, root string, pid, ppid int, comm, cmdline string) {
+	t.Helper()
+	dir := filepath.Join(root, strconv.Itoa(pid))
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	stat := strconv.Itoa(pid) + " (" + comm + ") S " + strconv.Itoa(ppid) + " 1 1 0"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0644))
+}
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{"SIGHUP", syscall.SIGHUP, false},
+		{"HUP", syscall.SIGHUP, false},
+		{"sigusr1", syscall.SIGUSR1, false},
+		{" SIGUSR2 ", syscall.SIGUSR2, false},
+		{"SIGKILL", 0, true},
+		{"bogus", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSignal(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindProcesses(t *testing.T) {
+	root := t.TempDir()
+	writeFakeProc(t, root, 1, 0, "pause", "/pause\x00")
+	writeFakeProc(t, root, 7, 0, "nginx", "nginx: master process nginx -g daemon off;\x00")
+	writeFakeProc(t, root, 12, 7, "nginx", "nginx: worker process\x00")
+	writeFakeProc(t, root, 13, 7, "nginx", "nginx: worker process\x00")
+	writeFakeProc(t, root, 20, 0, "pgbouncer", "/usr/bin/pgbouncer\x00/etc/pgbouncer.ini\x00")
+	writeFakeProc(t, root, 30, 0, "keeper-sidecar", "/keeper-sidecar\x00--mode=sidecar\x00")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "self"), 0755))
+
+	pids, err := findProcesses(root, "nginx", 30)
+	require.NoError(t, err)
+	assert.Equal(t, []int{7}, pids, "only the nginx master should be signalled")
+
+	pids, err = findProcesses(root, "pgbouncer", 30)
+	require.NoError(t, err)
+	assert.Equal(t, []int{20}, pids)
+
+	pids, err = findProcesses(root, "keeper-sidecar", 30)
+	require.NoError(t, err)
+	assert.Empty(t, pids, "agent must not signal itself")
+}
+
+func TestFindProcesses_CommFallback(t *testing.T) {
+	root := t.TempDir()
+	writeFakeProc(t, root, 5, 1, "app", "")
+	writeFakeProc(t, root, 6, 1, "app", "")
+
+	pids, err := findProcesses(root, "app", 0)
+	require.NoError(t, err)
+	sort.Ints(pids)
+	assert.Equal(t, []int{5, 6}, pids)
+}
+
+func TestSignalApplication_NoSignalConfigured(t *testing.T) {
+	agent := &Agent{
+		config: &AgentConfig{},
+		logger: zap.NewNop(),
+	}
+	assert.NoError(t, agent.signalApplication())
+}
+
+func TestSignalApplication_ProcessNotFound(t *testing.T) {
+	agent := &Agent{
+		config:   &AgentConfig{RefreshSignal: "SIGHUP", SignalProcess: "nginx"},
+		logger:   zap.NewNop(),
+		procRoot: t.TempDir(),
+	}
+	err := agent.signalApplication()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "shareProcessNamespace")
+}
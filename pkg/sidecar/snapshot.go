@@ -0,0 +1,307 @@
+package sidecar
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Snapshot layout, modelled on kubelet's AtomicWriter for ConfigMap volumes:
+//
+//	<root>/..2026_01_02_15_04_05.123456789/db.json   generation directory
+//	<root>/..data -> ..2026_01_02_15_04_05.123456789  current generation
+//	<root>/db.json -> ..data/db.json                  user-visible path
+//
+// Every refresh that changes at least one file builds a complete new generation
+// and swaps ..data with a single rename, so readers never see a mix of old and
+// new files.
+const (
+	dataDirName    = "..data"
+	dataDirTmpName = "..data_tmp"
+	generationFmt  = "2006_01_02_15_04_05.000000000"
+)
+
+// DefaultOutputRoot is the shared secrets volume mount path
+const DefaultOutputRoot = "/keeper/secrets"
+
+// snapshotFiles returns the pending snapshot of root. It starts from the
+// generation already on disk, written by the init container or before a
+// restart, so files not published again are carried over rather than lost.
+func (a *Agent) snapshotFiles(root string) map[string][]byte {
+	if a.snapshots == nil {
+		a.snapshots = make(map[string]map[string][]byte)
+	}
+	files, ok := a.snapshots[root]
+	if !ok {
+		files = a.loadSnapshot(root)
+		a.snapshots[root] = files
+	}
+	return files
+}
+
+// loadSnapshot reads the files of the current generation under root.
+// Entries starting with ".." are injector metadata and are skipped.
+func (a *Agent) loadSnapshot(root string) map[string][]byte {
+	files := make(map[string][]byte)
+	target, err := os.Readlink(filepath.Join(root, dataDirName))
+	if err != nil {
+		return files
+	}
+	generation := filepath.Join(root, target)
+
+	err = filepath.WalkDir(generation, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == generation {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), "..") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(generation, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[rel] = data
+		return nil
+	})
+	if err != nil {
+		a.logger.Warn("failed to read current snapshot", zap.String("root", root), zap.Error(err))
+		return make(map[string][]byte)
+	}
+
+	a.logger.Debug("loaded secrets snapshot",
+		zap.String("root", root),
+		zap.String("generation", target),
+		zap.Int("files", len(files)))
+	return files
+}
+
+// stageSecret adds a file to the pending snapshot of its output root.
+// dirty marks the root for a new generation on the next commit.
+func (a *Agent) stageSecret(path string, data []byte, dirty bool) {
+	if a.dirtyRoots == nil {
+		a.dirtyRoots = make(map[string]bool)
+	}
+
+	root := a.outputRoot(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+
+	files := a.snapshotFiles(root)
+	files[rel] = data
+	if dirty {
+		a.dirtyRoots[root] = true
+	}
+}
+
+// unstageSecret removes a file from the pending snapshot of its output root,
+// so the next generation no longer contains it. Returns false if the file
+// was not staged.
+func (a *Agent) unstageSecret(path string) bool {
+	root := a.outputRoot(path)
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+
+	files := a.snapshotFiles(root)
+	if _, ok := files[rel]; !ok {
+		return false
+	}
+	delete(files, rel)
+	delete(a.digests, path)
+	if a.dirtyRoots == nil {
+		a.dirtyRoots = make(map[string]bool)
+	}
+	a.dirtyRoots[root] = true
+	return true
+}
+
+// outputRoot returns the directory a path is published under atomically.
+// Paths under a configured output root share that root's generation;
+// any other path is published within its own parent directory.
+func (a *Agent) outputRoot(path string) string {
+	roots := []string{DefaultOutputRoot}
+	if a.config != nil && len(a.config.OutputRoots) > 0 {
+		roots = a.config.OutputRoots
+	}
+
+	best := ""
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		if strings.HasPrefix(path, root+string(filepath.Separator)) && len(root) > len(best) {
+			best = root
+		}
+	}
+	if best == "" {
+		return filepath.Dir(path)
+	}
+	return best
+}
+
+// commitSnapshots publishes a new generation for every root with staged changes
+func (a *Agent) commitSnapshots() error {
+	roots := make([]string, 0, len(a.dirtyRoots))
+	for root := range a.dirtyRoots {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	var errs []string
+	for _, root := range roots {
+		if err := a.commitSnapshot(root, a.snapshots[root]); err != nil {
+			errs = append(errs, err.Error())
+			// Forget digests so the next refresh compares against disk again
+			for rel := range a.snapshots[root] {
+				delete(a.digests, filepath.Join(root, rel))
+			}
+			continue
+		}
+		delete(a.dirtyRoots, root)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to publish snapshot: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// commitSnapshot writes files into a new generation directory under root,
+// swaps the ..data symlink to it and removes older generations.
+func (a *Agent) commitSnapshot(root string, files map[string][]byte) error {
+	if err := os.MkdirAll(root, 0750); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", root, err)
+	}
+
+	// 1. Build the new generation
+	generation, err := os.MkdirTemp(root, ".."+time.Now().Format(generationFmt)+".")
+	if err != nil {
+		return fmt.Errorf("failed to create generation directory: %w", err)
+	}
+	if err := os.Chmod(generation, 0750); err != nil {
+		_ = os.RemoveAll(generation)
+		return fmt.Errorf("failed to set generation directory permissions: %w", err)
+	}
+	for rel, data := range files {
+		if err := a.writeSecretFile(filepath.Join(generation, rel), data); err != nil {
+			_ = os.RemoveAll(generation)
+			return err
+		}
+	}
+
+	if err := a.writeFolderManifest(root, generation); err != nil {
+		_ = os.RemoveAll(generation)
+		return err
+	}
+
+	// 2. Swap ..data in one rename
+	tmpLink := filepath.Join(root, dataDirTmpName)
+	_ = os.Remove(tmpLink)
+	if err := os.Symlink(filepath.Base(generation), tmpLink); err != nil {
+		_ = os.RemoveAll(generation)
+		return fmt.Errorf("failed to create %s symlink: %w", dataDirTmpName, err)
+	}
+	if err := os.Rename(tmpLink, filepath.Join(root, dataDirName)); err != nil {
+		_ = os.Remove(tmpLink)
+		_ = os.RemoveAll(generation)
+		return fmt.Errorf("failed to swap %s symlink: %w", dataDirName, err)
+	}
+
+	// 3. Point user-visible top-level entries into ..data
+	visible := make(map[string]bool)
+	for rel := range files {
+		visible[strings.SplitN(rel, string(filepath.Separator), 2)[0]] = true
+	}
+	for name := range visible {
+		if err := ensureDataLink(root, name); err != nil {
+			return err
+		}
+	}
+
+	// 4. Remove stale links and old generations
+	a.cleanupRoot(root, filepath.Base(generation), visible)
+
+	a.logger.Debug("published secrets snapshot",
+		zap.String("root", root),
+		zap.String("generation", filepath.Base(generation)),
+		zap.Int("files", len(files)))
+	return nil
+}
+
+// ensureDataLink makes root/name a symlink to ..data/name, replacing any
+// regular file or directory left by older agent versions.
+func ensureDataLink(root, name string) error {
+	path := filepath.Join(root, name)
+	target := filepath.Join(dataDirName, name)
+
+	if current, err := os.Readlink(path); err == nil && current == target {
+		return nil
+	}
+
+	if info, err := os.Lstat(path); err == nil && info.IsDir() {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to replace directory %s: %w", path, err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("failed to create symlink for %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s with symlink: %w", path, err)
+	}
+	return nil
+}
+
+// cleanupRoot removes generations other than current and top-level symlinks
+// into ..data that are no longer part of the snapshot.
+func (a *Agent) cleanupRoot(root, current string, visible map[string]bool) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		a.logger.Warn("failed to list snapshot root", zap.String("root", root), zap.Error(err))
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(root, name)
+
+		switch {
+		case name == dataDirName || name == current:
+			continue
+
+		case strings.HasPrefix(name, "..") && entry.IsDir():
+			if err := os.RemoveAll(path); err != nil {
+				a.logger.Warn("failed to remove old generation", zap.String("path", path), zap.Error(err))
+			}
+
+		case entry.Type()&os.ModeSymlink != 0 && !visible[name]:
+			target, err := os.Readlink(path)
+			if err == nil && strings.HasPrefix(target, dataDirName+string(filepath.Separator)) {
+				_ = os.Remove(path)
+			}
+		}
+	}
+}
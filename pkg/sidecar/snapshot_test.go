@@ -0,0 +1,133 @@
+package sidecar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// generations lists the generation directories under root
+func generations(t *testing.T, root string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	var gens []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), "..") {
+			gens = append(gens, e.Name())
+		}
+	}
+	return gens
+}
+
+func newSnapshotAgent(root string) *Agent {
+	return &Agent{
+		config: &AgentConfig{OutputRoots: []string{root}},
+		logger: zap.NewNop(),
+	}
+}
+
+func TestCommitSnapshot_Layout(t *testing.T) {
+	root := t.TempDir()
+	agent := newSnapshotAgent(root)
+
+	_, err := agent.publishSecret("db", filepath.Join(root, "db.json"), []byte("db-v1"))
+	require.NoError(t, err)
+	_, err = agent.publishSecret("tls", filepath.Join(root, "tls", "tls.key"), []byte("key-v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+
+	// ..data points at the only generation
+	target, err := os.Readlink(filepath.Join(root, dataDirName))
+	require.NoError(t, err)
+	assert.Equal(t, []string{target}, generations(t, root))
+
+	// User-visible entries are symlinks into ..data
+	link, err := os.Readlink(filepath.Join(root, "db.json"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dataDirName, "db.json"), link)
+	link, err = os.Readlink(filepath.Join(root, "tls"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dataDirName, "tls"), link)
+
+	content, err := os.ReadFile(filepath.Join(root, "tls", "tls.key"))
+	require.NoError(t, err)
+	assert.Equal(t, "key-v1", string(content))
+}
+
+func TestCommitSnapshot_SwapCarriesUnchangedFiles(t *testing.T) {
+	root := t.TempDir()
+	agent := newSnapshotAgent(root)
+
+	_, err := agent.publishSecret("db", filepath.Join(root, "db.json"), []byte("db-v1"))
+	require.NoError(t, err)
+	_, err = agent.publishSecret("tls", filepath.Join(root, "tls.key"), []byte("key-v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	first := generations(t, root)
+
+	// Only db changes; tls.key must still be present in the new generation
+	changed, err := agent.publishSecret("db", filepath.Join(root, "db.json"), []byte("db-v2"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	_, err = agent.publishSecret("tls", filepath.Join(root, "tls.key"), []byte("key-v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+
+	second := generations(t, root)
+	require.Len(t, second, 1, "old generations are removed")
+	assert.NotEqual(t, first, second)
+
+	content, err := os.ReadFile(filepath.Join(root, "db.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "db-v2", string(content))
+	content, err = os.ReadFile(filepath.Join(root, "tls.key"))
+	require.NoError(t, err)
+	assert.Equal(t, "key-v1", string(content))
+}
+
+func TestCommitSnapshot_NoChangesNoNewGeneration(t *testing.T) {
+	root := t.TempDir()
+	agent := newSnapshotAgent(root)
+
+	_, err := agent.publishSecret("db", filepath.Join(root, "db.json"), []byte("db-v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	first := generations(t, root)
+
+	_, err = agent.publishSecret("db", filepath.Join(root, "db.json"), []byte("db-v1"))
+	require.NoError(t, err)
+	require.NoError(t, agent.commitSnapshots())
+	assert.Equal(t, first, generations(t, root))
+}
+
+func TestCommitSnapshot_ReplacesLegacyFile(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "db.json"), []byte("legacy"), 0400))
+	agent := newSnapshotAgent(root)
+
+	changed, err := agent.publishSecret("db", filepath.Join(root, "db.json"), []byte("db-v2"))
+	require.NoError(t, err)
+	assert.True(t, changed)
+	require.NoError(t, agent.commitSnapshots())
+
+	info, err := os.Lstat(filepath.Join(root, "db.json"))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+}
+
+func TestOutputRoot(t *testing.T) {
+	agent := &Agent{config: &AgentConfig{OutputRoots: []string{"/keeper/secrets", "/keeper/secrets/db"}}}
+
+	assert.Equal(t, "/keeper/secrets", agent.outputRoot("/keeper/secrets/api.json"))
+	assert.Equal(t, "/keeper/secrets/db", agent.outputRoot("/keeper/secrets/db/pg.json"))
+	assert.Equal(t, "/app/config", agent.outputRoot("/app/config/app.yaml"))
+
+	agent = &Agent{}
+	assert.Equal(t, DefaultOutputRoot, agent.outputRoot("/keeper/secrets/tls/tls.key"))
+}
@@ -6,49 +6,257 @@ import (
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"text/template"
+	"text/template/parse"
+	"time"
 
 	"github.com/Masterminds/sprig/v3"
 )
 
+// maxTemplateOutput limits the size of a rendered template (1 MiB)
+const maxTemplateOutput = 1 << 20
+
+// maxTemplateListLen limits the lists built by until and untilStep
+const maxTemplateListLen = 10000
+
+// loopGuardFunc is called at the start of every range iteration. It is added
+// to the parsed tree only, so templates cannot call it.
+const loopGuardFunc = "keeperLoopGuard"
+
+// templateTimeout limits template execution time (variable for tests)
+var templateTimeout = 5 * time.Second
+
+// maxTemplateIterations limits the range iterations of one render, including
+// nested ranges (variable for tests)
+var maxTemplateIterations = 1000000
+
+// unsafeTemplateFuncs read the agent's environment (which holds KEEPER_AUTH_CONFIG)
+// or reach the network. They are removed unless the cluster operator allows them.
+var unsafeTemplateFuncs = []string{"env", "expandenv", "getHostByName"}
+
+// unboundedTemplateFuncs allocate without limit and are always removed;
+// until, untilStep and repeat are replaced with capped versions instead
+var unboundedTemplateFuncs = []string{"seq"}
+
+// errTemplateOutputTooLarge is returned when a template renders more than maxTemplateOutput
+var errTemplateOutputTooLarge = fmt.Errorf("template output exceeds %d bytes", maxTemplateOutput)
+
 // renderTemplate executes a Go template with secret data.
 // Uses Sprig library for comprehensive template functions.
 func renderTemplate(data map[string]interface{}, templateStr string) ([]byte, error) {
+	return renderTemplateWithContext(data, templateStr, nil)
+}
+
+// renderTemplateWithContext executes a Go template with secret data and the
+// record context used by fileContent and bcrypt.
+func renderTemplateWithContext(data map[string]interface{}, templateStr string, rc *renderContext) ([]byte, error) {
 	if templateStr == "" {
 		return nil, fmt.Errorf("template string is empty")
 	}
 
-	// Create template with Sprig functions
-	tmpl, err := template.New("secret").Funcs(templateFuncs()).Parse(templateStr)
+	tmpl, err := parseTemplate(templateStr, rc)
 	if err != nil {
-		return nil, fmt.Errorf("template parse error: %w", err)
+		return nil, err
 	}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return nil, fmt.Errorf("template execute error: %w", err)
+	// Execute with size, iteration and time limits. On timeout the execution
+	// is aborted: its writes fail and its next range iteration stops it.
+	out := &limitedBuffer{limit: maxTemplateOutput}
+	iterations := 0
+	tmpl.Funcs(template.FuncMap{
+		loopGuardFunc: func() (string, error) {
+			if out.aborted.Load() {
+				return "", fmt.Errorf("template execution aborted")
+			}
+			iterations++
+			if iterations > maxTemplateIterations {
+				return "", fmt.Errorf("template exceeds %d range iterations", maxTemplateIterations)
+			}
+			return "", nil
+		},
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- tmpl.Execute(out, data)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			if errors.Is(err, errTemplateOutputTooLarge) {
+				return nil, errTemplateOutputTooLarge
+			}
+			return nil, fmt.Errorf("template execute error: %w", err)
+		}
+		return out.buf.Bytes(), nil
+	case <-time.After(templateTimeout):
+		out.aborted.Store(true)
+		return nil, fmt.Errorf("template execution exceeded %s", templateTimeout)
 	}
+}
+
+// limitedBuffer is a bytes.Buffer that rejects writes beyond limit or after abort
+type limitedBuffer struct {
+	buf     bytes.Buffer
+	limit   int
+	aborted atomic.Bool
+}
 
-	return buf.Bytes(), nil
+// Write implements io.Writer
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.aborted.Load() {
+		return 0, fmt.Errorf("template execution aborted")
+	}
+	if b.buf.Len()+len(p) > b.limit {
+		return 0, errTemplateOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// ValidateTemplate checks that a template string parses with the agent's
+// function map. The webhook uses it to reject broken templates at admission.
+// allowUnsafe must match the agent's --allow-unsafe-template-funcs setting.
+func ValidateTemplate(templateStr string, allowUnsafe bool) error {
+	if templateStr == "" {
+		return fmt.Errorf("template string is empty")
+	}
+	_, err := parseTemplate(templateStr, &renderContext{allowUnsafe: allowUnsafe})
+	return err
+}
+
+// parseTemplate parses a template with Sprig and Keeper functions and
+// guards every range loop with loopGuardFunc
+func parseTemplate(templateStr string, rc *renderContext) (*template.Template, error) {
+	tmpl, err := template.New("secret").Funcs(templateFuncs(rc)).Parse(templateStr)
+	if err != nil {
+		return nil, fmt.Errorf("template parse error: %w", err)
+	}
+	tmpl.Funcs(template.FuncMap{loopGuardFunc: func() string { return "" }})
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			guardLoops(t.Tree.Root)
+		}
+	}
+	return tmpl, nil
+}
+
+// guardLoops inserts a loopGuardFunc call at the start of each range body.
+// text/template cannot be interrupted, so this is what stops loops that
+// produce no output once the render times out.
+func guardLoops(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			guardLoops(child)
+		}
+	case *parse.IfNode:
+		guardLoops(n.List)
+		guardLoops(n.ElseList)
+	case *parse.WithNode:
+		guardLoops(n.List)
+		guardLoops(n.ElseList)
+	case *parse.RangeNode:
+		guardLoops(n.List)
+		guardLoops(n.ElseList)
+		if n.List == nil {
+			return
+		}
+		guard := &parse.ActionNode{
+			NodeType: parse.NodeAction,
+			Pos:      n.Pos,
+			Line:     n.Line,
+			Pipe: &parse.PipeNode{
+				NodeType: parse.NodePipe,
+				Pos:      n.Pos,
+				Line:     n.Line,
+				Cmds: []*parse.CommandNode{{
+					NodeType: parse.NodeCommand,
+					Pos:      n.Pos,
+					Args:     []parse.Node{parse.NewIdentifier(loopGuardFunc).SetPos(n.Pos)},
+				}},
+			},
+		}
+		n.List.Nodes = append([]parse.Node{guard}, n.List.Nodes...)
+	}
 }
 
 // templateFuncs returns the template function map.
 // Uses Sprig as base (100+ functions) and adds Keeper-specific overrides.
-func templateFuncs() template.FuncMap {
+// Unsafe functions are only included when the render context allows them.
+func templateFuncs(rc *renderContext) template.FuncMap {
 	// Start with Sprig's comprehensive function library
 	// Provides: date/time, crypto, string, math, encoding, and more
 	funcs := sprig.TxtFuncMap()
+	if rc == nil || !rc.allowUnsafe {
+		for _, name := range unsafeTemplateFuncs {
+			delete(funcs, name)
+		}
+	}
+
+	// Cap functions that allocate based on their arguments
+	for _, name := range unboundedTemplateFuncs {
+		delete(funcs, name)
+	}
+	funcs["until"] = until
+	funcs["untilStep"] = untilStep
+	funcs["repeat"] = repeat
 
 	// Add or override with Keeper-specific functions
 	funcs["base64enc"] = base64Encode
 	funcs["base64dec"] = base64Decode
 	funcs["sha256sum"] = sha256Hash
 	funcs["sha512sum"] = sha512Hash
+	for name, fn := range keeperFuncs(rc) {
+		funcs[name] = fn
+	}
 
 	return funcs
 }
 
+// until returns the integers from 0 towards count, like Sprig's until,
+// limited to maxTemplateListLen elements
+func until(count int) ([]int, error) {
+	step := 1
+	if count < 0 {
+		step = -1
+	}
+	return untilStep(0, count, step)
+}
+
+// untilStep returns the integers from start towards stop by step, like
+// Sprig's untilStep, limited to maxTemplateListLen elements
+func untilStep(start, stop, step int) ([]int, error) {
+	var v []int
+	if step == 0 {
+		return v, nil
+	}
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		if len(v) == maxTemplateListLen {
+			return nil, fmt.Errorf("list exceeds %d elements", maxTemplateListLen)
+		}
+		v = append(v, i)
+	}
+	return v, nil
+}
+
+// repeat repeats a string count times, limited to maxTemplateOutput bytes
+func repeat(count int, str string) (string, error) {
+	if count <= 0 {
+		return "", nil
+	}
+	if len(str) > 0 && count > maxTemplateOutput/len(str) {
+		return "", errTemplateOutputTooLarge
+	}
+	return strings.Repeat(str, count), nil
+}
+
 // base64Encode encodes a string to base64.
 func base64Encode(s string) string {
 	return base64.StdEncoding.EncodeToString([]byte(s))
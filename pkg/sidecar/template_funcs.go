@@ -0,0 +1,176 @@
+package sidecar
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptHashPattern matches bcrypt hashes in previously rendered output
+var bcryptHashPattern = regexp.MustCompile(`\$2[aby]\$[0-9]{2}\$[./A-Za-z0-9]{53}`)
+
+// renderContext carries per-render inputs for the Keeper template functions.
+// A nil context still parses templates; functions that need it return an error.
+type renderContext struct {
+	// fileContent returns an attachment of the rendered record(s) by name
+	fileContent func(name string) ([]byte, error)
+	// previous is the last published output; its bcrypt hashes are reused
+	// while the password is unchanged so the output stays stable
+	previous []byte
+	// allowUnsafe enables functions that read the environment or network
+	allowUnsafe bool
+}
+
+// keeperFuncs returns the Keeper-specific template functions
+func keeperFuncs(rc *renderContext) template.FuncMap {
+	return template.FuncMap{
+		"totp":        ksm.GetTOTPCode,
+		"pemChain":    pemChain,
+		"pemKey":      pemKey,
+		"bcrypt":      rc.bcrypt,
+		"htpasswd":    rc.htpasswd,
+		"urlEncode":   url.QueryEscape,
+		"dsn":         dsn,
+		"fileContent": rc.readFile,
+	}
+}
+
+// newRenderContext builds the render context for a template written to path
+// from the given records
+func (a *Agent) newRenderContext(ctx context.Context, path string, records []*ksm.SecretData) *renderContext {
+	return &renderContext{
+		fileContent: func(name string) ([]byte, error) {
+			var owner *ksm.SecretData
+			for _, record := range records {
+				for _, f := range record.Files {
+					if f.Name != name && f.Title != name {
+						continue
+					}
+					if owner != nil && owner != record {
+						return nil, fmt.Errorf("attachment %q found in several records", name)
+					}
+					owner = record
+				}
+			}
+			if owner == nil {
+				return nil, fmt.Errorf("attachment %q not found", name)
+			}
+			return a.ksmClient.GetFileContent(ctx, owner.RecordUID, name)
+		},
+		previous:    a.previousOutput(path),
+		allowUnsafe: a.config.AllowUnsafeTemplateFuncs,
+	}
+}
+
+// previousOutput returns the content last published at path, if any
+func (a *Agent) previousOutput(path string) []byte {
+	root := a.outputRoot(path)
+	if rel, err := filepath.Rel(root, path); err == nil {
+		if data, ok := a.snapshotFiles(root)[rel]; ok {
+			return data
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// readFile inlines an attachment of the rendered record
+func (rc *renderContext) readFile(name string) (string, error) {
+	if rc == nil || rc.fileContent == nil {
+		return "", fmt.Errorf("fileContent is not available in this template")
+	}
+	data, err := rc.fileContent(name)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// bcrypt hashes a password, reusing a matching hash from the previous output
+func (rc *renderContext) bcrypt(password string) (string, error) {
+	if rc != nil {
+		for _, candidate := range bcryptHashPattern.FindAll(rc.previous, -1) {
+			if bcrypt.CompareHashAndPassword(candidate, []byte(password)) == nil {
+				return string(candidate), nil
+			}
+		}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt failed: %w", err)
+	}
+	return string(hash), nil
+}
+
+// htpasswd returns an htpasswd line with a bcrypt hash
+func (rc *renderContext) htpasswd(user, password string) (string, error) {
+	if strings.Contains(user, ":") {
+		return "", fmt.Errorf("htpasswd user must not contain ':'")
+	}
+	hash, err := rc.bcrypt(password)
+	if err != nil {
+		return "", err
+	}
+	return user + ":" + hash, nil
+}
+
+// dsn builds a URL-style connection string with escaped credentials,
+// e.g. dsn "postgres" .login .password "db:5432" "app" → postgres://user:pass@db:5432/app
+func dsn(scheme, user, password, host, database string) string {
+	u := url.URL{
+		Scheme: scheme,
+		User:   url.UserPassword(user, password),
+		Host:   host,
+	}
+	if database != "" {
+		u.Path = "/" + database
+	}
+	return u.String()
+}
+
+// pemChain returns the certificates in a PEM bundle, in order
+func pemChain(bundle string) ([]string, error) {
+	var certs []string
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			certs = append(certs, string(pem.EncodeToMemory(block)))
+		}
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates found in PEM bundle")
+	}
+	return certs, nil
+}
+
+// pemKey returns the first private key in a PEM bundle
+func pemKey(bundle string) (string, error) {
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return "", fmt.Errorf("no private key found in PEM bundle")
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return string(pem.EncodeToMemory(block)), nil
+		}
+	}
+}
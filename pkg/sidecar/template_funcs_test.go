@@ -0,0 +1,118 @@
+package sidecar
+
+import (
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func pemBlock(blockType, body string) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: []byte(body)}))
+}
+
+func TestPemChainAndKey(t *testing.T) {
+	leaf := pemBlock("CERTIFICATE", "leaf")
+	intermediate := pemBlock("CERTIFICATE", "intermediate")
+	key := pemBlock("EC PRIVATE KEY", "key")
+	bundle := key + leaf + intermediate
+
+	chain, err := pemChain(bundle)
+	require.NoError(t, err)
+	assert.Equal(t, []string{leaf, intermediate}, chain)
+
+	got, err := pemKey(bundle)
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	_, err = pemChain(key)
+	assert.Error(t, err)
+	_, err = pemKey(leaf)
+	assert.Error(t, err)
+
+	out, err := renderTemplate(map[string]interface{}{"cert": bundle}, `{{ index (pemChain .cert) 1 }}{{ pemKey .cert }}`)
+	require.NoError(t, err)
+	assert.Equal(t, intermediate+key, string(out))
+}
+
+func TestBcrypt_ReusesPreviousHash(t *testing.T) {
+	var rc *renderContext
+	first, err := rc.bcrypt("s3cret")
+	require.NoError(t, err)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(first), []byte("s3cret")))
+
+	// Same password with the previous output available: identical hash
+	rc = &renderContext{previous: []byte("admin:" + first + "\n")}
+	again, err := rc.bcrypt("s3cret")
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+
+	// Changed password: new hash
+	changed, err := rc.bcrypt("rotated")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, changed)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(changed), []byte("rotated")))
+}
+
+func TestHtpasswd(t *testing.T) {
+	out, err := renderTemplate(map[string]interface{}{"login": "admin", "password": "s3cret"}, `{{ htpasswd .login .password }}`)
+	require.NoError(t, err)
+	user, hash, ok := strings.Cut(string(out), ":")
+	require.True(t, ok)
+	assert.Equal(t, "admin", user)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("s3cret")))
+
+	_, err = renderTemplate(map[string]interface{}{"login": "a:b"}, `{{ htpasswd .login "x" }}`)
+	assert.Error(t, err)
+}
+
+func TestDSNAndURLEncode(t *testing.T) {
+	data := map[string]interface{}{"login": "app user", "password": "p@ss/word:1"}
+
+	out, err := renderTemplate(data, `{{ dsn "postgres" .login .password "db:5432" "orders" }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://app%20user:p%40ss%2Fword%3A1@db:5432/orders", string(out))
+
+	out, err = renderTemplate(data, `{{ urlEncode .password }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "p%40ss%2Fword%3A1", string(out))
+}
+
+func TestTOTP(t *testing.T) {
+	data := map[string]interface{}{
+		"oneTimeCode": "otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&issuer=Example&algorithm=SHA1&digits=6&period=30",
+	}
+	out, err := renderTemplate(data, `{{ totp .oneTimeCode }}`)
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9]{6}$`), string(out))
+
+	_, err = renderTemplate(map[string]interface{}{"oneTimeCode": "not-a-url"}, `{{ totp .oneTimeCode }}`)
+	assert.Error(t, err)
+}
+
+func TestFileContent(t *testing.T) {
+	rc := &renderContext{
+		fileContent: func(name string) ([]byte, error) {
+			if name == "ca.pem" {
+				return []byte("CA DATA"), nil
+			}
+			return nil, fmt.Errorf("attachment %q not found", name)
+		},
+	}
+
+	out, err := renderTemplateWithContext(map[string]interface{}{}, `ca: {{ fileContent "ca.pem" }}`, rc)
+	require.NoError(t, err)
+	assert.Equal(t, "ca: CA DATA", string(out))
+
+	_, err = renderTemplateWithContext(map[string]interface{}{}, `{{ fileContent "missing.pem" }}`, rc)
+	assert.Error(t, err)
+
+	// Without a record context the function exists but fails
+	_, err = renderTemplate(map[string]interface{}{}, `{{ fileContent "ca.pem" }}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package sidecar
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/metrics"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar/retry"
+	"go.uber.org/zap"
+)
+
+// TemplateConfig is a file rendered from several Keeper records.
+// Records maps the alias used in the template ({{ .db.password }}) to a record title or UID.
+type TemplateConfig struct {
+	Name         string            `json:"name"`
+	Path         string            `json:"path"`
+	Records      map[string]string `json:"records"`
+	Template     string            `json:"template"`
+	TemplateFile string            `json:"templateFile,omitempty"` // Mounted template file (overrides Template)
+	Hooks        []HookConfig      `json:"hooks,omitempty"`
+}
+
+// fetchTemplates renders every template output from a single fetch of all
+// records the templates depend on. Returns the number of outputs rendered.
+func (a *Agent) fetchTemplates(ctx context.Context) (int, []error) {
+	if len(a.config.Templates) == 0 {
+		return 0, nil
+	}
+
+	startTime := time.Now()
+	records, fetchErr := a.fetchTemplateRecords(ctx)
+	if fetchErr != nil {
+		a.logger.Error("failed to fetch template records", zap.Error(fetchErr))
+	}
+
+	var errs []error
+	count := 0
+	for _, tmpl := range a.config.Templates {
+		if err := a.publishTemplate(ctx, tmpl, records, fetchErr); err != nil {
+			a.logger.Error("failed to render template",
+				zap.String("name", tmpl.Name),
+				zap.Error(err))
+			errs = append(errs, err)
+			metrics.RecordSecretFetch("template:"+tmpl.Name, false, time.Since(startTime).Seconds())
+			continue
+		}
+		a.lastFetch["template:"+tmpl.Name] = time.Now()
+		metrics.RecordSecretFetch("template:"+tmpl.Name, true, time.Since(startTime).Seconds())
+		count++
+	}
+	return count, errs
+}
+
+// templateRecords are the records referenced by the templates, keyed by UID or title
+type templateRecords struct {
+	byRef     map[string]*ksm.SecretData
+	ambiguous map[string]bool // Titles matching several records under strict lookup
+}
+
+// fetchTemplateRecords fetches every record referenced by a template in one
+// Keeper API call and resolves them by UID or title.
+func (a *Agent) fetchTemplateRecords(ctx context.Context) (templateRecords, error) {
+	var refs []string
+	for _, tmpl := range a.config.Templates {
+		for _, ref := range tmpl.Records {
+			refs = append(refs, ref)
+		}
+	}
+
+	var all []*ksm.SecretData
+	err := retry.WithRetry(ctx, retry.DefaultConfig(), func() error {
+		var getErr error
+		all, getErr = a.ksmClient.GetSecretsByRefs(ctx, refs)
+		return getErr
+	})
+	if err != nil {
+		return templateRecords{}, fmt.Errorf("failed to fetch records: %w", err)
+	}
+
+	records := resolveTemplateRecords(all, a.config.Templates)
+	for title := range records.ambiguous {
+		if a.config.StrictLookup {
+			delete(records.byRef, title)
+			continue
+		}
+		delete(records.ambiguous, title)
+		a.logger.Warn("multiple records match title, using first match", zap.String("title", title))
+	}
+
+	a.logger.Debug("fetched template records",
+		zap.Int("templates", len(a.config.Templates)),
+		zap.Int("records", len(records.byRef)))
+	return records, nil
+}
+
+// resolveTemplateRecords maps each record referenced by the templates to its data.
+// UIDs take precedence over titles; the first record wins for duplicate titles,
+// which are also marked ambiguous so the caller can apply strict lookup.
+func resolveTemplateRecords(all []*ksm.SecretData, templates []TemplateConfig) templateRecords {
+	byUID := make(map[string]*ksm.SecretData, len(all))
+	byTitle := make(map[string]*ksm.SecretData, len(all))
+	titleCount := make(map[string]int, len(all))
+	for _, record := range all {
+		byUID[record.RecordUID] = record
+		if _, ok := byTitle[record.Title]; !ok {
+			byTitle[record.Title] = record
+		}
+		titleCount[record.Title]++
+	}
+
+	records := templateRecords{
+		byRef:     make(map[string]*ksm.SecretData),
+		ambiguous: make(map[string]bool),
+	}
+	for _, tmpl := range templates {
+		for _, ref := range tmpl.Records {
+			if _, done := records.byRef[ref]; done {
+				continue
+			}
+			if record, ok := byUID[ref]; ok {
+				records.byRef[ref] = record
+				continue
+			}
+			if record, ok := byTitle[ref]; ok {
+				records.byRef[ref] = record
+				if titleCount[ref] > 1 {
+					records.ambiguous[ref] = true
+				}
+			}
+		}
+	}
+	return records
+}
+
+// publishTemplate renders a template output and writes it.
+// Falls back to the last rendered content when records are unavailable.
+func (a *Agent) publishTemplate(ctx context.Context, tmpl TemplateConfig, records templateRecords, fetchErr error) error {
+	cacheKey := "template:" + tmpl.Name
+
+	var data []byte
+	err := fetchErr
+	if err == nil {
+		tmpl.Template, err = loadTemplate(tmpl.Template, tmpl.TemplateFile)
+	}
+	if err == nil {
+		data, err = a.renderTemplateOutput(ctx, tmpl, records)
+	}
+
+	if err != nil {
+		if cached, ok := a.secretCache.Get(cacheKey); ok {
+			a.logger.Warn("using cached template output",
+				zap.String("template", tmpl.Name),
+				zap.Duration("cache_age", a.secretCache.Age(cacheKey)),
+				zap.Error(err))
+
+			_, err := a.publishSecret(tmpl.Name, tmpl.Path, cached.Data)
+			return err
+		}
+
+		if a.config.FailOnError {
+			return fmt.Errorf("template %s unavailable and no cached value: %w", tmpl.Name, err)
+		}
+
+		a.logger.Error("template unavailable, no cache, continuing with degraded state",
+			zap.String("template", tmpl.Name),
+			zap.Error(err))
+		return nil
+	}
+
+	a.secretCache.Set(cacheKey, data)
+	if a.api != nil {
+		a.api.set(tmpl.Name, nil, data)
+	}
+
+	_, err = a.publishSecret(tmpl.Name, tmpl.Path, data)
+	return err
+}
+
+// renderTemplateOutput executes a template with each alias bound to its record's fields
+func (a *Agent) renderTemplateOutput(ctx context.Context, tmpl TemplateConfig, records templateRecords) ([]byte, error) {
+	aliases := make([]string, 0, len(tmpl.Records))
+	for alias := range tmpl.Records {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	data := make(map[string]interface{}, len(aliases))
+	used := make([]*ksm.SecretData, 0, len(aliases))
+	for _, alias := range aliases {
+		ref := tmpl.Records[alias]
+		if records.ambiguous[ref] {
+			return nil, fmt.Errorf("record %q for alias %q is ambiguous: several records have this title (strict lookup)", ref, alias)
+		}
+		record, ok := records.byRef[ref]
+		if !ok {
+			return nil, fmt.Errorf("record %q for alias %q not found", ref, alias)
+		}
+		data[alias] = record.Fields
+		used = append(used, record)
+	}
+
+	return renderTemplateWithContext(data, tmpl.Template, a.newRenderContext(ctx, tmpl.Path, used))
+}
@@ -0,0 +1,95 @@
+package sidecar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestResolveTemplateRecords(t *testing.T) {
+	all := []*ksm.SecretData{
+		{RecordUID: "uid-db", Title: "postgres", Fields: map[string]interface{}{"password": "pg-pass"}},
+		{RecordUID: "uid-redis", Title: "redis", Fields: map[string]interface{}{"url": "redis://cache:6379"}},
+		{RecordUID: "uid-dup-1", Title: "shared", Fields: map[string]interface{}{"v": "1"}},
+		{RecordUID: "uid-dup-2", Title: "shared", Fields: map[string]interface{}{"v": "2"}},
+	}
+	templates := []TemplateConfig{
+		{Name: "app", Records: map[string]string{"db": "postgres", "cache": "uid-redis"}},
+		{Name: "other", Records: map[string]string{"db": "postgres", "s": "shared", "gone": "missing"}},
+	}
+
+	records := resolveTemplateRecords(all, templates)
+
+	assert.Len(t, records.byRef, 3)
+	assert.Equal(t, "uid-db", records.byRef["postgres"].RecordUID)
+	assert.Equal(t, "uid-redis", records.byRef["uid-redis"].RecordUID)
+	assert.Equal(t, "uid-dup-1", records.byRef["shared"].RecordUID, "first match wins")
+	assert.NotContains(t, records.byRef, "missing")
+	assert.Equal(t, map[string]bool{"shared": true}, records.ambiguous)
+}
+
+func TestRenderTemplateOutput(t *testing.T) {
+	records := templateRecords{byRef: map[string]*ksm.SecretData{
+		"postgres": {Fields: map[string]interface{}{"password": "pg-pass"}},
+		"redis":    {Fields: map[string]interface{}{"url": "redis://cache:6379"}},
+	}}
+	tmpl := TemplateConfig{
+		Name:     "application",
+		Records:  map[string]string{"db": "postgres", "redis": "redis"},
+		Template: "db: {{ .db.password }}\nredis: {{ .redis.url }}",
+	}
+
+	agent := &Agent{config: &AgentConfig{}, logger: zap.NewNop()}
+	out, err := agent.renderTemplateOutput(context.Background(), tmpl, records)
+	require.NoError(t, err)
+	assert.Equal(t, "db: pg-pass\nredis: redis://cache:6379", string(out))
+
+	tmpl.Records["api"] = "missing"
+	_, err = agent.renderTemplateOutput(context.Background(), tmpl, records)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `alias "api"`)
+
+	delete(tmpl.Records, "api")
+	records.ambiguous = map[string]bool{"redis": true}
+	_, err = agent.renderTemplateOutput(context.Background(), tmpl, records)
+	assert.ErrorContains(t, err, `record "redis" for alias "redis" is ambiguous`)
+}
+
+func TestPublishTemplate_FallsBackToCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "application.yaml")
+	agent := &Agent{
+		config:      &AgentConfig{FailOnError: true},
+		logger:      zap.NewNop(),
+		secretCache: cache.NewSecretCache(time.Hour),
+	}
+	tmpl := TemplateConfig{
+		Name:     "application",
+		Path:     path,
+		Records:  map[string]string{"db": "postgres"},
+		Template: "{{ .db.password }}",
+	}
+	records := templateRecords{byRef: map[string]*ksm.SecretData{"postgres": {Fields: map[string]interface{}{"password": "v1"}}}}
+
+	require.NoError(t, agent.publishTemplate(context.Background(), tmpl, records, nil))
+	require.NoError(t, agent.commitSnapshots())
+
+	// Keeper unavailable: the last rendered output is kept
+	require.NoError(t, agent.publishTemplate(context.Background(), tmpl, templateRecords{}, errors.New("keeper unavailable")))
+	require.NoError(t, agent.commitSnapshots())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(content))
+
+	// No cached output and fail-on-error: the error is returned
+	tmpl.Name = "uncached"
+	assert.Error(t, agent.publishTemplate(context.Background(), tmpl, templateRecords{}, errors.New("keeper unavailable")))
+}
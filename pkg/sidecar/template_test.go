@@ -1,8 +1,10 @@
 package sidecar
 
 import (
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRenderTemplate(t *testing.T) {
@@ -211,3 +213,114 @@ func TestTemplateMultiline(t *testing.T) {
 		t.Errorf("missing base64 encoded password in output: %s", output)
 	}
 }
+
+func TestValidateTemplate(t *testing.T) {
+	if err := ValidateTemplate(`{{ .password | sha256sum | upper }}`, false); err != nil {
+		t.Errorf("ValidateTemplate() unexpected error: %v", err)
+	}
+	if err := ValidateTemplate("", false); err == nil {
+		t.Error("ValidateTemplate() expected error for empty template")
+	}
+	if err := ValidateTemplate(`{{ .password | missing }}`, false); err == nil {
+		t.Error("ValidateTemplate() expected error for unknown function")
+	}
+}
+
+func TestTemplateSandbox_UnsafeFuncs(t *testing.T) {
+	t.Setenv("KEEPER_AUTH_CONFIG", "do-not-leak")
+
+	for _, tmpl := range []string{`{{ env "KEEPER_AUTH_CONFIG" }}`, `{{ expandenv "$KEEPER_AUTH_CONFIG" }}`, `{{ getHostByName "localhost" }}`} {
+		if err := ValidateTemplate(tmpl, false); err == nil {
+			t.Errorf("ValidateTemplate(%q) expected error when unsafe functions are disabled", tmpl)
+		}
+		if _, err := renderTemplate(nil, tmpl); err == nil {
+			t.Errorf("renderTemplate(%q) expected error when unsafe functions are disabled", tmpl)
+		}
+	}
+
+	got, err := renderTemplateWithContext(nil, `{{ env "KEEPER_AUTH_CONFIG" }}`, &renderContext{allowUnsafe: true})
+	if err != nil {
+		t.Fatalf("renderTemplateWithContext() error = %v", err)
+	}
+	if string(got) != "do-not-leak" {
+		t.Errorf("renderTemplateWithContext() = %q, want %q", got, "do-not-leak")
+	}
+}
+
+func TestTemplateSandbox_MaxOutput(t *testing.T) {
+	data := map[string]interface{}{"chunk": strings.Repeat("x", 1024)}
+
+	if _, err := renderTemplate(data, `{{ range until 1023 }}{{ $.chunk }}{{ end }}`); err != nil {
+		t.Errorf("renderTemplate() unexpected error below limit: %v", err)
+	}
+	_, err := renderTemplate(data, `{{ range until 2048 }}{{ $.chunk }}{{ end }}`)
+	if err != errTemplateOutputTooLarge {
+		t.Errorf("renderTemplate() error = %v, want %v", err, errTemplateOutputTooLarge)
+	}
+}
+
+func TestTemplateSandbox_Timeout(t *testing.T) {
+	orig := templateTimeout
+	templateTimeout = time.Millisecond
+	defer func() { templateTimeout = orig }()
+
+	before := runtime.NumGoroutine()
+
+	// Nested ranges that produce no output run well past the timeout
+	_, err := renderTemplate(nil, `{{ range until 1000 }}{{ range until 999 }}{{ end }}{{ end }}`)
+	if err == nil || !strings.Contains(err.Error(), "exceeded") {
+		t.Errorf("renderTemplate() error = %v, want timeout", err)
+	}
+
+	// The aborted execution stops at its next iteration instead of running on
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines still running after timeout, want %d", n, before)
+	}
+}
+
+func TestTemplateSandbox_Iterations(t *testing.T) {
+	orig := maxTemplateIterations
+	maxTemplateIterations = 10000
+	defer func() { maxTemplateIterations = orig }()
+
+	if _, err := renderTemplate(nil, `{{ range until 99 }}{{ range until 100 }}{{ end }}{{ end }}`); err != nil {
+		t.Errorf("renderTemplate() unexpected error at limit: %v", err)
+	}
+	for _, tmpl := range []string{
+		`{{ range until 200 }}{{ range until 200 }}{{ end }}{{ end }}`,
+		`{{ range 100000000 }}{{ end }}`,
+		`{{ define "loop" }}{{ range until 1000 }}{{ end }}{{ end }}{{ range until 20 }}{{ template "loop" }}{{ end }}`,
+	} {
+		_, err := renderTemplate(nil, tmpl)
+		if err == nil || !strings.Contains(err.Error(), "range iterations") {
+			t.Errorf("renderTemplate(%q) error = %v, want iteration limit", tmpl, err)
+		}
+	}
+}
+
+func TestTemplateSandbox_BoundedFuncs(t *testing.T) {
+	got, err := renderTemplate(nil, `{{ until 3 }} {{ untilStep 5 0 -2 }} {{ repeat 3 "ab" }}`)
+	if err != nil {
+		t.Fatalf("renderTemplate() error = %v", err)
+	}
+	if want := "[0 1 2] [5 3 1] ababab"; string(got) != want {
+		t.Errorf("renderTemplate() = %q, want %q", got, want)
+	}
+
+	for _, tmpl := range []string{
+		`{{ until 100000000 }}`,
+		`{{ untilStep 0 100000000 1 }}`,
+		`{{ repeat 100000000 "x" }}`,
+	} {
+		if _, err := renderTemplate(nil, tmpl); err == nil {
+			t.Errorf("renderTemplate(%q) expected error", tmpl)
+		}
+	}
+	if err := ValidateTemplate(`{{ seq 100000000 }}`, false); err == nil {
+		t.Error("ValidateTemplate() expected error for seq")
+	}
+}
@@ -0,0 +1,75 @@
+package sidecar
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// templateWatchInterval is how often mounted template files are checked for changes.
+// Kubelet propagates ConfigMap updates within about a minute, so polling is sufficient.
+const templateWatchInterval = 10 * time.Second
+
+// loadTemplate returns the inline template, or the content of the mounted template file
+func loadTemplate(inline, file string) (string, error) {
+	if file == "" {
+		return inline, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template file %s: %w", file, err)
+	}
+	return string(data), nil
+}
+
+// templateFiles lists the mounted template files used by secrets and templates
+func (a *Agent) templateFiles() []string {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(file string) {
+		if file != "" && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	for _, s := range a.config.Secrets {
+		add(s.TemplateFile)
+	}
+	for _, t := range a.config.Templates {
+		add(t.TemplateFile)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// templateFilesChanged reports whether any template file changed since the last call.
+// The first call records the current content and reports no change.
+func (a *Agent) templateFilesChanged() bool {
+	first := a.templateDigests == nil
+	if first {
+		a.templateDigests = make(map[string][sha256.Size]byte)
+	}
+
+	changed := false
+	for _, file := range a.templateFiles() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			a.logger.Warn("failed to read template file", zap.String("path", file), zap.Error(err))
+			continue
+		}
+		digest := sha256.Sum256(data)
+		if prev, ok := a.templateDigests[file]; ok && prev == digest {
+			continue
+		}
+		a.templateDigests[file] = digest
+		if !first {
+			a.logger.Info("template file changed", zap.String("path", file))
+			changed = true
+		}
+	}
+	return changed
+}
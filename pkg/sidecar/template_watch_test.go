@@ -0,0 +1,88 @@
+package sidecar
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLoadTemplate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.tmpl")
+	require.NoError(t, os.WriteFile(file, []byte("from-file"), 0600))
+
+	got, err := loadTemplate("inline", "")
+	require.NoError(t, err)
+	assert.Equal(t, "inline", got)
+
+	got, err = loadTemplate("", file)
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", got)
+
+	_, err = loadTemplate("", filepath.Join(t.TempDir(), "missing.tmpl"))
+	assert.Error(t, err)
+}
+
+func TestTemplateFilesChanged(t *testing.T) {
+	dir := t.TempDir()
+	secretTmpl := filepath.Join(dir, "db.tmpl")
+	appTmpl := filepath.Join(dir, "app.tmpl")
+	require.NoError(t, os.WriteFile(secretTmpl, []byte("v1"), 0600))
+	require.NoError(t, os.WriteFile(appTmpl, []byte("v1"), 0600))
+
+	agent := &Agent{
+		config: &AgentConfig{
+			Secrets:   []SecretConfig{{Name: "db", TemplateFile: secretTmpl}, {Name: "api"}},
+			Templates: []TemplateConfig{{Name: "app", TemplateFile: appTmpl}},
+		},
+		logger: zap.NewNop(),
+	}
+
+	assert.Equal(t, []string{appTmpl, secretTmpl}, agent.templateFiles())
+	assert.False(t, agent.templateFilesChanged(), "first call records digests")
+	assert.False(t, agent.templateFilesChanged())
+
+	require.NoError(t, os.WriteFile(appTmpl, []byte("v2"), 0600))
+	assert.True(t, agent.templateFilesChanged())
+	assert.False(t, agent.templateFilesChanged())
+}
+
+func TestPublishTemplate_ReadsTemplateFile(t *testing.T) {
+	dir := t.TempDir()
+	tmplFile := filepath.Join(dir, "app.tmpl")
+	out := filepath.Join(dir, "out", "application.yaml")
+	require.NoError(t, os.WriteFile(tmplFile, []byte("password: {{ .db.password }}"), 0600))
+
+	agent := &Agent{
+		config:      &AgentConfig{},
+		logger:      zap.NewNop(),
+		secretCache: cache.NewSecretCache(0),
+	}
+	tmpl := TemplateConfig{
+		Name:         "application",
+		Path:         out,
+		Records:      map[string]string{"db": "postgres"},
+		TemplateFile: tmplFile,
+	}
+	records := templateRecords{byRef: map[string]*ksm.SecretData{"postgres": {Fields: map[string]interface{}{"password": "s3cret"}}}}
+
+	require.NoError(t, agent.publishTemplate(context.Background(), tmpl, records, nil))
+	require.NoError(t, agent.commitSnapshots())
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "password: s3cret", string(content))
+
+	// Edited template is picked up on the next render
+	require.NoError(t, os.WriteFile(tmplFile, []byte("pw={{ .db.password }}"), 0600))
+	require.NoError(t, agent.publishTemplate(context.Background(), tmpl, records, nil))
+	require.NoError(t, agent.commitSnapshots())
+	content, err = os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "pw=s3cret", string(content))
+}
@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// validateContainerTargets checks that the containers selected by name exist.
+// Excluded names are not checked, so they may name containers that other
+// webhooks add later (e.g. istio-proxy).
+func validateContainerTargets(pod *corev1.Pod, cfg *config.InjectionConfig) error {
+	for _, name := range cfg.Containers {
+		if !hasContainer(pod.Spec.Containers, name) {
+			return fmt.Errorf("%s: container %q not found in pod", config.AnnotationContainers, name)
+		}
+	}
+	for _, name := range cfg.InitContainers {
+		if !hasContainer(pod.Spec.InitContainers, name) {
+			return fmt.Errorf("%s: init container %q not found in pod", config.AnnotationInitContainers, name)
+		}
+	}
+	for _, s := range cfg.Secrets {
+		for _, name := range s.Containers {
+			if !hasContainer(pod.Spec.Containers, name) {
+				return fmt.Errorf("secret %s: container %q not found in pod", s.Name, name)
+			}
+		}
+	}
+
+	if len(cfg.ExcludeContainers) == 0 {
+		return nil
+	}
+	for _, c := range pod.Spec.Containers {
+		if cfg.TargetsContainer(c.Name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s excludes every container in the pod", config.AnnotationExcludeContainers)
+}
+
+// validateSecretIsolation rejects secrets limited to some containers whose
+// file lands in a volume that another container mounts. A container sees
+// every file in the volumes it mounts, so per-secret containers only hold
+// when the secret is written to a directory of its own.
+func validateSecretIsolation(pod *corev1.Pod, cfg *config.InjectionConfig, roots []outputRoot) error {
+	check := func(s config.SecretRef, mounts func(name string) bool) error {
+		if len(s.Containers) == 0 {
+			return nil
+		}
+		for _, c := range pod.Spec.Containers {
+			if mounts(c.Name) && !s.TargetsContainer(c.Name) {
+				return fmt.Errorf("secret %s is limited to containers %v, but container %s can read its file %s; set path to a directory outside %s that only those containers use",
+					s.Name, s.Containers, c.Name, s.Path, config.DefaultSecretsPath)
+			}
+		}
+		if len(cfg.InitContainers) > 0 {
+			return fmt.Errorf("secret %s is limited to containers %v, but init containers %v can read its file %s",
+				s.Name, s.Containers, cfg.InitContainers, s.Path)
+		}
+		return nil
+	}
+
+	for _, s := range cfg.Secrets {
+		if s.Path == "" || !isWithin(filepath.Clean(s.Path), config.DefaultSecretsPath) {
+			continue
+		}
+		if err := check(s, func(name string) bool { return mountsSecretsVolume(cfg, name) }); err != nil {
+			return err
+		}
+	}
+	for _, root := range roots {
+		for _, s := range root.secrets {
+			if err := check(s, func(name string) bool { return root.targetsContainer(cfg, name) }); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mountsSecretsVolume reports whether the app container gets the shared
+// secrets volume: it is targeted and at least one output reaches it. All
+// files share the volume, so validateSecretIsolation keeps secrets limited
+// to some containers out of it when another container mounts it.
+func mountsSecretsVolume(cfg *config.InjectionConfig, name string) bool {
+	if !cfg.TargetsContainer(name) {
+		return false
+	}
+	if len(cfg.Secrets) == 0 || len(cfg.Folders) > 0 || len(cfg.Templates) > 0 {
+		return true
+	}
+	for _, s := range cfg.Secrets {
+		if s.TargetsContainer(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// insertInitContainer adds keeper-secrets-init to the pod's init containers.
+// By default it runs last; with InitFirst it runs before the app's init
+// containers, but after native sidecars (restartPolicy: Always) declared
+// ahead of them, such as a service mesh proxy it may need for network access.
+func insertInitContainer(pod *corev1.Pod, cfg *config.InjectionConfig, initContainer corev1.Container) {
+	if !cfg.InitFirst {
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, initContainer)
+		return
+	}
+	i := 0
+	for i < len(pod.Spec.InitContainers) && isNativeSidecar(pod.Spec.InitContainers[i]) {
+		i++
+	}
+	pod.Spec.InitContainers = slices.Insert(pod.Spec.InitContainers, i, initContainer)
+}
+
+// isNativeSidecar reports whether an init container keeps running alongside the app
+func isNativeSidecar(c corev1.Container) bool {
+	return c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func hasVolumeMount(c *corev1.Container, name string) bool {
+	for _, m := range c.VolumeMounts {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMutatePod_ContainerTargeting(t *testing.T) {
+	pod := newTestPod(
+		corev1.Container{Name: "istio-proxy", Image: "proxyv2"},
+		corev1.Container{Name: "app", Image: "app"},
+		corev1.Container{Name: "log-shipper", Image: "fluent-bit"},
+	)
+	cfg := &config.InjectionConfig{
+		AuthSecretName:    "keeper-auth",
+		RefreshInterval:   "5m",
+		Signal:            "SIGHUP",
+		ExcludeContainers: []string{"istio-proxy", "log-shipper"},
+		Secrets:           []config.SecretRef{{Name: "db", Path: "/keeper/secrets/db.json", Format: "json"}},
+	}
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	assert.True(t, hasVolumeMount(findContainer(pod.Spec.Containers, "app"), "keeper-secrets"))
+	assert.False(t, hasVolumeMount(findContainer(pod.Spec.Containers, "istio-proxy"), "keeper-secrets"))
+	assert.False(t, hasVolumeMount(findContainer(pod.Spec.Containers, "log-shipper"), "keeper-secrets"))
+	assert.Equal(t, "app", cfg.SignalContainer, "the signal defaults to the first targeted container")
+}
+
+func TestMutatePod_PerSecretContainers(t *testing.T) {
+	pod := newTestPod(
+		corev1.Container{Name: "api", Image: "api", Command: []string{"/api"}},
+		corev1.Container{Name: "worker", Image: "worker", Command: []string{"/worker"}},
+	)
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		EnvSource:       config.EnvSourceExec,
+		Secrets: []config.SecretRef{
+			{Name: "db", Path: "/keeper/secrets/db.json", Format: "json", InjectAsEnvVars: true, Containers: []string{"api"}},
+		},
+	}
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	api := findContainer(pod.Spec.Containers, "api")
+	assert.Equal(t, ExecWrapperPath, api.Command[0])
+	assert.True(t, hasVolumeMount(api, "keeper-secrets"))
+
+	worker := findContainer(pod.Spec.Containers, "worker")
+	assert.Equal(t, []string{"/worker"}, worker.Command, "a container no secret targets is not wrapped")
+	assert.False(t, hasVolumeMount(worker, "keeper-secrets"))
+}
+
+func TestMutatePod_PerSecretContainersIsolation(t *testing.T) {
+	newPod := func() *corev1.Pod {
+		return newTestPod(
+			corev1.Container{Name: "api", Image: "api"},
+			corev1.Container{Name: "worker", Image: "worker"},
+		)
+	}
+	newConfig := func(dbPath, queuePath string) *config.InjectionConfig {
+		return &config.InjectionConfig{
+			AuthSecretName:  "keeper-auth",
+			RefreshInterval: "5m",
+			Secrets: []config.SecretRef{
+				{Name: "db", Path: dbPath, Format: "json", Containers: []string{"api"}},
+				{Name: "queue", Path: queuePath, Format: "json", Containers: []string{"worker"}},
+			},
+		}
+	}
+
+	// Both containers mount the shared volume, so each could read the other's file
+	err := newTestMutator().mutatePod(context.Background(), newPod(), newConfig("/keeper/secrets/db.json", "/keeper/secrets/queue.json"))
+	assert.ErrorContains(t, err, "secret db is limited to containers [api], but container worker can read its file")
+
+	// A directory per container keeps the files apart
+	pod := newPod()
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, newConfig("/keeper/api/db.json", "/keeper/worker/queue.json")))
+	api := findContainer(pod.Spec.Containers, "api")
+	worker := findContainer(pod.Spec.Containers, "worker")
+	assert.Contains(t, api.VolumeMounts, corev1.VolumeMount{Name: "keeper-output-0", MountPath: "/keeper/api", ReadOnly: true})
+	assert.False(t, hasVolumeMount(worker, "keeper-output-0"))
+	assert.False(t, hasVolumeMount(api, "keeper-output-1"))
+
+	// A folder in the same directory reaches every container
+	cfg := newConfig("/keeper/api/db.json", "/keeper/worker/queue.json")
+	cfg.Folders = []config.FolderRef{{FolderUID: "f1", OutputPath: "/keeper/api/folder"}}
+	err = newTestMutator().mutatePod(context.Background(), newPod(), cfg)
+	assert.ErrorContains(t, err, "container worker can read its file /keeper/api/db.json")
+}
+
+func TestMutatePod_ContainerTargetingErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.InjectionConfig
+		wantErr string
+	}{
+		{
+			name:    "unknown allowlisted container",
+			cfg:     &config.InjectionConfig{Containers: []string{"ap"}},
+			wantErr: `container "ap" not found`,
+		},
+		{
+			name:    "unknown secret container",
+			cfg:     &config.InjectionConfig{Secrets: []config.SecretRef{{Name: "db", Containers: []string{"web"}}}},
+			wantErr: `secret db: container "web" not found`,
+		},
+		{
+			name:    "everything excluded",
+			cfg:     &config.InjectionConfig{ExcludeContainers: []string{"app"}},
+			wantErr: "excludes every container",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.AuthSecretName = "keeper-auth"
+			tt.cfg.RefreshInterval = "5m"
+			pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+
+			err := newTestMutator().mutatePod(context.Background(), pod, tt.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func initContainerNames(pod *corev1.Pod) []string {
+	names := make([]string, 0, len(pod.Spec.InitContainers))
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestMutatePod_InitContainerOrder(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	newPod := func() *corev1.Pod {
+		pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+		pod.Spec.InitContainers = []corev1.Container{
+			{Name: "istio-proxy", Image: "proxyv2", RestartPolicy: &always},
+			{Name: "migrate", Image: "migrate"},
+			{Name: "seed", Image: "seed"},
+		}
+		return pod
+	}
+
+	pod := newPod()
+	cfg := &config.InjectionConfig{AuthSecretName: "keeper-auth", RefreshInterval: "5m"}
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+	assert.Equal(t, []string{"istio-proxy", "migrate", "seed", "keeper-secrets-init"}, initContainerNames(pod))
+
+	pod = newPod()
+	cfg = &config.InjectionConfig{AuthSecretName: "keeper-auth", RefreshInterval: "5m", InitFirst: true}
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+	assert.Equal(t, []string{"istio-proxy", "keeper-secrets-init", "migrate", "seed"}, initContainerNames(pod),
+		"runs before the app's init containers but after native sidecars")
+}
+
+func TestMutatePod_InitContainersMountSecrets(t *testing.T) {
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+	pod.Spec.InitContainers = []corev1.Container{
+		{Name: "migrate", Image: "migrate"},
+		{Name: "wait-for-db", Image: "busybox"},
+	}
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		InitContainers:  []string{"migrate"},
+		InitFirst:       true,
+	}
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	assert.Equal(t, "keeper-secrets-init", pod.Spec.InitContainers[0].Name)
+	migrate := findContainer(pod.Spec.InitContainers, "migrate")
+	require.True(t, hasVolumeMount(migrate, "keeper-secrets"))
+	for _, m := range migrate.VolumeMounts {
+		if m.Name == "keeper-secrets" {
+			assert.Equal(t, config.DefaultSecretsPath, m.MountPath)
+			assert.True(t, m.ReadOnly)
+		}
+	}
+	assert.False(t, hasVolumeMount(findContainer(pod.Spec.InitContainers, "wait-for-db"), "keeper-secrets"))
+}
+
+func TestMutatePod_UnknownInitContainer(t *testing.T) {
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		InitContainers:  []string{"migrate"},
+		InitFirst:       true,
+	}
+
+	err := newTestMutator().mutatePod(context.Background(), pod, cfg)
+	assert.ErrorContains(t, err, `init container "migrate" not found`)
+}
@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// envSecretPrefix starts the names of the Secrets holding env var values
+	envSecretPrefix = "keeper-env-"
+
+	// EnvSecretLabel marks the Secrets holding env var values
+	EnvSecretLabel = "keeper.security/env-secret"
+
+	// envSecretCollectInterval is how often Secrets of bare pods are adopted or removed
+	envSecretCollectInterval = time.Minute
+
+	// envSecretGracePeriod is how long an unreferenced Secret is kept, covering
+	// the time between admission and the pod being stored
+	envSecretGracePeriod = 5 * time.Minute
+)
+
+// storeEnvVarsInSecret writes the env var values into the pod's managed Secret
+// and returns env vars that reference it with secretKeyRef
+func (m *PodMutator) storeEnvVarsInSecret(ctx context.Context, pod *corev1.Pod, envVars []corev1.EnvVar) ([]corev1.EnvVar, error) {
+	name := envSecretName(pod)
+	data := make(map[string][]byte, len(envVars))
+	refs := make([]corev1.EnvVar, 0, len(envVars))
+	for _, envVar := range envVars {
+		data[envVar.Name] = []byte(envVar.Value)
+		refs = append(refs, corev1.EnvVar{
+			Name: envVar.Name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+					Key:                  envVar.Name,
+				},
+			},
+		})
+	}
+
+	if err := m.applyEnvSecret(ctx, pod, name, data); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+// envSecretName names the managed Secret of a pod. Pods of the same controller
+// (e.g. a ReplicaSet) share it; a bare pod gets its own. The name of a bare
+// pod may only be generated after admission, so its Secret name is random.
+func envSecretName(pod *corev1.Pod) string {
+	h := sha256.New()
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		fmt.Fprintf(h, "%s/%s/%s/%s", pod.Namespace, owner.Kind, owner.Name, owner.UID)
+	} else {
+		fmt.Fprintf(h, "%s/Pod/%s/%s", pod.Namespace, pod.Name, utilrand.String(16))
+	}
+	return envSecretPrefix + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// applyEnvSecret creates or updates the managed Secret. It is owned by the
+// pod's controller, so it is deleted with it; pods carry no UID during
+// admission, so a bare pod's Secret starts without an owner and the
+// EnvSecretCollector adopts it. Secrets not created by the injector are
+// never changed.
+func (m *PodMutator) applyEnvSecret(ctx context.Context, pod *corev1.Pod, name string, data map[string][]byte) error {
+	secret := &corev1.Secret{Type: corev1.SecretTypeOpaque, Data: data}
+	if err := validateSecretSize(secret); err != nil {
+		return fmt.Errorf("env var Secret %s: %w", name, err)
+	}
+
+	var op controllerutil.OperationResult
+	retryable := func(err error) bool { return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) }
+	err := retry.OnError(retry.DefaultRetry, retryable, func() error {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: pod.Namespace}}
+		var err error
+		op, err = controllerutil.CreateOrUpdate(ctx, m.Client, secret, func() error {
+			if secret.ResourceVersion != "" && secret.Labels[EnvSecretLabel] != "true" {
+				return fmt.Errorf("secret %s exists and is not managed by the injector", name)
+			}
+			secret.Labels = map[string]string{
+				"app.kubernetes.io/managed-by": "keeper-injector",
+				EnvSecretLabel:                 "true",
+			}
+			if owner := metav1.GetControllerOf(pod); owner != nil {
+				secret.OwnerReferences = []metav1.OwnerReference{{
+					APIVersion: owner.APIVersion,
+					Kind:       owner.Kind,
+					Name:       owner.Name,
+					UID:        owner.UID,
+				}}
+			}
+			secret.Type = corev1.SecretTypeOpaque
+			secret.Data = data
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write env var Secret %s: %w", name, err)
+	}
+
+	m.logger.Debug("stored env vars in Secret",
+		zap.String("name", name),
+		zap.String("namespace", pod.Namespace),
+		zap.String("operation", string(op)),
+		zap.Int("varCount", len(data)))
+	return nil
+}
+
+// usesEnvSecret reports whether env var values go into a managed Secret
+func usesEnvSecret(cfg *config.InjectionConfig) bool {
+	return cfg.EnvSource != config.EnvSourceLiteral
+}
+
+// EnvSecretCollector gives the managed Secrets of bare pods an owner. A
+// Secret without one is owned by the pods that reference it, so Kubernetes
+// deletes it with them; a Secret no pod references after the grace period
+// (e.g. because the pod was rejected after admission) is deleted.
+type EnvSecretCollector struct {
+	Client   client.Client
+	Interval time.Duration
+	logger   *zap.Logger
+
+	// now returns the current time (replaced in tests)
+	now func() time.Time
+}
+
+// NewEnvSecretCollector creates a collector that runs every minute
+func NewEnvSecretCollector(c client.Client, logger *zap.Logger) *EnvSecretCollector {
+	return &EnvSecretCollector{
+		Client:   c,
+		Interval: envSecretCollectInterval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// NeedLeaderElection runs the collector on the leader replica only
+func (c *EnvSecretCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Start collects Secrets every Interval until ctx is cancelled
+func (c *EnvSecretCollector) Start(ctx context.Context) error {
+	c.logger.Info("starting env var Secret collector", zap.Duration("interval", c.Interval))
+
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		c.collect(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect adopts or deletes the managed Secrets that have no owner
+func (c *EnvSecretCollector) collect(ctx context.Context) {
+	secrets := &corev1.SecretList{}
+	if err := c.Client.List(ctx, secrets, client.MatchingLabels{EnvSecretLabel: "true"}); err != nil {
+		c.logger.Error("failed to list env var Secrets", zap.Error(err))
+		return
+	}
+
+	pods := make(map[string][]corev1.Pod)
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if len(secret.OwnerReferences) > 0 {
+			continue
+		}
+		if _, ok := pods[secret.Namespace]; !ok {
+			list := &corev1.PodList{}
+			if err := c.Client.List(ctx, list, client.InNamespace(secret.Namespace)); err != nil {
+				c.logger.Error("failed to list pods", zap.String("namespace", secret.Namespace), zap.Error(err))
+				continue
+			}
+			pods[secret.Namespace] = list.Items
+		}
+
+		var owners []metav1.OwnerReference
+		for _, pod := range pods[secret.Namespace] {
+			if referencesSecret(&pod, secret.Name) {
+				owners = append(owners, metav1.OwnerReference{
+					APIVersion: "v1",
+					Kind:       "Pod",
+					Name:       pod.Name,
+					UID:        pod.UID,
+				})
+			}
+		}
+
+		switch {
+		case len(owners) > 0:
+			secret.OwnerReferences = owners
+			if err := c.Client.Update(ctx, secret); err != nil {
+				c.logger.Error("failed to adopt env var Secret", zap.String("name", secret.Name), zap.Error(err))
+			}
+		case c.now().Sub(secret.CreationTimestamp.Time) > envSecretGracePeriod:
+			if err := c.Client.Delete(ctx, secret, client.Preconditions{UID: &secret.UID}); client.IgnoreNotFound(err) != nil {
+				c.logger.Error("failed to delete unused env var Secret", zap.String("name", secret.Name), zap.Error(err))
+				continue
+			}
+			c.logger.Info("deleted unused env var Secret",
+				zap.String("name", secret.Name),
+				zap.String("namespace", secret.Namespace))
+		}
+	}
+}
+
+// referencesSecret reports whether a container of the pod reads an env var from the Secret
+func referencesSecret(pod *corev1.Pod, name string) bool {
+	containers := append(append([]corev1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
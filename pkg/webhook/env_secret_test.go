@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func replicaSetPod(rsName string, rsUID types.UID) *corev1.Pod {
+	controller := true
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		GenerateName: rsName + "-",
+		Namespace:    "apps",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "apps/v1",
+			Kind:       "ReplicaSet",
+			Name:       rsName,
+			UID:        rsUID,
+			Controller: &controller,
+		}},
+	}}
+}
+
+func TestStoreEnvVarsInSecret(t *testing.T) {
+	c := newProfileClient(t)
+	m := &PodMutator{Client: c, logger: zap.NewNop()}
+	pod := replicaSetPod("web-5d8f", "rs-uid")
+	envVars := []corev1.EnvVar{
+		{Name: "DB_LOGIN", Value: "admin"},
+		{Name: "DB_PASSWORD", Value: "s3cret"},
+	}
+
+	refs, err := m.storeEnvVarsInSecret(context.Background(), pod, envVars)
+	require.NoError(t, err)
+	require.Len(t, refs, 2)
+	name := envSecretName(pod)
+	for i, ref := range refs {
+		assert.Equal(t, envVars[i].Name, ref.Name)
+		assert.Empty(t, ref.Value, "no plaintext in the pod spec")
+		require.NotNil(t, ref.ValueFrom)
+		require.NotNil(t, ref.ValueFrom.SecretKeyRef)
+		assert.Equal(t, name, ref.ValueFrom.SecretKeyRef.Name)
+		assert.Equal(t, envVars[i].Name, ref.ValueFrom.SecretKeyRef.Key)
+	}
+
+	secret := &corev1.Secret{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: name}, secret))
+	assert.Equal(t, "s3cret", string(secret.Data["DB_PASSWORD"]))
+	assert.Equal(t, "true", secret.Labels[EnvSecretLabel])
+	require.Len(t, secret.OwnerReferences, 1)
+	assert.Equal(t, "ReplicaSet", secret.OwnerReferences[0].Kind)
+	assert.Equal(t, types.UID("rs-uid"), secret.OwnerReferences[0].UID)
+
+	// The next pod of the ReplicaSet reuses the Secret with current values
+	_, err = m.storeEnvVarsInSecret(context.Background(), replicaSetPod("web-5d8f", "rs-uid"),
+		[]corev1.EnvVar{{Name: "DB_PASSWORD", Value: "rotated"}})
+	require.NoError(t, err)
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: name}, secret))
+	assert.Equal(t, map[string][]byte{"DB_PASSWORD": []byte("rotated")}, secret.Data)
+}
+
+func TestStoreEnvVarsInSecret_UnmanagedSecret(t *testing.T) {
+	pod := replicaSetPod("web-5d8f", "rs-uid")
+	existing := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: envSecretName(pod), Namespace: "apps"},
+		Data:       map[string][]byte{"other": []byte("kept")},
+	}
+	c := newProfileClient(t, existing)
+	m := &PodMutator{Client: c, logger: zap.NewNop()}
+
+	_, err := m.storeEnvVarsInSecret(context.Background(), pod, []corev1.EnvVar{{Name: "A", Value: "b"}})
+	assert.ErrorContains(t, err, "not managed by the injector")
+
+	secret := &corev1.Secret{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(existing), secret))
+	assert.Equal(t, "kept", string(secret.Data["other"]))
+}
+
+func TestEnvSecretName(t *testing.T) {
+	a := envSecretName(replicaSetPod("web-5d8f", "uid-1"))
+	assert.Regexp(t, `^keeper-env-[0-9a-f]{16}$`, a)
+	assert.Equal(t, a, envSecretName(replicaSetPod("web-5d8f", "uid-1")))
+	assert.NotEqual(t, a, envSecretName(replicaSetPod("web-5d8f", "uid-2")), "a recreated owner gets a new Secret")
+
+	bare := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "debug-", Namespace: "apps"}}
+	assert.NotEqual(t, a, envSecretName(bare))
+	assert.NotEqual(t, envSecretName(bare), envSecretName(bare), "bare pods sharing a generateName get separate Secrets")
+}
+
+func TestStoreEnvVarsInSecret_DryRun(t *testing.T) {
+	c := newProfileClient(t)
+	m := &PodMutator{Client: c, logger: zap.NewNop()}
+
+	refs, err := m.dryRun().storeEnvVarsInSecret(context.Background(), replicaSetPod("web-5d8f", "rs-uid"),
+		[]corev1.EnvVar{{Name: "DB_PASSWORD", Value: "s3cret"}})
+	require.NoError(t, err)
+	require.Len(t, refs, 1)
+
+	secrets := &corev1.SecretList{}
+	require.NoError(t, c.List(context.Background(), secrets))
+	assert.Empty(t, secrets.Items, "a dry run must not create the Secret")
+}
+
+func TestEnvSecretCollector(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	envSecret := func(name string, age time.Duration) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "apps",
+			Labels:            map[string]string{EnvSecretLabel: "true"},
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+		}}
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "debug-x7k2p", Namespace: "apps", UID: "pod-uid"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name: "app",
+			Env: []corev1.EnvVar{{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "keeper-env-used"},
+					Key:                  "DB_PASSWORD",
+				},
+			}}},
+		}}},
+	}
+	owned := envSecret("keeper-env-owned", time.Hour)
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web", UID: "rs-uid"}}
+	c := newProfileClient(t, pod, owned,
+		envSecret("keeper-env-used", time.Hour),
+		envSecret("keeper-env-new", time.Minute),
+		envSecret("keeper-env-orphan", time.Hour))
+
+	collector := NewEnvSecretCollector(c, zap.NewNop())
+	collector.now = func() time.Time { return now }
+	collector.collect(context.Background())
+
+	secret := &corev1.Secret{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "keeper-env-used"}, secret))
+	require.Len(t, secret.OwnerReferences, 1, "a referenced Secret is adopted by its pod")
+	assert.Equal(t, "Pod", secret.OwnerReferences[0].Kind)
+	assert.Equal(t, types.UID("pod-uid"), secret.OwnerReferences[0].UID)
+
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "keeper-env-owned"}, secret))
+	assert.Equal(t, types.UID("rs-uid"), secret.OwnerReferences[0].UID)
+
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "keeper-env-new"}, secret),
+		"an unreferenced Secret is kept during the grace period")
+	assert.Empty(t, secret.OwnerReferences)
+
+	err := c.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: "keeper-env-orphan"}, secret)
+	assert.True(t, apierrors.IsNotFound(err), "an unreferenced Secret is deleted after the grace period")
+}
@@ -23,6 +23,11 @@ func (m *PodMutator) injectEnvironmentVariables(ctx context.Context, pod *corev1
 		return nil
 	}
 
+	// The exec wrapper loads the values inside the app containers at start
+	if cfg.EnvSource == config.EnvSourceExec {
+		return m.injectExecWrapper(pod, cfg, envSecrets)
+	}
+
 	m.logger.Info("injecting environment variables",
 		zap.Int("secretCount", len(envSecrets)),
 		zap.String("pod", pod.Name))
@@ -42,9 +47,11 @@ func (m *PodMutator) injectEnvironmentVariables(ctx context.Context, pod *corev1
 		}
 	}()
 
-	// Fetch and convert each secret to env vars
+	// Fetch and convert each secret to env vars, remembering which secret each came from
+	var envVars []corev1.EnvVar
+	var sources []config.SecretRef
 	for _, secret := range envSecrets {
-		envVars, err := m.buildEnvVarsFromSecret(ctx, ksmClient, secret, cfg)
+		secretEnvVars, err := m.buildEnvVarsFromSecret(ctx, ksmClient, secret, cfg)
 		if err != nil {
 			if cfg.FailOnError {
 				return fmt.Errorf("failed to build env vars for secret %s: %w", secret.Name, err)
@@ -54,15 +61,42 @@ func (m *PodMutator) injectEnvironmentVariables(ctx context.Context, pod *corev1
 				zap.Error(err))
 			continue
 		}
-
-		// Inject into all containers
-		for i := range pod.Spec.Containers {
-			pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, envVars...)
+		envVars = append(envVars, secretEnvVars...)
+		for range secretEnvVars {
+			sources = append(sources, secret)
 		}
 
-		m.logger.Debug("injected env vars from secret",
+		m.logger.Debug("built env vars from secret",
 			zap.String("secret", secret.Name),
-			zap.Int("varCount", len(envVars)))
+			zap.Int("varCount", len(secretEnvVars)))
+	}
+	if len(envVars) == 0 {
+		return nil
+	}
+
+	// Keep the values out of the pod spec unless literal values were requested
+	if usesEnvSecret(cfg) {
+		envVars, err = m.storeEnvVarsInSecret(ctx, pod, envVars)
+		if err != nil {
+			if cfg.FailOnError {
+				return fmt.Errorf("failed to store env vars: %w", err)
+			}
+			m.logger.Warn("failed to store env vars in a Secret, skipping env var injection", zap.Error(err))
+			return nil
+		}
+	}
+
+	// Inject into the containers each secret targets
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if !cfg.TargetsContainer(c.Name) {
+			continue
+		}
+		for j, envVar := range envVars {
+			if sources[j].TargetsContainer(c.Name) {
+				c.Env = append(c.Env, envVar)
+			}
+		}
 	}
 
 	return nil
@@ -86,13 +120,18 @@ func filterEnvVarSecrets(cfg *config.InjectionConfig) []config.SecretRef {
 
 // createKSMClient creates a KSM client using credentials from K8s secret
 func (m *PodMutator) createKSMClient(ctx context.Context, namespace string, cfg *config.InjectionConfig) (*ksm.Client, error) {
+	return newKSMClient(ctx, m.Client, m.logger, namespace, cfg)
+}
+
+// newKSMClient creates a KSM client from the auth secret in namespace
+func newKSMClient(ctx context.Context, c client.Client, logger *zap.Logger, namespace string, cfg *config.InjectionConfig) (*ksm.Client, error) {
 	// Fetch auth secret from K8s
 	authSecret := &corev1.Secret{}
 	secretKey := client.ObjectKey{
 		Name:      cfg.AuthSecretName,
 		Namespace: namespace,
 	}
-	if err := m.Client.Get(ctx, secretKey, authSecret); err != nil {
+	if err := c.Get(ctx, secretKey, authSecret); err != nil {
 		return nil, fmt.Errorf("failed to fetch auth secret %s: %w", cfg.AuthSecretName, err)
 	}
 
@@ -107,7 +146,7 @@ func (m *PodMutator) createKSMClient(ctx context.Context, namespace string, cfg
 		ConfigJSON:  string(configData),
 		AuthMethod:  ksm.AuthMethod(cfg.AuthMethod),
 		StrictMatch: cfg.StrictLookup,
-		Logger:      m.logger,
+		Logger:      logger,
 	}
 
 	client, err := ksm.NewClient(ctx, ksmConfig)
@@ -187,6 +226,14 @@ func (m *PodMutator) buildEnvVarsFromSecret(ctx context.Context, ksmClient *ksm.
 	return convertFieldsToEnvVars(secretData.Fields, secret.EnvVarPrefix, cfg.EnvPrefix), nil
 }
 
+// envVarPrefix returns the env var name prefix of a secret
+func envVarPrefix(secret config.SecretRef, cfg *config.InjectionConfig) string {
+	if secret.EnvVarPrefix != "" {
+		return secret.EnvVarPrefix
+	}
+	return cfg.EnvPrefix
+}
+
 // convertFieldsToEnvVars converts secret fields map to []EnvVar
 func convertFieldsToEnvVars(fields map[string]interface{}, secretPrefix, globalPrefix string) []corev1.EnvVar {
 	var envVars []corev1.EnvVar
@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// execBinVolumeName is the shared volume the init container copies the agent into
+	execBinVolumeName = "keeper-bin"
+
+	// execBinPath is where the shared volume is mounted
+	execBinPath = "/keeper/bin"
+
+	// ExecWrapperPath is the agent binary that wraps app containers in exec mode
+	ExecWrapperPath = execBinPath + "/keeper-agent"
+
+	// execAuthVolumeName is the memory-backed volume carrying the KSM
+	// credentials from the init container to the wrappers
+	execAuthVolumeName = "keeper-exec-auth"
+
+	// execAuthPath is where the credentials volume is mounted; each wrapped
+	// container mounts only its own subdirectory
+	execAuthPath = "/keeper/exec-auth"
+
+	// ExecCredentialsFile is the file a wrapper reads its KSM credentials
+	// from each time the container starts
+	ExecCredentialsFile = execAuthPath + "/ksm-config"
+)
+
+// injectExecWrapper makes the agent the entrypoint of the app containers that
+// receive env var secrets. The init container copies the agent binary into a
+// shared volume; in each wrapped container it fetches that container's
+// secrets, builds the environment and execs the original command, so the
+// values never reach the API server.
+//
+// The KSM credentials never appear in an app container's spec: the init
+// container writes them to the container's directory on a memory-backed
+// volume, mounted read-only so the wrapper can read them again whenever the
+// container restarts.
+//
+// The webhook cannot read image metadata, so a container without a command
+// in the pod spec cannot be wrapped: admission fails with FailOnError, and
+// otherwise the container gets no env var secrets.
+func (m *PodMutator) injectExecWrapper(pod *corev1.Pod, cfg *config.InjectionConfig, envSecrets []config.SecretRef) error {
+	wrapped := make(map[string][]config.SecretRef)
+	for _, c := range pod.Spec.Containers {
+		if c.Name == "keeper-secrets-sidecar" || !cfg.TargetsContainer(c.Name) {
+			continue
+		}
+		var secrets []config.SecretRef
+		for _, s := range envSecrets {
+			if s.TargetsContainer(c.Name) {
+				secrets = append(secrets, s)
+			}
+		}
+		if len(secrets) == 0 {
+			continue
+		}
+		if len(c.Command) == 0 {
+			if cfg.FailOnError {
+				return fmt.Errorf("container %s has no command: %s=%s wraps the command, so it must be set in the pod spec",
+					c.Name, config.AnnotationEnvSource, config.EnvSourceExec)
+			}
+			m.logger.Warn("container has no command in the pod spec, not wrapping it",
+				zap.String("container", c.Name),
+				zap.String("envSource", config.EnvSourceExec))
+			continue
+		}
+		wrapped[c.Name] = secrets
+	}
+	if len(wrapped) == 0 {
+		return nil
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes,
+		corev1.Volume{
+			Name: execBinVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		corev1.Volume{
+			Name: execAuthVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory},
+			},
+		},
+	)
+
+	var credentials []string
+	for _, c := range pod.Spec.Containers {
+		if _, ok := wrapped[c.Name]; ok {
+			credentials = append(credentials, path.Join(execAuthPath, c.Name, path.Base(ExecCredentialsFile)))
+		}
+	}
+	for i := range pod.Spec.InitContainers {
+		c := &pod.Spec.InitContainers[i]
+		if c.Name != "keeper-secrets-init" {
+			continue
+		}
+		c.Args = append(c.Args,
+			"--install-exec-wrapper="+ExecWrapperPath,
+			"--exec-credentials="+strings.Join(credentials, ","))
+		c.VolumeMounts = append(c.VolumeMounts,
+			corev1.VolumeMount{
+				Name:      execBinVolumeName,
+				MountPath: execBinPath,
+			},
+			corev1.VolumeMount{
+				Name:      execAuthVolumeName,
+				MountPath: execAuthPath,
+			},
+		)
+	}
+
+	mounts := []corev1.VolumeMount{
+		{
+			Name:      execBinVolumeName,
+			MountPath: execBinPath,
+			ReadOnly:  true,
+		},
+	}
+	if cfg.CACertSecret != "" || cfg.CACertConfigMap != "" {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "keeper-ca-cert",
+			MountPath: "/usr/local/share/ca-certificates/keeper-ca.crt",
+			SubPath:   "ca.crt",
+			ReadOnly:  true,
+		})
+	}
+
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		secrets, ok := wrapped[c.Name]
+		if !ok {
+			continue
+		}
+		configJSON, err := json.Marshal(m.buildExecConfig(cfg, secrets))
+		if err != nil {
+			return fmt.Errorf("failed to marshal exec config: %w", err)
+		}
+
+		args := append(append([]string{}, c.Command...), c.Args...)
+		c.Command = []string{
+			ExecWrapperPath,
+			"--mode=exec",
+			fmt.Sprintf("--refresh-interval=%s", cfg.RefreshInterval),
+			"--exec-credentials=" + ExecCredentialsFile,
+			"--",
+		}
+		c.Args = args
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "KEEPER_CONFIG",
+			Value: string(configJSON),
+		})
+		c.VolumeMounts = append(c.VolumeMounts, mounts...)
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      execAuthVolumeName,
+			MountPath: execAuthPath,
+			SubPath:   c.Name,
+			ReadOnly:  true,
+		})
+
+		m.logger.Debug("wrapped container command",
+			zap.String("container", c.Name),
+			zap.Int("secretCount", len(secrets)))
+	}
+
+	return nil
+}
+
+// buildExecConfig creates the configuration passed to the exec wrapper. It
+// only carries the env var secrets; files are still written by the init
+// container and sidecar.
+func (m *PodMutator) buildExecConfig(cfg *config.InjectionConfig, envSecrets []config.SecretRef) map[string]interface{} {
+	execCfg := &config.InjectionConfig{
+		Secrets:         envSecrets,
+		InjectEnvVars:   cfg.InjectEnvVars,
+		EnvPrefix:       cfg.EnvPrefix,
+		FailOnError:     cfg.FailOnError,
+		StrictLookup:    cfg.StrictLookup,
+		AuthMethod:      cfg.AuthMethod,
+		AWSSecretID:     cfg.AWSSecretID,
+		AWSRegion:       cfg.AWSRegion,
+		GCPSecretID:     cfg.GCPSecretID,
+		AzureVaultName:  cfg.AzureVaultName,
+		AzureSecretName: cfg.AzureSecretName,
+	}
+	result := m.buildSidecarConfig(execCfg)
+	if cfg.ExecRestart {
+		result["execRestart"] = true
+	}
+	return result
+}
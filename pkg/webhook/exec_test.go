@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func execTestConfig() *config.InjectionConfig {
+	return &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		EnvSource:       config.EnvSourceExec,
+		ExecRestart:     true,
+		EnvPrefix:       "APP_",
+		Secrets: []config.SecretRef{
+			{Name: "db", Path: "/keeper/secrets/db.json", Format: "json", InjectAsEnvVars: true, EnvVarPrefix: "DB_"},
+			{Name: "tls", Path: "/keeper/secrets/tls.json", Format: "json"},
+		},
+	}
+}
+
+func TestMutatePod_ExecWrapper(t *testing.T) {
+	pod := newTestPod(corev1.Container{
+		Name:    "app",
+		Image:   "app",
+		Command: []string{"/app/server"},
+		Args:    []string{"--port=8080"},
+	})
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, execTestConfig()))
+
+	app := findContainer(pod.Spec.Containers, "app")
+	require.NotNil(t, app)
+	assert.Equal(t, []string{ExecWrapperPath, "--mode=exec", "--refresh-interval=5m", "--exec-credentials=" + ExecCredentialsFile, "--"}, app.Command)
+	assert.Equal(t, []string{"/app/server", "--port=8080"}, app.Args)
+	for _, env := range app.Env {
+		assert.NotEqual(t, "DB_PASSWORD", env.Name, "values are loaded by the wrapper, not set in the pod spec")
+		assert.NotEqual(t, "KEEPER_AUTH_CONFIG", env.Name, "credentials reach the wrapper through a file")
+	}
+	assert.Contains(t, app.VolumeMounts, corev1.VolumeMount{Name: execAuthVolumeName, MountPath: execAuthPath, SubPath: "app", ReadOnly: true})
+
+	execCfg := sidecarConfigFrom(t, app)
+	assert.Equal(t, true, execCfg["execRestart"])
+	secrets := execCfg["secrets"].([]interface{})
+	require.Len(t, secrets, 1, "only env var secrets are loaded by the wrapper")
+	assert.Equal(t, "db", secrets[0].(map[string]interface{})["name"])
+	assert.Equal(t, "DB_", secrets[0].(map[string]interface{})["envPrefix"])
+
+	init := findContainer(pod.Spec.InitContainers, "keeper-secrets-init")
+	require.NotNil(t, init)
+	assert.Contains(t, init.Args, "--install-exec-wrapper="+ExecWrapperPath)
+	assert.Contains(t, init.Args, "--exec-credentials=/keeper/exec-auth/app/ksm-config")
+	assert.Contains(t, init.VolumeMounts, corev1.VolumeMount{Name: execAuthVolumeName, MountPath: execAuthPath})
+
+	sidecar := findContainer(pod.Spec.Containers, "keeper-secrets-sidecar")
+	require.NotNil(t, sidecar)
+	assert.Empty(t, sidecar.Command, "the sidecar is not wrapped")
+}
+
+func TestMutatePod_ExecWrapperSkipsContainerWithoutCommand(t *testing.T) {
+	pod := newTestPod(
+		corev1.Container{Name: "app", Image: "app", Command: []string{"/app/server"}},
+		corev1.Container{Name: "worker", Image: "worker", Args: []string{"serve"}},
+	)
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, execTestConfig()))
+
+	worker := findContainer(pod.Spec.Containers, "worker")
+	require.NotNil(t, worker)
+	assert.Empty(t, worker.Command, "the image entrypoint is unknown, so the container is not wrapped")
+	assert.Equal(t, []string{"serve"}, worker.Args)
+
+	init := findContainer(pod.Spec.InitContainers, "keeper-secrets-init")
+	require.NotNil(t, init)
+	assert.Contains(t, init.Args, "--exec-credentials=/keeper/exec-auth/app/ksm-config")
+}
+
+func TestMutatePod_ExecWrapperRequiresCommandWithFailOnError(t *testing.T) {
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app", Args: []string{"serve"}})
+	cfg := execTestConfig()
+	cfg.FailOnError = true
+
+	err := newTestMutator().mutatePod(context.Background(), pod, cfg)
+	assert.ErrorContains(t, err, "container app has no command")
+}
@@ -6,10 +6,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"slices"
+	"sort"
 	"time"
 
 	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
 	"github.com/keeper-security/keeper-k8s-injector/pkg/metrics"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -18,6 +22,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+// cacheVolumeName is the volume (or subPath of an existing volume) holding the persistent cache
+const cacheVolumeName = "keeper-cache"
+
 // PodMutator handles pod mutation for secret injection
 type PodMutator struct {
 	Client  client.Client
@@ -46,6 +53,11 @@ type WebhookConfig struct {
 	CPULimit string
 	// MemoryLimit for sidecar container
 	MemoryLimit string
+	// AllowUnsafeTemplateFuncs enables env, expandenv and getHostByName in secret templates
+	AllowUnsafeTemplateFuncs bool
+	// K8sSecretRotationRBAC lets the webhook create the Roles that allow
+	// sidecars to update their K8s Secrets
+	K8sSecretRotationRBAC bool
 }
 
 // DefaultWebhookConfig returns sensible defaults
@@ -109,16 +121,34 @@ func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 	}
 
 	// Parse injection configuration
-	injectionConfig, err := config.ParseAnnotations(pod)
+	injectionConfig, err := parseInjectionConfig(ctx, m.Client, pod, req.Namespace)
 	if err != nil {
 		m.logger.Error("failed to parse annotations", zap.Error(err))
 		metrics.RecordMutation(req.Namespace, false, time.Since(startTime).Seconds(), 0)
 		return admission.Errored(http.StatusBadRequest, fmt.Errorf("invalid injection configuration: %w", err))
 	}
 
+	// Reject templates the agent would fail to parse at runtime
+	if err := validateTemplates(injectionConfig, m.config.AllowUnsafeTemplateFuncs); err != nil {
+		m.logger.Error("invalid secret template", zap.Error(err))
+		metrics.RecordMutation(req.Namespace, false, time.Since(startTime).Seconds(), 0)
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("invalid injection configuration: %w", err))
+	}
+
+	// Pods created by controllers carry no namespace until after admission
+	if pod.Namespace == "" {
+		pod.Namespace = req.Namespace
+	}
+
+	// Dry-run requests must not leave Secrets or Roles behind
+	mutator := m
+	if req.DryRun != nil && *req.DryRun {
+		mutator = m.dryRun()
+	}
+
 	// Mutate the pod
 	mutatedPod := pod.DeepCopy()
-	if err := m.mutatePod(ctx, mutatedPod, injectionConfig); err != nil {
+	if err := mutator.mutatePod(ctx, mutatedPod, injectionConfig); err != nil {
 		m.logger.Error("failed to mutate pod", zap.Error(err))
 		metrics.RecordMutation(req.Namespace, false, time.Since(startTime).Seconds(), 0)
 		return admission.Errored(http.StatusInternalServerError, err)
@@ -143,8 +173,30 @@ func (m *PodMutator) Handle(ctx context.Context, req admission.Request) admissio
 	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
 }
 
+// dryRun returns a copy of the mutator whose writes are sent as dry runs,
+// so the API server validates them without persisting anything
+func (m *PodMutator) dryRun() *PodMutator {
+	dry := *m
+	dry.Client = client.NewDryRunClient(m.Client)
+	return &dry
+}
+
 // mutatePod adds the init container and/or sidecar to the pod
 func (m *PodMutator) mutatePod(ctx context.Context, pod *corev1.Pod, cfg *config.InjectionConfig) error {
+	if err := validateContainerTargets(pod, cfg); err != nil {
+		return err
+	}
+	roots, err := outputRoots(cfg)
+	if err != nil {
+		return err
+	}
+	if err := validateOutputRoots(pod, cfg, roots); err != nil {
+		return err
+	}
+	if err := validateSecretIsolation(pod, cfg, roots); err != nil {
+		return err
+	}
+
 	// Add shared volume for secrets
 	secretsVolume := corev1.Volume{
 		Name: "keeper-secrets",
@@ -156,6 +208,9 @@ func (m *PodMutator) mutatePod(ctx context.Context, pod *corev1.Pod, cfg *config
 	}
 	pod.Spec.Volumes = append(pod.Spec.Volumes, secretsVolume)
 
+	// Add volumes for output directories outside the secrets volume
+	pod.Spec.Volumes = append(pod.Spec.Volumes, outputVolumes(roots)...)
+
 	// Add CA certificate volume if specified (for corporate proxies)
 	if cfg.CACertSecret != "" || cfg.CACertConfigMap != "" {
 		caCertVolume := corev1.Volume{
@@ -191,33 +246,109 @@ func (m *PodMutator) mutatePod(ctx context.Context, pod *corev1.Pod, cfg *config
 		pod.Spec.Volumes = append(pod.Spec.Volumes, caCertVolume)
 	}
 
-	// Add volume mount to all existing containers
+	// Add ConfigMap volumes for templates referenced by templateRef
+	for i, name := range templateConfigMaps(cfg) {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: templateVolumeName(i),
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				},
+			},
+		})
+	}
+
+	// Add a node-local volume for the persistent cache unless an existing pod volume is used
+	if cfg.PersistentCache {
+		if cfg.CacheVolume == "" {
+			pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+				Name: cacheVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			})
+		} else if !hasVolume(pod, cfg.CacheVolume) {
+			return fmt.Errorf("%s: pod has no volume %q", config.AnnotationCacheVolume, cfg.CacheVolume)
+		}
+	}
+
+	// Add volume mount to the targeted app containers
 	secretsVolumeMount := corev1.VolumeMount{
 		Name:      "keeper-secrets",
 		MountPath: config.DefaultSecretsPath,
 		ReadOnly:  true,
 	}
 	for i := range pod.Spec.Containers {
+		if !mountsSecretsVolume(cfg, pod.Spec.Containers[i].Name) {
+			continue
+		}
 		pod.Spec.Containers[i].VolumeMounts = append(
 			pod.Spec.Containers[i].VolumeMounts,
 			secretsVolumeMount,
 		)
 	}
+	for _, root := range roots {
+		for i := range pod.Spec.Containers {
+			if root.targetsContainer(cfg, pod.Spec.Containers[i].Name) {
+				pod.Spec.Containers[i].VolumeMounts = append(pod.Spec.Containers[i].VolumeMounts, outputVolumeMount(root, true))
+			}
+		}
+	}
+
+	// Add volume mount to the selected app init containers
+	for i := range pod.Spec.InitContainers {
+		if slices.Contains(cfg.InitContainers, pod.Spec.InitContainers[i].Name) {
+			pod.Spec.InitContainers[i].VolumeMounts = append(
+				pod.Spec.InitContainers[i].VolumeMounts,
+				secretsVolumeMount,
+			)
+			for _, root := range roots {
+				pod.Spec.InitContainers[i].VolumeMounts = append(pod.Spec.InitContainers[i].VolumeMounts, outputVolumeMount(root, true))
+			}
+		}
+	}
+
+	// Resolve which process receives refresh signals (before the sidecar is appended)
+	if err := resolveSignalTarget(pod, cfg); err != nil {
+		return err
+	}
 
 	// Build sidecar config JSON
 	sidecarConfig := m.buildSidecarConfig(cfg)
+	if len(roots) > 0 {
+		sidecarConfig["outputRoots"] = outputRootPaths(roots)
+	}
 	sidecarConfigJSON, err := json.Marshal(sidecarConfig)
 	if err != nil {
 		return fmt.Errorf("failed to marshal sidecar config: %w", err)
 	}
 
-	// Create init container (always runs first to ensure secrets exist at startup)
+	// The agent runs as the signal target's user so it is allowed to signal it
+	runAsUser, runAsGroup, err := signalTargetIdentity(pod, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Create init container (runs before the app containers to ensure secrets exist at startup)
 	initContainer := m.buildInitContainer(cfg, string(sidecarConfigJSON))
-	pod.Spec.InitContainers = append(pod.Spec.InitContainers, initContainer)
+	for _, root := range roots {
+		initContainer.VolumeMounts = append(initContainer.VolumeMounts, outputVolumeMount(root, false))
+	}
+	applyRunAs(&initContainer, runAsUser, runAsGroup)
+	insertInitContainer(pod, cfg, initContainer)
 
 	// Create sidecar container (for rotation, unless init-only)
 	if !cfg.InitOnly {
+		// Refresh signals need to see the app's processes
+		if cfg.Signal != "" {
+			pod.Spec.ShareProcessNamespace = boolPtr(true)
+		}
+
 		sidecarContainer := m.buildSidecarContainer(cfg, string(sidecarConfigJSON))
+		for _, root := range roots {
+			sidecarContainer.VolumeMounts = append(sidecarContainer.VolumeMounts, outputVolumeMount(root, false))
+		}
+		applyRunAs(&sidecarContainer, runAsUser, runAsGroup)
 		pod.Spec.Containers = append(pod.Spec.Containers, sidecarContainer)
 	}
 
@@ -259,11 +390,22 @@ func (m *PodMutator) buildInitContainer(cfg *config.InjectionConfig, configJSON
 		})
 	}
 
+	// Add template ConfigMap mounts if configured
+	volumeMounts = append(volumeMounts, buildTemplateVolumeMounts(cfg)...)
+
+	// Add persistent cache mount if configured
+	volumeMounts = append(volumeMounts, buildCacheVolumeMounts(cfg)...)
+
+	args := []string{"--mode=init"}
+	if m.config.AllowUnsafeTemplateFuncs {
+		args = append(args, "--allow-unsafe-template-funcs")
+	}
+
 	return corev1.Container{
 		Name:            "keeper-secrets-init",
 		Image:           m.config.SidecarImage,
 		ImagePullPolicy: m.config.SidecarImagePullPolicy,
-		Args:            []string{"--mode=init"},
+		Args:            args,
 		Env: []corev1.EnvVar{
 			{
 				Name:  "KEEPER_CONFIG",
@@ -282,7 +424,7 @@ func (m *PodMutator) buildInitContainer(cfg *config.InjectionConfig, configJSON
 			},
 		},
 		VolumeMounts: volumeMounts,
-		Resources: m.buildResourceRequirements(),
+		Resources: m.buildResourceRequirements(cfg),
 		SecurityContext: &corev1.SecurityContext{
 			RunAsNonRoot:             boolPtr(true),
 			ReadOnlyRootFilesystem:   boolPtr(true),
@@ -303,6 +445,9 @@ func (m *PodMutator) buildSidecarContainer(cfg *config.InjectionConfig, configJS
 	if cfg.Signal != "" {
 		args = append(args, fmt.Sprintf("--signal=%s", cfg.Signal))
 	}
+	if m.config.AllowUnsafeTemplateFuncs {
+		args = append(args, "--allow-unsafe-template-funcs")
+	}
 
 	return corev1.Container{
 		Name:            "keeper-secrets-sidecar",
@@ -325,9 +470,18 @@ func (m *PodMutator) buildSidecarContainer(cfg *config.InjectionConfig, configJS
 					},
 				},
 			},
+			{
+				// K8s Secret rotation defaults to the pod's namespace
+				Name: "POD_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.namespace",
+					},
+				},
+			},
 		},
 		VolumeMounts: m.buildVolumeMounts(cfg),
-		Resources: m.buildResourceRequirements(),
+		Resources: m.buildResourceRequirements(cfg),
 		SecurityContext: &corev1.SecurityContext{
 			RunAsNonRoot:             boolPtr(true),
 			ReadOnlyRootFilesystem:   boolPtr(true),
@@ -359,6 +513,134 @@ func (m *PodMutator) buildSidecarContainer(cfg *config.InjectionConfig, configJS
 	}
 }
 
+// validateTemplates parses every inline secret, folder and multi-record template with the
+// agent's function map, and every folder file name pattern. Templates from ConfigMaps are parsed by the agent when rendering.
+// Unsafe template functions are rejected unless the cluster operator allows them.
+func validateTemplates(cfg *config.InjectionConfig, allowUnsafe bool) error {
+	for _, s := range cfg.Secrets {
+		if s.IsFile && (s.Template != "" || s.TemplateSource != nil) {
+			return fmt.Errorf("secret %q: template cannot be used with file attachments", s.Name)
+		}
+		if s.Template == "" {
+			continue
+		}
+		if err := sidecar.ValidateTemplate(s.Template, allowUnsafe); err != nil {
+			return fmt.Errorf("secret %q: %w", s.Name, err)
+		}
+	}
+	for _, t := range cfg.Templates {
+		if t.Template == "" {
+			continue
+		}
+		if err := sidecar.ValidateTemplate(t.Template, allowUnsafe); err != nil {
+			return fmt.Errorf("template %q: %w", t.Name, err)
+		}
+	}
+	for _, f := range cfg.Folders {
+		name := f.FolderUID
+		if name == "" {
+			name = f.FolderPath
+		}
+		if err := sidecar.ValidateFilenamePattern(f.FilenamePattern); err != nil {
+			return fmt.Errorf("folder %q: %w", name, err)
+		}
+		if f.Template == "" {
+			continue
+		}
+		if err := sidecar.ValidateTemplate(f.Template, allowUnsafe); err != nil {
+			return fmt.Errorf("folder %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// resolveSignalTarget fills in the signal target process for the configured refresh signal.
+// The target container defaults to the first targeted app container and the
+// process name defaults to the container name.
+func resolveSignalTarget(pod *corev1.Pod, cfg *config.InjectionConfig) error {
+	if cfg.Signal == "" || cfg.InitOnly {
+		return nil
+	}
+
+	containerName := cfg.SignalContainer
+	if containerName == "" {
+		for _, c := range pod.Spec.Containers {
+			if cfg.TargetsContainer(c.Name) {
+				containerName = c.Name
+				break
+			}
+		}
+		if containerName == "" {
+			return fmt.Errorf("signal %s configured but pod has no containers", cfg.Signal)
+		}
+	} else if !hasContainer(pod.Spec.Containers, containerName) {
+		return fmt.Errorf("signal container %q not found in pod", containerName)
+	}
+	cfg.SignalContainer = containerName
+
+	if cfg.SignalProcess == "" {
+		cfg.SignalProcess = containerName
+	}
+
+	return nil
+}
+
+// signalTargetIdentity returns the user and group the signal target container runs as.
+// Returns nil when no signal is configured or the identity is unknown (image default).
+// A target that runs as root is rejected: the non-root agent cannot signal it.
+func signalTargetIdentity(pod *corev1.Pod, cfg *config.InjectionConfig) (*int64, *int64, error) {
+	if cfg.Signal == "" || cfg.InitOnly {
+		return nil, nil, nil
+	}
+
+	var runAsUser, runAsGroup *int64
+	if pod.Spec.SecurityContext != nil {
+		runAsUser = pod.Spec.SecurityContext.RunAsUser
+		runAsGroup = pod.Spec.SecurityContext.RunAsGroup
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name != cfg.SignalContainer || c.SecurityContext == nil {
+			continue
+		}
+		if c.SecurityContext.RunAsUser != nil {
+			runAsUser = c.SecurityContext.RunAsUser
+		}
+		if c.SecurityContext.RunAsGroup != nil {
+			runAsGroup = c.SecurityContext.RunAsGroup
+		}
+	}
+
+	if runAsUser == nil {
+		return nil, nil, nil
+	}
+	if *runAsUser == 0 {
+		return nil, nil, fmt.Errorf("signal %s: container %s runs as root, which the non-root sidecar cannot signal",
+			cfg.Signal, cfg.SignalContainer)
+	}
+	return runAsUser, runAsGroup, nil
+}
+
+// applyRunAs overrides the container user and group when set
+func applyRunAs(c *corev1.Container, runAsUser, runAsGroup *int64) {
+	if runAsUser == nil {
+		return
+	}
+	c.SecurityContext.RunAsUser = runAsUser
+	if runAsGroup != nil {
+		c.SecurityContext.RunAsGroup = runAsGroup
+	}
+}
+
+// hasContainer reports whether a container with the given name exists
+func hasContainer(containers []corev1.Container, name string) bool {
+	for _, c := range containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // buildVolumeMounts creates volume mounts for init and sidecar containers
 func (m *PodMutator) buildVolumeMounts(cfg *config.InjectionConfig) []corev1.VolumeMount {
 	mounts := []corev1.VolumeMount{
@@ -378,11 +660,82 @@ func (m *PodMutator) buildVolumeMounts(cfg *config.InjectionConfig) []corev1.Vol
 		})
 	}
 
+	// Add template ConfigMap mounts (not subPath, so ConfigMap updates reach the sidecar)
+	mounts = append(mounts, buildTemplateVolumeMounts(cfg)...)
+
+	// Add persistent cache mount if configured
+	mounts = append(mounts, buildCacheVolumeMounts(cfg)...)
+
 	return mounts
 }
 
-// buildResourceRequirements creates resource requirements for containers
-func (m *PodMutator) buildResourceRequirements() corev1.ResourceRequirements {
+// buildCacheVolumeMounts mounts the persistent cache for the init and sidecar containers.
+// An existing pod volume is mounted at a subPath so it can also hold application data.
+func buildCacheVolumeMounts(cfg *config.InjectionConfig) []corev1.VolumeMount {
+	if !cfg.PersistentCache {
+		return nil
+	}
+	if cfg.CacheVolume == "" {
+		return []corev1.VolumeMount{{Name: cacheVolumeName, MountPath: config.DefaultCachePath}}
+	}
+	return []corev1.VolumeMount{{Name: cfg.CacheVolume, MountPath: config.DefaultCachePath, SubPath: cacheVolumeName}}
+}
+
+// hasVolume reports whether the pod defines a volume with the given name
+func hasVolume(pod *corev1.Pod, name string) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// templateConfigMaps returns the distinct ConfigMaps referenced by templateRef, sorted
+func templateConfigMaps(cfg *config.InjectionConfig) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(src *config.TemplateSource) {
+		if src != nil && !seen[src.ConfigMap] {
+			seen[src.ConfigMap] = true
+			names = append(names, src.ConfigMap)
+		}
+	}
+	for _, s := range cfg.Secrets {
+		add(s.TemplateSource)
+	}
+	for _, t := range cfg.Templates {
+		add(t.TemplateSource)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateVolumeName returns the volume name for the i-th template ConfigMap
+func templateVolumeName(i int) string {
+	return fmt.Sprintf("keeper-templates-%d", i)
+}
+
+// buildTemplateVolumeMounts mounts each template ConfigMap read-only under DefaultTemplatesPath
+func buildTemplateVolumeMounts(cfg *config.InjectionConfig) []corev1.VolumeMount {
+	names := templateConfigMaps(cfg)
+	mounts := make([]corev1.VolumeMount, 0, len(names))
+	for i, name := range names {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      templateVolumeName(i),
+			MountPath: filepath.Join(config.DefaultTemplatesPath, name),
+			ReadOnly:  true,
+		})
+	}
+	return mounts
+}
+
+// buildResourceRequirements creates resource requirements for containers;
+// an injection profile's resources replace the webhook's
+func (m *PodMutator) buildResourceRequirements(cfg *config.InjectionConfig) corev1.ResourceRequirements {
+	if cfg.Resources != nil {
+		return *cfg.Resources.DeepCopy()
+	}
 	return corev1.ResourceRequirements{
 		Requests: corev1.ResourceList{
 			corev1.ResourceCPU:    mustParseQuantity(m.config.CPURequest),
@@ -410,15 +763,31 @@ func (m *PodMutator) buildSidecarConfig(cfg *config.InjectionConfig) map[string]
 		if s.Notation != "" {
 			secret["notation"] = s.Notation
 		}
+		if prefix := envVarPrefix(s, cfg); prefix != "" && (s.InjectAsEnvVars || cfg.InjectEnvVars) {
+			secret["envPrefix"] = prefix
+		}
 		if s.Template != "" {
 			secret["template"] = s.Template
 		}
+		if s.TemplateSource != nil {
+			secret["templateFile"] = s.TemplateSource.FilePath()
+		}
 		if s.FileName != "" {
 			secret["fileName"] = s.FileName
 		}
 		if s.IsFile {
 			secret["isFile"] = s.IsFile
 		}
+		if len(s.Hooks) > 0 {
+			secret["hooks"] = buildHookConfigs(s.Name, s.Hooks)
+		}
+		if s.InjectAsK8sSecret || cfg.InjectAsK8sSecret {
+			secret["injectAsK8sSecret"] = true
+			secret["k8sSecretName"] = k8sSecretName(s, cfg)
+			if len(s.K8sSecretKeys) > 0 {
+				secret["k8sSecretKeys"] = s.K8sSecretKeys
+			}
+		}
 		secrets = append(secrets, secret)
 	}
 
@@ -427,6 +796,7 @@ func (m *PodMutator) buildSidecarConfig(cfg *config.InjectionConfig) map[string]
 	for _, f := range cfg.Folders {
 		folder := map[string]interface{}{
 			"outputPath": f.OutputPath,
+			"prune":      f.Prune,
 		}
 		if f.FolderUID != "" {
 			folder["folderUid"] = f.FolderUID
@@ -434,9 +804,51 @@ func (m *PodMutator) buildSidecarConfig(cfg *config.InjectionConfig) map[string]
 		if f.FolderPath != "" {
 			folder["folderPath"] = f.FolderPath
 		}
+		if f.Format != "" {
+			folder["format"] = f.Format
+		}
+		if f.Template != "" {
+			folder["template"] = f.Template
+		}
+		if f.FilenamePattern != "" {
+			folder["filenamePattern"] = f.FilenamePattern
+		}
+		if f.Recursive {
+			folder["recursive"] = true
+		}
+		if f.InjectAsK8sSecret || cfg.InjectAsK8sSecret {
+			folder["injectAsK8sSecret"] = true
+			folder["k8sSecretNamePrefix"] = f.K8sSecretNamePrefix
+		}
+		if !f.Filter.IsEmpty() {
+			folder["filter"] = map[string]interface{}{
+				"types":      f.Filter.Types,
+				"title":      f.Filter.Title,
+				"titleRegex": f.Filter.TitleRegex,
+				"labels":     f.Filter.Labels,
+			}
+		}
 		folders = append(folders, folder)
 	}
 
+	// Build multi-record template configs
+	templates := make([]map[string]interface{}, 0, len(cfg.Templates))
+	for _, t := range cfg.Templates {
+		tmpl := map[string]interface{}{
+			"name":     t.Name,
+			"path":     t.Path,
+			"records":  t.Records,
+			"template": t.Template,
+		}
+		if t.TemplateSource != nil {
+			tmpl["templateFile"] = t.TemplateSource.FilePath()
+		}
+		if len(t.Hooks) > 0 {
+			tmpl["hooks"] = buildHookConfigs(t.Name, t.Hooks)
+		}
+		templates = append(templates, tmpl)
+	}
+
 	result := map[string]interface{}{
 		"secrets":       secrets,
 		"failOnError":   cfg.FailOnError,
@@ -445,9 +857,35 @@ func (m *PodMutator) buildSidecarConfig(cfg *config.InjectionConfig) map[string]
 		"authMethod":    cfg.AuthMethod,
 	}
 
+	if cfg.SignalProcess != "" {
+		result["signalProcess"] = cfg.SignalProcess
+	}
+	if len(cfg.Hooks) > 0 {
+		result["hooks"] = buildHookConfigs("", cfg.Hooks)
+	}
+
 	if len(folders) > 0 {
 		result["folders"] = folders
 	}
+	if len(templates) > 0 {
+		result["templates"] = templates
+	}
+	if cfg.PersistentCache {
+		result["cacheDir"] = config.DefaultCachePath
+	}
+	if cfg.SecretsAPI {
+		result["secretsApi"] = true
+	}
+	if cfg.CacheMaxAge != "" {
+		result["cacheMaxAge"] = cfg.CacheMaxAge
+	}
+	if cfg.K8sSecretRotation && !cfg.InitOnly {
+		result["k8sSecretRotation"] = true
+		result["k8sSecretMode"] = cfg.K8sSecretMode
+		if cfg.K8sSecretNamespace != "" {
+			result["k8sSecretNamespace"] = cfg.K8sSecretNamespace
+		}
+	}
 
 	// Add cloud provider configuration if present
 	if cfg.AWSSecretID != "" {
@@ -467,6 +905,44 @@ func (m *PodMutator) buildSidecarConfig(cfg *config.InjectionConfig) map[string]
 	return result
 }
 
+// buildHookConfigs converts hooks to sidecar config entries with defaults
+// applied. Unnamed hooks are named after their secret or template (owner),
+// so their metrics stay apart.
+func buildHookConfigs(owner string, hooks []config.HookRef) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(hooks))
+	for i, h := range hooks {
+		name := h.Name
+		if name == "" {
+			name = fmt.Sprintf("hook-%d", i)
+			if owner != "" {
+				name = owner + "-" + name
+			}
+		}
+		timeout := h.Timeout
+		if timeout == "" {
+			timeout = config.DefaultHookTimeout
+		}
+		retries := config.DefaultHookRetries
+		if h.Retries != nil {
+			retries = *h.Retries
+		}
+
+		hook := map[string]interface{}{
+			"name":    name,
+			"timeout": timeout,
+			"retries": retries,
+		}
+		if h.URL != "" {
+			hook["url"] = h.URL
+		}
+		if len(h.Command) > 0 {
+			hook["command"] = h.Command
+		}
+		result = append(result, hook)
+	}
+	return result
+}
+
 // InjectDecoder injects the decoder
 func (m *PodMutator) InjectDecoder(d admission.Decoder) error {
 	m.decoder = d
@@ -0,0 +1,591 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// newTestMutator returns a PodMutator with default config and no K8s client
+func newTestMutator() *PodMutator {
+	return NewPodMutator(nil, zap.NewNop(), DefaultWebhookConfig())
+}
+
+// newTestPod returns a pod with the given app containers
+func newTestPod(containers ...corev1.Container) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+		Spec: corev1.PodSpec{
+			Containers: containers,
+		},
+	}
+}
+
+// findContainer returns the named container or nil
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+// sidecarConfigFrom decodes KEEPER_CONFIG from a container
+func sidecarConfigFrom(t *testing.T, c *corev1.Container) map[string]interface{} {
+	t.Helper()
+	for _, env := range c.Env {
+		if env.Name == "KEEPER_CONFIG" {
+			var result map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(env.Value), &result))
+			return result
+		}
+	}
+	t.Fatalf("KEEPER_CONFIG not set on container %s", c.Name)
+	return nil
+}
+
+func TestMutatePod_SignalDefaultsToFirstContainer(t *testing.T) {
+	pod := newTestPod(
+		corev1.Container{Name: "nginx", Image: "nginx"},
+		corev1.Container{Name: "log-shipper", Image: "fluent-bit"},
+	)
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		Signal:          "SIGHUP",
+		Secrets:         []config.SecretRef{{Name: "tls", Path: "/keeper/secrets/tls.json", Format: "json"}},
+	}
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	require.NotNil(t, pod.Spec.ShareProcessNamespace)
+	assert.True(t, *pod.Spec.ShareProcessNamespace)
+
+	sidecar := findContainer(pod.Spec.Containers, "keeper-secrets-sidecar")
+	require.NotNil(t, sidecar)
+	assert.Contains(t, sidecar.Args, "--signal=SIGHUP")
+
+	sidecarCfg := sidecarConfigFrom(t, sidecar)
+	assert.Equal(t, "SIGHUP", sidecarCfg["refreshSignal"])
+	assert.Equal(t, "nginx", sidecarCfg["signalProcess"])
+}
+
+func TestMutatePod_SignalTargetContainerAndProcess(t *testing.T) {
+	uid := int64(70)
+	gid := int64(70)
+	pod := newTestPod(
+		corev1.Container{Name: "app", Image: "app"},
+		corev1.Container{
+			Name:  "pooler",
+			Image: "pgbouncer",
+			SecurityContext: &corev1.SecurityContext{
+				RunAsUser:  &uid,
+				RunAsGroup: &gid,
+			},
+		},
+	)
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		Signal:          "SIGHUP",
+		SignalContainer: "pooler",
+		SignalProcess:   "pgbouncer",
+		Secrets:         []config.SecretRef{{Name: "db", Path: "/keeper/secrets/db.json", Format: "json"}},
+	}
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	sidecar := findContainer(pod.Spec.Containers, "keeper-secrets-sidecar")
+	require.NotNil(t, sidecar)
+	assert.Equal(t, "pgbouncer", sidecarConfigFrom(t, sidecar)["signalProcess"])
+
+	// Agent runs as the target's user so it may signal it
+	require.NotNil(t, sidecar.SecurityContext.RunAsUser)
+	assert.Equal(t, uid, *sidecar.SecurityContext.RunAsUser)
+	assert.Equal(t, gid, *sidecar.SecurityContext.RunAsGroup)
+
+	initContainer := findContainer(pod.Spec.InitContainers, "keeper-secrets-init")
+	require.NotNil(t, initContainer)
+	assert.Equal(t, uid, *initContainer.SecurityContext.RunAsUser)
+}
+
+func TestMutatePod_SignalTargetRunsAsRoot(t *testing.T) {
+	root := int64(0)
+	pod := newTestPod(corev1.Container{
+		Name:            "app",
+		Image:           "app",
+		SecurityContext: &corev1.SecurityContext{RunAsUser: &root},
+	})
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		Signal:          "SIGHUP",
+		Secrets:         []config.SecretRef{{Name: "db", Path: "/keeper/secrets/db.json", Format: "json"}},
+	}
+
+	err := newTestMutator().mutatePod(context.Background(), pod, cfg)
+	assert.ErrorContains(t, err, "container app runs as root")
+}
+
+func TestMutatePod_SignalUnknownContainer(t *testing.T) {
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		Signal:          "SIGHUP",
+		SignalContainer: "missing",
+		Secrets:         []config.SecretRef{{Name: "db", Path: "/keeper/secrets/db.json", Format: "json"}},
+	}
+
+	err := newTestMutator().mutatePod(context.Background(), pod, cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing")
+}
+
+func TestMutatePod_NoSignalNoSharedProcessNamespace(t *testing.T) {
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		Secrets:         []config.SecretRef{{Name: "db", Path: "/keeper/secrets/db.json", Format: "json"}},
+	}
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	assert.Nil(t, pod.Spec.ShareProcessNamespace)
+	sidecar := findContainer(pod.Spec.Containers, "keeper-secrets-sidecar")
+	require.NotNil(t, sidecar)
+	assert.Nil(t, sidecar.SecurityContext.RunAsUser)
+	assert.NotContains(t, sidecarConfigFrom(t, sidecar), "signalProcess")
+}
+
+// newAdmissionRequest wraps a pod in an admission create request
+func newAdmissionRequest(t *testing.T, pod *corev1.Pod) admission.Request {
+	t.Helper()
+	raw, err := json.Marshal(pod)
+	require.NoError(t, err)
+	return admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: pod.Namespace,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+}
+
+// newDecodingMutator returns a PodMutator able to decode admission requests
+func newDecodingMutator(t *testing.T) *PodMutator {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	m := newTestMutator()
+	require.NoError(t, m.InjectDecoder(admission.NewDecoder(scheme)))
+	return m
+}
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		secret      config.SecretRef
+		allowUnsafe bool
+		wantErr     string
+	}{
+		{
+			name:   "no template",
+			secret: config.SecretRef{Name: "db", Format: "json"},
+		},
+		{
+			name:   "valid template with sprig and keeper functions",
+			secret: config.SecretRef{Name: "db", Template: `{{ .login | upper }}:{{ .password | base64enc }}`},
+		},
+		{
+			name:    "unclosed action",
+			secret:  config.SecretRef{Name: "db", Template: `{{ .login `},
+			wantErr: "template parse error",
+		},
+		{
+			name:    "unknown function",
+			secret:  config.SecretRef{Name: "db", Template: `{{ .login | nosuchfunc }}`},
+			wantErr: "nosuchfunc",
+		},
+		{
+			name:    "template on file attachment",
+			secret:  config.SecretRef{Name: "certs", IsFile: true, FileName: "ca.pem", Template: `{{ . }}`},
+			wantErr: "file attachments",
+		},
+		{
+			name:    "env disabled by default",
+			secret:  config.SecretRef{Name: "db", Template: `{{ env "KEEPER_AUTH_CONFIG" }}`},
+			wantErr: `function "env" not defined`,
+		},
+		{
+			name:        "env allowed by operator",
+			secret:      config.SecretRef{Name: "db", Template: `{{ env "HOME" }}`},
+			allowUnsafe: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTemplates(&config.InjectionConfig{Secrets: []config.SecretRef{tt.secret}}, tt.allowUnsafe)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Contains(t, err.Error(), tt.secret.Name)
+		})
+	}
+}
+
+func TestHandle_RejectsInvalidTemplate(t *testing.T) {
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+	pod.Annotations = map[string]string{
+		config.AnnotationInject:    "true",
+		config.AnnotationKSMConfig: "keeper-auth",
+		config.AnnotationConfig: `
+secrets:
+  - record: db
+    path: /keeper/secrets/db.conf
+    template: "user={{ .login "
+`,
+	}
+
+	resp := newDecodingMutator(t).Handle(context.Background(), newAdmissionRequest(t, pod))
+
+	assert.False(t, resp.Allowed)
+	require.NotNil(t, resp.Result)
+	assert.Equal(t, int32(http.StatusBadRequest), resp.Result.Code)
+	assert.Contains(t, resp.Result.Message, "template parse error")
+}
+
+func TestBuildContainers_AllowUnsafeTemplateFuncs(t *testing.T) {
+	cfg := &config.InjectionConfig{AuthSecretName: "keeper-auth", RefreshInterval: "5m"}
+
+	mutator := NewPodMutator(nil, nil, nil)
+	assert.NotContains(t, mutator.buildInitContainer(cfg, "{}").Args, "--allow-unsafe-template-funcs")
+	assert.NotContains(t, mutator.buildSidecarContainer(cfg, "{}").Args, "--allow-unsafe-template-funcs")
+
+	webhookCfg := DefaultWebhookConfig()
+	webhookCfg.AllowUnsafeTemplateFuncs = true
+	mutator = NewPodMutator(nil, nil, webhookCfg)
+	assert.Contains(t, mutator.buildInitContainer(cfg, "{}").Args, "--allow-unsafe-template-funcs")
+	assert.Contains(t, mutator.buildSidecarContainer(cfg, "{}").Args, "--allow-unsafe-template-funcs")
+}
+
+func TestHandle_TemplatePassedToSidecar(t *testing.T) {
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+	pod.Annotations = map[string]string{
+		config.AnnotationInject:    "true",
+		config.AnnotationKSMConfig: "keeper-auth",
+		config.AnnotationConfig: `
+secrets:
+  - record: db
+    path: /keeper/secrets/db.conf
+    template: "user={{ .login }}"
+`,
+	}
+
+	resp := newDecodingMutator(t).Handle(context.Background(), newAdmissionRequest(t, pod))
+	require.True(t, resp.Allowed, "unexpected denial: %v", resp.Result)
+
+	// Apply the patch to inspect the sidecar config
+	var mutated *corev1.Pod
+	for _, op := range resp.Patches {
+		if op.Path != "/spec/containers/1" {
+			continue
+		}
+		raw, err := json.Marshal(op.Value)
+		require.NoError(t, err)
+		var c corev1.Container
+		require.NoError(t, json.Unmarshal(raw, &c))
+		mutated = newTestPod(c)
+	}
+	require.NotNil(t, mutated, "sidecar container not found in patch")
+
+	sidecarCfg := sidecarConfigFrom(t, &mutated.Spec.Containers[0])
+	secrets := sidecarCfg["secrets"].([]interface{})
+	require.Len(t, secrets, 1)
+	assert.Equal(t, "user={{ .login }}", secrets[0].(map[string]interface{})["template"])
+}
+
+func TestBuildSidecarConfig_Templates(t *testing.T) {
+	cfg := &config.InjectionConfig{
+		Templates: []config.TemplateRef{{
+			Name:     "application",
+			Path:     "/keeper/secrets/application.yaml",
+			Records:  map[string]string{"db": "postgres", "redis": "redis"},
+			Template: "{{ .db.password }}",
+			Hooks:    []config.HookRef{{URL: "http://127.0.0.1:8080/reload"}},
+		}},
+	}
+
+	raw, err := json.Marshal(newTestMutator().buildSidecarConfig(cfg))
+	require.NoError(t, err)
+
+	var result struct {
+		Templates []struct {
+			Name     string                   `json:"name"`
+			Path     string                   `json:"path"`
+			Records  map[string]string        `json:"records"`
+			Template string                   `json:"template"`
+			Hooks    []map[string]interface{} `json:"hooks"`
+		} `json:"templates"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &result))
+	require.Len(t, result.Templates, 1)
+	tmpl := result.Templates[0]
+	assert.Equal(t, "application", tmpl.Name)
+	assert.Equal(t, "/keeper/secrets/application.yaml", tmpl.Path)
+	assert.Equal(t, map[string]string{"db": "postgres", "redis": "redis"}, tmpl.Records)
+	assert.Equal(t, "{{ .db.password }}", tmpl.Template)
+	require.Len(t, tmpl.Hooks, 1)
+	assert.Equal(t, "http://127.0.0.1:8080/reload", tmpl.Hooks[0]["url"])
+	assert.Equal(t, "application-hook-0", tmpl.Hooks[0]["name"])
+
+	// Multi-record templates are validated at admission like secret templates
+	cfg.Templates[0].Template = "{{ .db.password | nosuchfunc }}"
+	err = validateTemplates(cfg, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `template "application"`)
+}
+
+func TestMutatePod_TemplateRefMountsConfigMap(t *testing.T) {
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		Secrets: []config.SecretRef{{
+			Name:           "db",
+			Path:           "/keeper/secrets/db.conf",
+			Format:         "json",
+			TemplateSource: &config.TemplateSource{ConfigMap: "app-templates", Key: "db.conf.tmpl"},
+		}},
+		Templates: []config.TemplateRef{{
+			Name:           "application",
+			Path:           "/keeper/secrets/application.yaml",
+			Records:        map[string]string{"db": "db"},
+			TemplateSource: &config.TemplateSource{ConfigMap: "app-templates", Key: "application.yaml.tmpl"},
+		}},
+	}
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	// One volume per ConfigMap, shared by all templates in it
+	var templateVolumes []corev1.Volume
+	for _, v := range pod.Spec.Volumes {
+		if v.ConfigMap != nil && v.ConfigMap.Name == "app-templates" {
+			templateVolumes = append(templateVolumes, v)
+		}
+	}
+	require.Len(t, templateVolumes, 1)
+
+	expected := corev1.VolumeMount{
+		Name:      templateVolumes[0].Name,
+		MountPath: "/keeper/templates/app-templates",
+		ReadOnly:  true,
+	}
+	initContainer := findContainer(pod.Spec.InitContainers, "keeper-secrets-init")
+	require.NotNil(t, initContainer)
+	assert.Contains(t, initContainer.VolumeMounts, expected)
+	sidecar := findContainer(pod.Spec.Containers, "keeper-secrets-sidecar")
+	require.NotNil(t, sidecar)
+	assert.Contains(t, sidecar.VolumeMounts, expected)
+
+	// The app container does not see templates
+	assert.NotContains(t, findContainer(pod.Spec.Containers, "app").VolumeMounts, expected)
+
+	sidecarCfg := sidecarConfigFrom(t, sidecar)
+	secret := sidecarCfg["secrets"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "/keeper/templates/app-templates/db.conf.tmpl", secret["templateFile"])
+	tmpl := sidecarCfg["templates"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "/keeper/templates/app-templates/application.yaml.tmpl", tmpl["templateFile"])
+}
+
+func TestMutatePod_PersistentCache(t *testing.T) {
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		Secrets:         []config.SecretRef{{Name: "db", Path: "/keeper/secrets/db.json", Format: "json"}},
+		PersistentCache: true,
+		CacheMaxAge:     "2h",
+	}
+
+	// Default: node-local emptyDir
+	pod := newTestPod(corev1.Container{Name: "app", Image: "app"})
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	var cacheVolume *corev1.Volume
+	for i := range pod.Spec.Volumes {
+		if pod.Spec.Volumes[i].Name == "keeper-cache" {
+			cacheVolume = &pod.Spec.Volumes[i]
+		}
+	}
+	require.NotNil(t, cacheVolume)
+	require.NotNil(t, cacheVolume.EmptyDir)
+	assert.Empty(t, cacheVolume.EmptyDir.Medium, "cache is stored on node disk, not tmpfs")
+
+	expected := corev1.VolumeMount{Name: "keeper-cache", MountPath: "/keeper/cache"}
+	assert.Contains(t, findContainer(pod.Spec.InitContainers, "keeper-secrets-init").VolumeMounts, expected)
+	sidecar := findContainer(pod.Spec.Containers, "keeper-secrets-sidecar")
+	assert.Contains(t, sidecar.VolumeMounts, expected)
+	assert.NotContains(t, findContainer(pod.Spec.Containers, "app").VolumeMounts, expected)
+
+	sidecarCfg := sidecarConfigFrom(t, sidecar)
+	assert.Equal(t, "/keeper/cache", sidecarCfg["cacheDir"])
+	assert.Equal(t, "2h", sidecarCfg["cacheMaxAge"])
+	assert.NotContains(t, sidecarCfg, "secretsApi")
+
+	// Existing pod volume, mounted at a subPath
+	cfg.CacheVolume = "keeper-state"
+	pod = newTestPod(corev1.Container{Name: "app", Image: "app"})
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: "keeper-state",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "keeper-state-0"},
+		},
+	})
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+	assert.Len(t, pod.Spec.Volumes, 2, "no emptyDir is added")
+	assert.Contains(t, findContainer(pod.Spec.Containers, "keeper-secrets-sidecar").VolumeMounts,
+		corev1.VolumeMount{Name: "keeper-state", MountPath: "/keeper/cache", SubPath: "keeper-cache"})
+
+	// Missing pod volume
+	pod = newTestPod(corev1.Container{Name: "app", Image: "app"})
+	err := newTestMutator().mutatePod(context.Background(), pod, cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "keeper-state")
+}
+
+func TestBuildSidecarConfig_SecretsAPI(t *testing.T) {
+	cfg := &config.InjectionConfig{SecretsAPI: true}
+	result := newTestMutator().buildSidecarConfig(cfg)
+	assert.Equal(t, true, result["secretsApi"])
+}
+
+func TestBuildSidecarConfig_Folders(t *testing.T) {
+	cfg := &config.InjectionConfig{
+		Folders: []config.FolderRef{
+			{FolderUID: "FOLDER1", OutputPath: "/keeper/secrets/a", Prune: true},
+			{FolderUID: "FOLDER2", OutputPath: "/keeper/secrets/b", Recursive: true,
+				Filter: config.RecordFilter{Types: []string{"login"}, Labels: map[string]string{"env": "prod"}}},
+		},
+	}
+
+	raw, err := json.Marshal(newTestMutator().buildSidecarConfig(cfg))
+	require.NoError(t, err)
+
+	var result struct {
+		Folders []struct {
+			FolderUID string `json:"folderUid"`
+			Prune     bool   `json:"prune"`
+			Recursive bool   `json:"recursive"`
+			Filter    *struct {
+				Types  []string          `json:"types"`
+				Labels map[string]string `json:"labels"`
+			} `json:"filter"`
+		} `json:"folders"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &result))
+	require.Len(t, result.Folders, 2)
+	assert.True(t, result.Folders[0].Prune)
+	assert.False(t, result.Folders[1].Prune)
+	assert.False(t, result.Folders[0].Recursive)
+	assert.Nil(t, result.Folders[0].Filter)
+	assert.True(t, result.Folders[1].Recursive)
+	require.NotNil(t, result.Folders[1].Filter)
+	assert.Equal(t, []string{"login"}, result.Folders[1].Filter.Types)
+	assert.Equal(t, "prod", result.Folders[1].Filter.Labels["env"])
+}
+
+func TestValidateTemplates_Folders(t *testing.T) {
+	valid := &config.InjectionConfig{Folders: []config.FolderRef{{
+		FolderUID:       "FOLDER1",
+		Template:        "DB_PASSWORD={{ .password }}",
+		FilenamePattern: "{{ .Type }}/{{ .Title | lower }}.env",
+	}}}
+	assert.NoError(t, validateTemplates(valid, false))
+
+	badPattern := &config.InjectionConfig{Folders: []config.FolderRef{{FolderUID: "FOLDER1", FilenamePattern: "{{ .Title "}}}
+	err := validateTemplates(badPattern, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "filename pattern parse error")
+
+	badTemplate := &config.InjectionConfig{Folders: []config.FolderRef{{FolderUID: "FOLDER1", Template: `{{ env "HOME" }}`, FilenamePattern: "{{ .Title }}"}}}
+	assert.Error(t, validateTemplates(badTemplate, false))
+}
+
+func TestBuildSidecarConfig_K8sSecretRotation(t *testing.T) {
+	cfg := &config.InjectionConfig{
+		AuthSecretName:    "keeper-auth",
+		RefreshInterval:   "5m",
+		InjectAsK8sSecret: true,
+		K8sSecretName:     "app-secrets",
+		K8sSecretMode:     "merge",
+		K8sSecretRotation: true,
+		Secrets: []config.SecretRef{
+			{Name: "db", Path: "/keeper/secrets/db.json", Format: "json"},
+			{Name: "api", Path: "/keeper/secrets/api.json", Format: "json",
+				K8sSecretName: "api-keys", K8sSecretKeys: map[string]string{"token": "API_TOKEN"}},
+		},
+		Folders: []config.FolderRef{{FolderUID: "FOLDER1", OutputPath: "/keeper/secrets/f"}},
+	}
+
+	mutator := newTestMutator()
+	raw, err := json.Marshal(mutator.buildSidecarConfig(cfg))
+	require.NoError(t, err)
+	sidecar := mutator.buildSidecarContainer(cfg, string(raw))
+	assert.Contains(t, sidecar.Env, corev1.EnvVar{
+		Name:      "POD_NAMESPACE",
+		ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}},
+	})
+
+	result := sidecarConfigFrom(t, &sidecar)
+	assert.Equal(t, true, result["k8sSecretRotation"])
+	assert.Equal(t, "merge", result["k8sSecretMode"])
+	assert.NotContains(t, result, "k8sSecretNamespace")
+
+	secrets := result["secrets"].([]interface{})
+	db := secrets[0].(map[string]interface{})
+	assert.Equal(t, true, db["injectAsK8sSecret"])
+	assert.Equal(t, "app-secrets", db["k8sSecretName"])
+	api := secrets[1].(map[string]interface{})
+	assert.Equal(t, "api-keys", api["k8sSecretName"])
+	assert.Equal(t, map[string]interface{}{"token": "API_TOKEN"}, api["k8sSecretKeys"])
+	folder := result["folders"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, true, folder["injectAsK8sSecret"])
+
+	// No sidecar runs in init-only pods
+	cfg.InitOnly = true
+	assert.NotContains(t, mutator.buildSidecarConfig(cfg), "k8sSecretRotation")
+}
+
+func TestBuildHookConfigs_DefaultNames(t *testing.T) {
+	reload := config.HookRef{URL: "http://127.0.0.1:8080/reload"}
+	named := config.HookRef{Name: "notify", Command: []string{"/bin/notify"}}
+
+	db := buildHookConfigs("db", []config.HookRef{reload, named})
+	api := buildHookConfigs("api", []config.HookRef{reload})
+	pod := buildHookConfigs("", []config.HookRef{reload})
+
+	assert.Equal(t, "db-hook-0", db[0]["name"])
+	assert.Equal(t, "notify", db[1]["name"], "explicit names are kept")
+	assert.Equal(t, "api-hook-0", api[0]["name"], "unnamed hooks of different secrets get separate metric labels")
+	assert.Equal(t, "hook-0", pod[0]["name"])
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
 	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -24,19 +25,17 @@ const (
 // injectK8sSecrets creates K8s Secret objects from Keeper secrets.
 // This is called during pod admission (webhook time) to create/update Secrets.
 func (m *PodMutator) injectK8sSecrets(ctx context.Context, pod *corev1.Pod, cfg *config.InjectionConfig) error {
-	if !cfg.InjectAsK8sSecret {
-		return nil
-	}
-
-	// Filter secrets that should become K8s Secrets
+	// Filter secrets and folders that should become K8s Secrets
 	k8sSecrets := filterK8sSecretConfigs(cfg)
-	if len(k8sSecrets) == 0 {
+	k8sFolders := filterK8sSecretFolders(cfg)
+	if len(k8sSecrets) == 0 && len(k8sFolders) == 0 {
 		m.logger.Debug("no secrets configured for K8s Secret injection")
 		return nil
 	}
 
 	m.logger.Info("injecting Kubernetes Secrets",
 		zap.Int("secretCount", len(k8sSecrets)),
+		zap.Int("folderCount", len(k8sFolders)),
 		zap.String("pod", pod.Name))
 
 	// Create KSM client (reuse from envvar.go pattern)
@@ -63,6 +62,9 @@ func (m *PodMutator) injectK8sSecrets(ctx context.Context, pod *corev1.Pod, cfg
 		m.logger.Error("failed to fetch secrets, continuing", zap.Error(err))
 	}
 
+	// Names of the Secrets the sidecar keeps in sync
+	var rotated []string
+
 	// Create/update K8s Secrets
 	for i, secretRef := range k8sSecrets {
 		data, ok := secretsData[i]
@@ -80,18 +82,136 @@ func (m *PodMutator) injectK8sSecrets(ctx context.Context, pod *corev1.Pod, cfg
 			return fmt.Errorf("K8s Secret %s exceeds size limit: %w", k8sSecret.Name, err)
 		}
 
-		if err := m.createOrUpdateSecret(ctx, k8sSecret, cfg.K8sSecretMode, cfg.K8sSecretOwnerRef); err != nil {
+		written, err := m.createOrUpdateSecret(ctx, k8sSecret, cfg.K8sSecretMode, cfg.K8sSecretOwnerRef)
+		if err != nil {
 			return fmt.Errorf("failed to create/update K8s Secret %s: %w", k8sSecret.Name, err)
 		}
+		if !written {
+			continue
+		}
 
 		m.logger.Info("created/updated K8s Secret",
 			zap.String("name", k8sSecret.Name),
 			zap.String("namespace", k8sSecret.Namespace))
+		rotated = append(rotated, k8sSecret.Name)
+	}
+
+	// Create/update one K8s Secret per folder record
+	for _, folder := range k8sFolders {
+		listing, err := sidecar.ListFolderRecords(ctx, ksmClient, folderConfig(folder))
+		if err != nil {
+			if cfg.FailOnError {
+				return fmt.Errorf("failed to list folder %s: %w", folderName(folder), err)
+			}
+			m.logger.Error("failed to list folder, continuing",
+				zap.String("folder", folderName(folder)),
+				zap.Error(err))
+			continue
+		}
+
+		for _, k8sSecret := range m.buildFolderK8sSecrets(pod, folder, listing.Records, cfg) {
+			if err := validateSecretSize(k8sSecret); err != nil {
+				return fmt.Errorf("K8s Secret %s exceeds size limit: %w", k8sSecret.Name, err)
+			}
+			written, err := m.createOrUpdateSecret(ctx, k8sSecret, cfg.K8sSecretMode, cfg.K8sSecretOwnerRef)
+			if err != nil {
+				return fmt.Errorf("failed to create/update K8s Secret %s: %w", k8sSecret.Name, err)
+			}
+			if !written {
+				continue
+			}
+			m.logger.Info("created/updated K8s Secret from folder record",
+				zap.String("name", k8sSecret.Name),
+				zap.String("namespace", k8sSecret.Namespace),
+				zap.String("folder", folderName(folder)))
+			rotated = append(rotated, k8sSecret.Name)
+		}
+	}
+
+	// Let the sidecar's service account update exactly the Secrets written here
+	if cfg.K8sSecretRotation && !cfg.InitOnly && len(rotated) > 0 {
+		if err := m.ensureRotationRBAC(ctx, pod, k8sSecretNamespace(pod, cfg), rotated); err != nil {
+			if cfg.FailOnError {
+				return fmt.Errorf("failed to grant K8s Secret rotation: %w", err)
+			}
+			m.logger.Warn("failed to grant K8s Secret rotation, Secrets will not be updated by the sidecar",
+				zap.Error(err))
+		}
 	}
 
 	return nil
 }
 
+// filterK8sSecretFolders returns only folders whose records should become K8s Secrets
+func filterK8sSecretFolders(cfg *config.InjectionConfig) []config.FolderRef {
+	var k8sFolders []config.FolderRef
+	for _, folder := range cfg.Folders {
+		if folder.InjectAsK8sSecret || cfg.InjectAsK8sSecret {
+			k8sFolders = append(k8sFolders, folder)
+		}
+	}
+	return k8sFolders
+}
+
+// buildFolderK8sSecrets constructs one K8s Secret per folder record, named
+// <prefix><sanitized title>. Records whose title gives no valid or a duplicate
+// Secret name are skipped.
+func (m *PodMutator) buildFolderK8sSecrets(pod *corev1.Pod, folder config.FolderRef, records []*ksm.SecretData, cfg *config.InjectionConfig) []*corev1.Secret {
+	var secrets []*corev1.Secret
+	seen := make(map[string]string, len(records))
+	for _, record := range records {
+		name := sidecar.FolderSecretName(folder.K8sSecretNamePrefix, record.Title)
+		if name == "" {
+			m.logger.Warn("skipping folder record without a valid K8s Secret name",
+				zap.String("title", record.Title))
+			continue
+		}
+		if other, ok := seen[name]; ok {
+			m.logger.Warn("skipping folder record with duplicate K8s Secret name",
+				zap.String("title", record.Title),
+				zap.String("conflictsWith", other),
+				zap.String("name", name))
+			continue
+		}
+		seen[name] = record.Title
+
+		secretRef := config.SecretRef{Name: record.Title, K8sSecretName: name}
+		k8sSecret, err := m.buildK8sSecret(pod, secretRef, record, cfg)
+		if err != nil {
+			continue // Only fails without a name
+		}
+		k8sSecret.Annotations["keeper.security/source-folder"] = folderName(folder)
+		secrets = append(secrets, k8sSecret)
+	}
+	return secrets
+}
+
+// folderConfig converts a folder reference to the sidecar's folder configuration
+func folderConfig(f config.FolderRef) sidecar.FolderConfig {
+	return sidecar.FolderConfig{
+		FolderUID:  f.FolderUID,
+		FolderPath: f.FolderPath,
+		OutputPath: f.OutputPath,
+		Recursive:  f.Recursive,
+		Filter: sidecar.RecordFilter{
+			Types:      f.Filter.Types,
+			Title:      f.Filter.Title,
+			TitleRegex: f.Filter.TitleRegex,
+			Labels:     f.Filter.Labels,
+		},
+		InjectAsK8sSecret:   f.InjectAsK8sSecret,
+		K8sSecretNamePrefix: f.K8sSecretNamePrefix,
+	}
+}
+
+// folderName names a folder reference in logs and errors
+func folderName(f config.FolderRef) string {
+	if f.FolderUID != "" {
+		return f.FolderUID
+	}
+	return f.FolderPath
+}
+
 // filterK8sSecretConfigs returns only secrets that should become K8s Secrets
 func filterK8sSecretConfigs(cfg *config.InjectionConfig) []config.SecretRef {
 	var k8sSecrets []config.SecretRef
@@ -189,15 +309,9 @@ func (m *PodMutator) batchFetchSecrets(ctx context.Context, ksmClient *ksm.Clien
 
 // buildK8sSecret constructs a K8s Secret from Keeper data
 func (m *PodMutator) buildK8sSecret(pod *corev1.Pod, secretRef config.SecretRef, data *ksm.SecretData, cfg *config.InjectionConfig) (*corev1.Secret, error) {
-	namespace := cfg.K8sSecretNamespace
-	if namespace == "" {
-		namespace = pod.Namespace
-	}
+	namespace := k8sSecretNamespace(pod, cfg)
 
-	secretName := secretRef.K8sSecretName
-	if secretName == "" {
-		secretName = cfg.K8sSecretName
-	}
+	secretName := k8sSecretName(secretRef, cfg)
 	if secretName == "" {
 		return nil, fmt.Errorf("k8s secret name not specified for secret %s", secretRef.Name)
 	}
@@ -271,27 +385,48 @@ func (m *PodMutator) buildK8sSecret(pod *corev1.Pod, secretRef config.SecretRef,
 	}, nil
 }
 
-// createOrUpdateSecret handles Secret creation with conflict resolution
-func (m *PodMutator) createOrUpdateSecret(ctx context.Context, secret *corev1.Secret, mode string, ownerRefEnabled bool) error {
+// k8sSecretNamespace returns the namespace of the K8s Secrets (defaults to the pod's)
+func k8sSecretNamespace(pod *corev1.Pod, cfg *config.InjectionConfig) string {
+	if cfg.K8sSecretNamespace != "" {
+		return cfg.K8sSecretNamespace
+	}
+	return pod.Namespace
+}
+
+// k8sSecretName returns the K8s Secret name of a secret (defaults to the pod-wide name)
+func k8sSecretName(secretRef config.SecretRef, cfg *config.InjectionConfig) string {
+	if secretRef.K8sSecretName != "" {
+		return secretRef.K8sSecretName
+	}
+	return cfg.K8sSecretName
+}
+
+// createOrUpdateSecret handles Secret creation with conflict resolution.
+// Returns whether the Secret was created or updated; an existing Secret
+// left untouched (skip-if-exists) is not the injector's to rotate.
+func (m *PodMutator) createOrUpdateSecret(ctx context.Context, secret *corev1.Secret, mode string, ownerRefEnabled bool) (bool, error) {
 	existing := &corev1.Secret{}
 	err := m.Client.Get(ctx, client.ObjectKeyFromObject(secret), existing)
 
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// Secret doesn't exist, create it
-			return m.Client.Create(ctx, secret)
+			if err := m.Client.Create(ctx, secret); err != nil {
+				return false, err
+			}
+			return true, nil
 		}
-		return fmt.Errorf("failed to check existing secret: %w", err)
+		return false, fmt.Errorf("failed to check existing secret: %w", err)
 	}
 
 	// Secret exists, handle based on mode
 	switch mode {
 	case "fail":
-		return fmt.Errorf("secret %s already exists (mode: fail)", secret.Name)
+		return false, fmt.Errorf("secret %s already exists (mode: fail)", secret.Name)
 
 	case "skip-if-exists":
 		m.logger.Info("skipping existing secret", zap.String("name", secret.Name))
-		return nil
+		return false, nil
 
 	case "merge":
 		// Merge data (new keys override, existing keys preserved)
@@ -318,7 +453,10 @@ func (m *PodMutator) createOrUpdateSecret(ctx context.Context, secret *corev1.Se
 		if ownerRefEnabled && len(secret.OwnerReferences) > 0 {
 			existing.OwnerReferences = secret.OwnerReferences
 		}
-		return m.Client.Update(ctx, existing)
+		if err := m.Client.Update(ctx, existing); err != nil {
+			return false, err
+		}
+		return true, nil
 
 	case "overwrite", "":
 		// Replace all data
@@ -329,10 +467,13 @@ func (m *PodMutator) createOrUpdateSecret(ctx context.Context, secret *corev1.Se
 		if ownerRefEnabled {
 			existing.OwnerReferences = secret.OwnerReferences
 		}
-		return m.Client.Update(ctx, existing)
+		if err := m.Client.Update(ctx, existing); err != nil {
+			return false, err
+		}
+		return true, nil
 
 	default:
-		return fmt.Errorf("unknown k8s-secret-mode: %s (valid: overwrite, merge, skip-if-exists, fail)", mode)
+		return false, fmt.Errorf("unknown k8s-secret-mode: %s (valid: overwrite, merge, skip-if-exists, fail)", mode)
 	}
 }
 
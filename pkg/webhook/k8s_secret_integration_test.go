@@ -159,7 +159,7 @@ func TestIntegration_CreateK8sSecret_SingleRecord(t *testing.T) {
 	assert.Equal(t, "test-pod", k8sSecret.OwnerReferences[0].Name)
 
 	// Create Secret in fake cluster
-	err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "overwrite", true)
+	_, err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "overwrite", true)
 	require.NoError(t, err)
 
 	// Verify Secret was created
@@ -287,7 +287,7 @@ func TestIntegration_ConflictMode_Overwrite(t *testing.T) {
 	require.NoError(t, err)
 
 	// Overwrite existing Secret
-	err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "overwrite", true)
+	_, err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "overwrite", true)
 	require.NoError(t, err)
 
 	// Verify Secret was overwritten
@@ -359,7 +359,7 @@ func TestIntegration_ConflictMode_Merge(t *testing.T) {
 	require.NoError(t, err)
 
 	// Merge with existing Secret
-	err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "merge", true)
+	_, err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "merge", true)
 	require.NoError(t, err)
 
 	// Verify Secret was merged
@@ -432,7 +432,7 @@ func TestIntegration_ConflictMode_SkipIfExists(t *testing.T) {
 	require.NoError(t, err)
 
 	// Skip if exists
-	err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "skip-if-exists", true)
+	_, err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "skip-if-exists", true)
 	require.NoError(t, err)
 
 	// Verify Secret was NOT modified
@@ -502,7 +502,7 @@ func TestIntegration_ConflictMode_Fail(t *testing.T) {
 	require.NoError(t, err)
 
 	// Should fail because Secret exists
-	err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "fail", true)
+	_, err = mutator.createOrUpdateSecret(context.Background(), k8sSecret, "fail", true)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "already exists")
 
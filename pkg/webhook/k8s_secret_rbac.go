@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rotationRBACName names the Role and RoleBinding that let a service account
+// rotate its K8s Secrets. The name keeps the pod namespace for Roles created
+// before rotation was limited to the pod's own namespace.
+func rotationRBACName(pod *corev1.Pod) string {
+	return fmt.Sprintf("keeper-secret-rotation:%s:%s", pod.Namespace, podServiceAccount(pod))
+}
+
+// podServiceAccount returns the service account the pod runs as
+func podServiceAccount(pod *corev1.Pod) string {
+	if pod.Spec.ServiceAccountName != "" {
+		return pod.Spec.ServiceAccountName
+	}
+	return "default"
+}
+
+// ensureRotationRBAC grants the pod's service account get and patch on the
+// named Secrets and nothing else, so the sidecar can keep them in sync.
+// Only Secrets in the pod's own namespace that carry the injector's labels
+// are granted. The Role is shared by every pod running as the service
+// account: names whose Secrets were deleted are pruned on each update, and
+// the Role is owned by its Secrets so it is garbage collected with the last
+// of them.
+//
+// Pods of one service account are often admitted concurrently, so writes
+// that lose a race are retried. The cached client may lag behind the write
+// that won, so the retries back off rather than retry at once.
+func (m *PodMutator) ensureRotationRBAC(ctx context.Context, pod *corev1.Pod, namespace string, secretNames []string) error {
+	if m.config == nil || !m.config.K8sSecretRotationRBAC {
+		return fmt.Errorf("the webhook is not allowed to grant K8s Secret rotation; install the chart with k8sSecretRotation.enabled=true (--enable-k8s-secret-rotation)")
+	}
+	if namespace != pod.Namespace {
+		return fmt.Errorf("refusing to grant rotation of Secrets in namespace %s to a pod in %s", namespace, pod.Namespace)
+	}
+
+	retryable := func(err error) bool { return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err) }
+	return retry.OnError(retry.DefaultBackoff, retryable, func() error {
+		return m.applyRotationRBAC(ctx, pod, namespace, secretNames)
+	})
+}
+
+// applyRotationRBAC makes one attempt at bringing the Role and RoleBinding up to date
+func (m *PodMutator) applyRotationRBAC(ctx context.Context, pod *corev1.Pod, namespace string, secretNames []string) error {
+	name := rotationRBACName(pod)
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "keeper-injector",
+	}
+
+	role := &rbacv1.Role{}
+	err := m.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, role)
+	exists := err == nil
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get Role %s: %w", name, err)
+	}
+
+	var existing []string
+	for _, rule := range role.Rules {
+		existing = append(existing, rule.ResourceNames...)
+	}
+	names, owners, err := m.managedSecrets(ctx, namespace, mergeNames(existing, secretNames))
+	if err != nil {
+		return err
+	}
+
+	// An empty resourceNames list would match every Secret
+	if len(names) == 0 {
+		if exists {
+			return m.deleteRotationRBAC(ctx, namespace, name)
+		}
+		return nil
+	}
+
+	if !exists {
+		role = &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels, OwnerReferences: owners},
+			Rules:      []rbacv1.PolicyRule{rotationRule(names)},
+		}
+		if err := m.Client.Create(ctx, role); err != nil {
+			return fmt.Errorf("failed to create Role %s: %w", name, err)
+		}
+	} else if !slices.Equal(names, existing) || len(role.Rules) != 1 || !slices.Equal(role.OwnerReferences, owners) {
+		role.Rules = []rbacv1.PolicyRule{rotationRule(names)}
+		role.OwnerReferences = owners
+		if err := m.Client.Update(ctx, role); err != nil {
+			return fmt.Errorf("failed to update Role %s: %w", name, err)
+		}
+	}
+
+	binding := &rbacv1.RoleBinding{}
+	err = m.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, binding)
+	if apierrors.IsNotFound(err) {
+		binding = &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    labels,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: rbacv1.SchemeGroupVersion.String(),
+					Kind:       "Role",
+					Name:       role.Name,
+					UID:        role.UID,
+				}},
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "Role",
+				Name:     name,
+			},
+			Subjects: []rbacv1.Subject{{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      podServiceAccount(pod),
+				Namespace: pod.Namespace,
+			}},
+		}
+		if err := m.Client.Create(ctx, binding); err != nil {
+			return fmt.Errorf("failed to create RoleBinding %s: %w", name, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to get RoleBinding %s: %w", name, err)
+	}
+
+	m.logger.Debug("granted K8s Secret rotation",
+		zap.String("role", name),
+		zap.String("namespace", namespace),
+		zap.Strings("secrets", names))
+	return nil
+}
+
+// managedSecrets keeps the names of Secrets that exist and carry the
+// injector's managed-by label, and returns owner references to them
+func (m *PodMutator) managedSecrets(ctx context.Context, namespace string, names []string) ([]string, []metav1.OwnerReference, error) {
+	var kept []string
+	var owners []metav1.OwnerReference
+	for _, name := range names {
+		secret := &corev1.Secret{}
+		err := m.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get Secret %s: %w", name, err)
+		}
+		if secret.Labels["app.kubernetes.io/managed-by"] != "keeper-injector" {
+			m.logger.Warn("not granting rotation of a Secret the injector does not manage",
+				zap.String("name", name),
+				zap.String("namespace", namespace))
+			continue
+		}
+		kept = append(kept, name)
+		owners = append(owners, metav1.OwnerReference{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Name:       name,
+			UID:        secret.UID,
+		})
+	}
+	return kept, owners, nil
+}
+
+// deleteRotationRBAC removes the Role and RoleBinding once none of their Secrets remain
+func (m *PodMutator) deleteRotationRBAC(ctx context.Context, namespace, name string) error {
+	meta := metav1.ObjectMeta{Name: name, Namespace: namespace}
+	if err := m.Client.Delete(ctx, &rbacv1.RoleBinding{ObjectMeta: meta}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete RoleBinding %s: %w", name, err)
+	}
+	if err := m.Client.Delete(ctx, &rbacv1.Role{ObjectMeta: meta}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete Role %s: %w", name, err)
+	}
+	return nil
+}
+
+// rotationRule allows reading and patching (server-side apply) the named Secrets
+func rotationRule(secretNames []string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		Verbs:         []string{"get", "patch"},
+		ResourceNames: secretNames,
+	}
+}
+
+// mergeNames returns the sorted union of two name lists
+func mergeNames(a, b []string) []string {
+	merged := append(slices.Clone(a), b...)
+	slices.Sort(merged)
+	return slices.Compact(merged)
+}
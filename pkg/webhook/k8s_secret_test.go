@@ -2,6 +2,7 @@ package webhook
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
@@ -10,11 +11,15 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 // TestBuildK8sSecret_AllFields tests building a K8s Secret with all fields
@@ -306,7 +311,7 @@ func TestCreateOrUpdateSecret_Create(t *testing.T) {
 		},
 	}
 
-	err := mutator.createOrUpdateSecret(context.Background(), secret, "overwrite", true)
+	_, err := mutator.createOrUpdateSecret(context.Background(), secret, "overwrite", true)
 	require.NoError(t, err)
 
 	// Verify secret was created
@@ -354,7 +359,7 @@ func TestCreateOrUpdateSecret_Overwrite(t *testing.T) {
 		},
 	}
 
-	err := mutator.createOrUpdateSecret(context.Background(), updated, "overwrite", true)
+	_, err := mutator.createOrUpdateSecret(context.Background(), updated, "overwrite", true)
 	require.NoError(t, err)
 
 	// Verify secret was overwritten
@@ -407,7 +412,7 @@ func TestCreateOrUpdateSecret_Merge(t *testing.T) {
 		},
 	}
 
-	err := mutator.createOrUpdateSecret(context.Background(), updated, "merge", true)
+	_, err := mutator.createOrUpdateSecret(context.Background(), updated, "merge", true)
 	require.NoError(t, err)
 
 	// Verify secret was merged
@@ -459,8 +464,9 @@ func TestCreateOrUpdateSecret_SkipIfExists(t *testing.T) {
 		},
 	}
 
-	err := mutator.createOrUpdateSecret(context.Background(), updated, "skip-if-exists", true)
+	written, err := mutator.createOrUpdateSecret(context.Background(), updated, "skip-if-exists", true)
 	require.NoError(t, err)
+	assert.False(t, written, "an existing Secret is not the injector's to rotate")
 
 	// Verify secret was NOT updated
 	result := &corev1.Secret{}
@@ -505,7 +511,7 @@ func TestCreateOrUpdateSecret_Fail(t *testing.T) {
 		},
 	}
 
-	err := mutator.createOrUpdateSecret(context.Background(), updated, "fail", true)
+	_, err := mutator.createOrUpdateSecret(context.Background(), updated, "fail", true)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "already exists")
 }
@@ -778,11 +784,11 @@ func TestCreateOrUpdateSecret_InvalidMode(t *testing.T) {
 	}
 
 	// Create the secret first
-	err := mutator.createOrUpdateSecret(context.Background(), secret, "overwrite", true)
+	_, err := mutator.createOrUpdateSecret(context.Background(), secret, "overwrite", true)
 	require.NoError(t, err)
 
 	// Try to update with invalid mode
-	err = mutator.createOrUpdateSecret(context.Background(), secret, "invalid-mode", true)
+	_, err = mutator.createOrUpdateSecret(context.Background(), secret, "invalid-mode", true)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "unknown k8s-secret-mode")
 }
@@ -898,3 +904,193 @@ func TestFilterK8sSecretConfigs(t *testing.T) {
 		assert.Len(t, filtered, 0)
 	})
 }
+
+// TestFilterK8sSecretFolders tests folder filtering logic
+func TestFilterK8sSecretFolders(t *testing.T) {
+	cfg := &config.InjectionConfig{
+		Folders: []config.FolderRef{
+			{FolderPath: "Production/APIs", InjectAsK8sSecret: true, K8sSecretNamePrefix: "api-"},
+			{FolderPath: "Production/Files"},
+		},
+	}
+	filtered := filterK8sSecretFolders(cfg)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "Production/APIs", filtered[0].FolderPath)
+
+	cfg.InjectAsK8sSecret = true
+	assert.Len(t, filterK8sSecretFolders(cfg), 2)
+}
+
+// TestBuildFolderK8sSecrets tests one Secret per folder record
+func TestBuildFolderK8sSecrets(t *testing.T) {
+	mutator := &PodMutator{logger: zap.NewNop()}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+			UID:       types.UID("test-uid-123"),
+		},
+	}
+	folder := config.FolderRef{FolderPath: "Production/APIs", InjectAsK8sSecret: true, K8sSecretNamePrefix: "api-"}
+	records := []*ksm.SecretData{
+		{Title: "Stripe", Fields: map[string]interface{}{"apiKey": "sk_live"}},
+		{Title: "Twilio Prod", Fields: map[string]interface{}{"token": "tw"}},
+		{Title: "twilio prod", Fields: map[string]interface{}{"token": "duplicate"}},
+		{Title: "!!!", Fields: map[string]interface{}{"x": "invalid name"}},
+	}
+	cfg := &config.InjectionConfig{K8sSecretOwnerRef: true, K8sSecretType: "Opaque"}
+
+	secrets := mutator.buildFolderK8sSecrets(pod, folder, records, cfg)
+	require.Len(t, secrets, 2)
+
+	assert.Equal(t, "api-stripe", secrets[0].Name)
+	assert.Equal(t, "default", secrets[0].Namespace)
+	assert.Equal(t, []byte("sk_live"), secrets[0].Data["apiKey"])
+	assert.Equal(t, "Stripe", secrets[0].Annotations["keeper.security/source-record"])
+	assert.Equal(t, "Production/APIs", secrets[0].Annotations["keeper.security/source-folder"])
+	require.Len(t, secrets[0].OwnerReferences, 1)
+	assert.Equal(t, "test-pod", secrets[0].OwnerReferences[0].Name)
+
+	assert.Equal(t, "api-twilio-prod", secrets[1].Name)
+	assert.Equal(t, []byte("tw"), secrets[1].Data["token"])
+}
+
+// TestEnsureRotationRBAC tests the least-privilege Role for sidecar rotation
+func TestEnsureRotationRBAC(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+
+	managed := func(name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "apps",
+			UID:       types.UID("uid-" + name),
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "keeper-injector"},
+		}}
+	}
+	foreign := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "apps"}}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(managed("stripe"), managed("db"), managed("twilio"), foreign).
+		Build()
+	mutator := &PodMutator{Client: fakeClient, logger: zap.NewNop(), config: &WebhookConfig{K8sSecretRotationRBAC: true}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "apps"},
+		Spec:       corev1.PodSpec{ServiceAccountName: "payments"},
+	}
+
+	require.NoError(t, mutator.ensureRotationRBAC(context.Background(), pod, "apps", []string{"stripe", "db"}))
+	// Another pod of the same service account adds its Secrets; unmanaged names are not granted
+	require.NoError(t, mutator.ensureRotationRBAC(context.Background(), pod, "apps", []string{"db", "twilio", "registry"}))
+
+	key := client.ObjectKey{Namespace: "apps", Name: "keeper-secret-rotation:apps:payments"}
+	role := &rbacv1.Role{}
+	require.NoError(t, fakeClient.Get(context.Background(), key, role))
+	require.Len(t, role.Rules, 1)
+	assert.Equal(t, []string{"secrets"}, role.Rules[0].Resources)
+	assert.Equal(t, []string{"get", "patch"}, role.Rules[0].Verbs)
+	assert.Equal(t, []string{"db", "stripe", "twilio"}, role.Rules[0].ResourceNames)
+	require.Len(t, role.OwnerReferences, 3)
+	assert.Equal(t, "Secret", role.OwnerReferences[0].Kind)
+	assert.Equal(t, types.UID("uid-db"), role.OwnerReferences[0].UID)
+
+	binding := &rbacv1.RoleBinding{}
+	require.NoError(t, fakeClient.Get(context.Background(), key, binding))
+	assert.Equal(t, key.Name, binding.RoleRef.Name)
+	require.Len(t, binding.Subjects, 1)
+	assert.Equal(t, "payments", binding.Subjects[0].Name)
+	assert.Equal(t, "apps", binding.Subjects[0].Namespace)
+
+	// Deleted Secrets are pruned from the Role
+	require.NoError(t, fakeClient.Delete(context.Background(), managed("stripe")))
+	require.NoError(t, mutator.ensureRotationRBAC(context.Background(), pod, "apps", []string{"db"}))
+	require.NoError(t, fakeClient.Get(context.Background(), key, role))
+	assert.Equal(t, []string{"db", "twilio"}, role.Rules[0].ResourceNames)
+
+	// Once no granted Secret remains, the Role and RoleBinding are removed
+	for _, name := range []string{"db", "twilio"} {
+		require.NoError(t, fakeClient.Delete(context.Background(), managed(name)))
+	}
+	require.NoError(t, mutator.ensureRotationRBAC(context.Background(), pod, "apps", []string{"db"}))
+	assert.True(t, apierrors.IsNotFound(fakeClient.Get(context.Background(), key, &rbacv1.Role{})))
+	assert.True(t, apierrors.IsNotFound(fakeClient.Get(context.Background(), key, &rbacv1.RoleBinding{})))
+}
+
+func TestEnsureRotationRBAC_OtherNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	mutator := &PodMutator{Client: fakeClient, logger: zap.NewNop(), config: &WebhookConfig{K8sSecretRotationRBAC: true}}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "apps"}}
+
+	err := mutator.ensureRotationRBAC(context.Background(), pod, "kube-system", []string{"bootstrap-token"})
+	assert.ErrorContains(t, err, "refusing to grant rotation")
+
+	roles := &rbacv1.RoleList{}
+	require.NoError(t, fakeClient.List(context.Background(), roles))
+	assert.Empty(t, roles.Items)
+}
+
+func TestEnsureRotationRBAC_Disabled(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	mutator := &PodMutator{Client: fakeClient, logger: zap.NewNop(), config: DefaultWebhookConfig()}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "apps"}}
+
+	err := mutator.ensureRotationRBAC(context.Background(), pod, "apps", []string{"db"})
+	assert.ErrorContains(t, err, "k8sSecretRotation.enabled=true")
+
+	roles := &rbacv1.RoleList{}
+	require.NoError(t, fakeClient.List(context.Background(), roles))
+	assert.Empty(t, roles.Items)
+}
+
+func TestEnsureRotationRBAC_ConcurrentCreate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      "db",
+		Namespace: "apps",
+		UID:       "uid-db",
+		Labels:    map[string]string{"app.kubernetes.io/managed-by": "keeper-injector"},
+	}}
+	// Another replica's admission creates each object just before this one does
+	raced := map[string]bool{}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				kind := fmt.Sprintf("%T", obj)
+				if raced[kind] {
+					return c.Create(ctx, obj, opts...)
+				}
+				raced[kind] = true
+				if err := c.Create(ctx, obj.DeepCopyObject().(client.Object), opts...); err != nil {
+					return err
+				}
+				return apierrors.NewAlreadyExists(schema.GroupResource{Group: rbacv1.GroupName}, obj.GetName())
+			},
+		}).
+		Build()
+	mutator := &PodMutator{Client: fakeClient, logger: zap.NewNop(), config: &WebhookConfig{K8sSecretRotationRBAC: true}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "apps"},
+		Spec:       corev1.PodSpec{ServiceAccountName: "payments"},
+	}
+
+	require.NoError(t, mutator.ensureRotationRBAC(context.Background(), pod, "apps", []string{"db"}))
+	assert.True(t, raced["*v1.Role"])
+	assert.True(t, raced["*v1.RoleBinding"])
+
+	key := client.ObjectKey{Namespace: "apps", Name: "keeper-secret-rotation:apps:payments"}
+	role := &rbacv1.Role{}
+	require.NoError(t, fakeClient.Get(context.Background(), key, role))
+	assert.Equal(t, []string{"db"}, role.Rules[0].ResourceNames)
+	require.NoError(t, fakeClient.Get(context.Background(), key, &rbacv1.RoleBinding{}))
+}
@@ -0,0 +1,236 @@
+package webhook
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// outputVolumePrefix starts the names of the volumes for output directories
+// outside config.DefaultSecretsPath
+const outputVolumePrefix = "keeper-output-"
+
+// caCertMountPath is where the custom CA certificate is mounted in agent containers
+const caCertMountPath = "/usr/local/share/ca-certificates/keeper-ca.crt"
+
+// systemDirs are image directories an output volume must not replace, as
+// the directory itself or as a parent
+var systemDirs = []string{
+	"/bin", "/boot", "/dev", "/etc", "/etc/pki", "/etc/ssl", "/home", "/lib", "/lib64", "/opt",
+	"/proc", "/root", "/run", "/sbin", "/sys", "/tmp", "/usr", "/usr/local", "/var", "/var/lib", "/var/run",
+}
+
+// systemTrees hold binaries, libraries and kernel interfaces; no output
+// directory may be below them
+var systemTrees = []string{"/bin", "/boot", "/dev", "/lib", "/lib64", "/proc", "/sbin", "/sys", "/usr"}
+
+// hiddenSystemDir returns the system directory an output volume at dir would hide
+func hiddenSystemDir(dir string) (string, bool) {
+	for _, tree := range systemTrees {
+		if isWithin(dir, tree) {
+			return tree, true
+		}
+	}
+	for _, sys := range systemDirs {
+		if isWithin(sys, dir) {
+			return sys, true
+		}
+	}
+	return "", false
+}
+
+// outputRoot is a directory outside config.DefaultSecretsPath that receives
+// secret files. Each gets its own memory-backed volume, mounted as a whole
+// rather than with subPath so rotated files reach the app.
+type outputRoot struct {
+	Path   string
+	Volume string
+
+	secrets []config.SecretRef // Secrets written under Path
+	shared  bool               // A folder or template writes under Path
+}
+
+// targetsContainer reports whether the app container needs the directory
+func (r outputRoot) targetsContainer(cfg *config.InjectionConfig, name string) bool {
+	if !cfg.TargetsContainer(name) {
+		return false
+	}
+	if r.shared {
+		return true
+	}
+	for _, s := range r.secrets {
+		if s.TargetsContainer(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// outputRoots derives the output directories outside config.DefaultSecretsPath
+// from the secret, folder and template paths. Nested directories share the
+// volume of the outermost one.
+func outputRoots(cfg *config.InjectionConfig) ([]outputRoot, error) {
+	type output struct {
+		dir    string
+		secret *config.SecretRef
+	}
+	var outputs []output
+	// file is set when dir is the parent of a file path rather than a
+	// directory named as the output of a folder
+	add := func(what, dir string, secret *config.SecretRef, file bool) error {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("%s: output path must be absolute", what)
+		}
+		dir = filepath.Clean(dir)
+		if isWithin(dir, config.DefaultSecretsPath) {
+			return nil
+		}
+		if dir == "/" || isWithin(config.DefaultSecretsPath, dir) {
+			return fmt.Errorf("%s: output directory %s would hide %s", what, dir, config.DefaultSecretsPath)
+		}
+		if sys, ok := hiddenSystemDir(dir); ok {
+			return fmt.Errorf("%s: output directory %s would hide the image's %s; use a directory that only holds secrets", what, dir, sys)
+		}
+		// A top-level directory such as /app usually holds the application
+		// itself, and the volume would replace it
+		if file && filepath.Dir(dir) == "/" {
+			return fmt.Errorf("%s: output directory %s is a top-level directory the volume would replace; write the file to a directory that only holds secrets, such as %s",
+				what, dir, filepath.Join(dir, "secrets"))
+		}
+		for _, reserved := range []string{config.DefaultTemplatesPath, config.DefaultCachePath, execBinPath, execAuthPath, caCertMountPath} {
+			if pathsOverlap(dir, reserved) {
+				return fmt.Errorf("%s: output directory %s overlaps the injector's %s", what, dir, reserved)
+			}
+		}
+		outputs = append(outputs, output{dir: dir, secret: secret})
+		return nil
+	}
+
+	for i := range cfg.Secrets {
+		s := &cfg.Secrets[i]
+		if s.Path == "" {
+			continue
+		}
+		if err := add("secret "+s.Name, filepath.Dir(s.Path), s, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range cfg.Folders {
+		if err := add("folder "+cmp.Or(f.FolderPath, f.FolderUID), f.OutputPath, nil, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range cfg.Templates {
+		if err := add("template "+t.Name, filepath.Dir(t.Path), nil, true); err != nil {
+			return nil, err
+		}
+	}
+
+	// Outer directories first, so nested ones fold into them
+	sort.SliceStable(outputs, func(i, j int) bool {
+		return len(outputs[i].dir) < len(outputs[j].dir)
+	})
+	var roots []outputRoot
+	for _, o := range outputs {
+		idx := slices.IndexFunc(roots, func(r outputRoot) bool { return isWithin(o.dir, r.Path) })
+		if idx < 0 {
+			roots = append(roots, outputRoot{Path: o.dir})
+			idx = len(roots) - 1
+		}
+		if o.secret != nil {
+			roots[idx].secrets = append(roots[idx].secrets, *o.secret)
+		} else {
+			roots[idx].shared = true
+		}
+	}
+
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Path < roots[j].Path })
+	for i := range roots {
+		roots[i].Volume = fmt.Sprintf("%s%d", outputVolumePrefix, i)
+	}
+	return roots, nil
+}
+
+// validateOutputRoots rejects output directories that overlap a volume the
+// pod already mounts in a container that would receive them
+func validateOutputRoots(pod *corev1.Pod, cfg *config.InjectionConfig, roots []outputRoot) error {
+	check := func(c corev1.Container, root outputRoot) error {
+		for _, m := range c.VolumeMounts {
+			if pathsOverlap(root.Path, filepath.Clean(m.MountPath)) {
+				return fmt.Errorf("output directory %s overlaps mount %s (volume %s) of container %s",
+					root.Path, m.MountPath, m.Name, c.Name)
+			}
+		}
+		return nil
+	}
+
+	for _, root := range roots {
+		for _, c := range pod.Spec.Containers {
+			if !root.targetsContainer(cfg, c.Name) {
+				continue
+			}
+			if err := check(c, root); err != nil {
+				return err
+			}
+		}
+		for _, c := range pod.Spec.InitContainers {
+			if !slices.Contains(cfg.InitContainers, c.Name) {
+				continue
+			}
+			if err := check(c, root); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// outputVolumes returns the volumes backing the output directories
+func outputVolumes(roots []outputRoot) []corev1.Volume {
+	volumes := make([]corev1.Volume, 0, len(roots))
+	for _, root := range roots {
+		volumes = append(volumes, corev1.Volume{
+			Name: root.Volume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium: corev1.StorageMediumMemory, // tmpfs - memory backed
+				},
+			},
+		})
+	}
+	return volumes
+}
+
+// outputVolumeMount mounts an output directory; only the agents write to it
+func outputVolumeMount(root outputRoot, readOnly bool) corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      root.Volume,
+		MountPath: root.Path,
+		ReadOnly:  readOnly,
+	}
+}
+
+// outputRootPaths returns the directories the agent publishes atomically
+func outputRootPaths(roots []outputRoot) []string {
+	paths := []string{config.DefaultSecretsPath}
+	for _, root := range roots {
+		paths = append(paths, root.Path)
+	}
+	return paths
+}
+
+// isWithin reports whether path is dir or below it
+func isWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// pathsOverlap reports whether one path is the other or contains it
+func pathsOverlap(a, b string) bool {
+	return isWithin(a, b) || isWithin(b, a)
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func findVolumeMount(c *corev1.Container, name string) *corev1.VolumeMount {
+	for i := range c.VolumeMounts {
+		if c.VolumeMounts[i].Name == name {
+			return &c.VolumeMounts[i]
+		}
+	}
+	return nil
+}
+
+func TestOutputRoots(t *testing.T) {
+	cfg := &config.InjectionConfig{
+		Secrets: []config.SecretRef{
+			{Name: "db", Path: "/app/config/db.json"},
+			{Name: "tls", Path: "/app/config/tls/cert.pem"},
+			{Name: "api", Path: "/etc/app/api.json"},
+			{Name: "default", Path: "/keeper/secrets/default.json"},
+		},
+		Folders: []config.FolderRef{{FolderPath: "Shared", OutputPath: "/data/keeper/"}},
+	}
+
+	roots, err := outputRoots(cfg)
+	require.NoError(t, err)
+	require.Len(t, roots, 3)
+	assert.Equal(t, "/app/config", roots[0].Path)
+	assert.Len(t, roots[0].secrets, 2, "nested directories share the outer volume")
+	assert.Equal(t, "/data/keeper", roots[1].Path)
+	assert.True(t, roots[1].shared)
+	assert.Equal(t, "/etc/app", roots[2].Path)
+	assert.Equal(t, "keeper-output-2", roots[2].Volume)
+	assert.Equal(t, []string{"/keeper/secrets", "/app/config", "/data/keeper", "/etc/app"}, outputRootPaths(roots))
+}
+
+func TestOutputRoots_TopLevelFolder(t *testing.T) {
+	// A folder names its output directory, so a top-level one is dedicated to secrets
+	cfg := &config.InjectionConfig{Folders: []config.FolderRef{{FolderPath: "Shared", OutputPath: "/secrets"}}}
+	roots, err := outputRoots(cfg)
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	assert.Equal(t, "/secrets", roots[0].Path)
+}
+
+func TestOutputRoots_Invalid(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{path: "db.json", wantErr: "must be absolute"},
+		{path: "/db.json", wantErr: "would hide /keeper/secrets"},
+		{path: "/keeper/db.json", wantErr: "would hide /keeper/secrets"},
+		{path: "/keeper/cache/db.json", wantErr: "overlaps the injector's /keeper/cache"},
+		{path: "/keeper/bin/db.json", wantErr: "overlaps the injector's /keeper/bin"},
+		{path: "/keeper/exec-auth/db.json", wantErr: "overlaps the injector's /keeper/exec-auth"},
+		{path: "/etc/db.json", wantErr: "would hide the image's /etc"},
+		{path: "/var/db.json", wantErr: "would hide the image's /var"},
+		{path: "/etc/ssl/db.json", wantErr: "would hide the image's /etc/ssl"},
+		{path: "/usr/local/app/db.json", wantErr: "would hide the image's /usr"},
+		{path: "/lib/app/db.json", wantErr: "would hide the image's /lib"},
+		{path: "/app/config.json", wantErr: "/app is a top-level directory the volume would replace"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			cfg := &config.InjectionConfig{Secrets: []config.SecretRef{{Name: "db", Path: tt.path}}}
+			_, err := outputRoots(cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMutatePod_OutputPaths(t *testing.T) {
+	pod := newTestPod(
+		corev1.Container{Name: "app", Image: "app"},
+		corev1.Container{Name: "worker", Image: "worker"},
+	)
+	cfg := &config.InjectionConfig{
+		AuthSecretName:  "keeper-auth",
+		RefreshInterval: "5m",
+		Secrets: []config.SecretRef{
+			{Name: "db", Path: "/app/config/db.json", Format: "json", Containers: []string{"app"}},
+			{Name: "tls", Path: "/keeper/secrets/tls.json", Format: "json"},
+		},
+	}
+
+	require.NoError(t, newTestMutator().mutatePod(context.Background(), pod, cfg))
+
+	assert.True(t, hasVolume(pod, "keeper-output-0"))
+
+	mount := findVolumeMount(findContainer(pod.Spec.Containers, "app"), "keeper-output-0")
+	require.NotNil(t, mount)
+	assert.Equal(t, "/app/config", mount.MountPath)
+	assert.True(t, mount.ReadOnly)
+	assert.Nil(t, findVolumeMount(findContainer(pod.Spec.Containers, "worker"), "keeper-output-0"),
+		"no secret in the directory targets worker")
+
+	for _, agent := range []*corev1.Container{
+		findContainer(pod.Spec.InitContainers, "keeper-secrets-init"),
+		findContainer(pod.Spec.Containers, "keeper-secrets-sidecar"),
+	} {
+		mount := findVolumeMount(agent, "keeper-output-0")
+		require.NotNil(t, mount, agent.Name)
+		assert.False(t, mount.ReadOnly, "%s writes the directory", agent.Name)
+	}
+
+	sidecarCfg := sidecarConfigFrom(t, findContainer(pod.Spec.Containers, "keeper-secrets-sidecar"))
+	assert.Equal(t, []interface{}{"/keeper/secrets", "/app/config"}, sidecarCfg["outputRoots"])
+}
+
+func TestMutatePod_OutputPathOverlap(t *testing.T) {
+	for _, mountPath := range []string{"/app/config", "/app", "/app/config/tls"} {
+		t.Run(mountPath, func(t *testing.T) {
+			pod := newTestPod(corev1.Container{
+				Name:         "app",
+				Image:        "app",
+				VolumeMounts: []corev1.VolumeMount{{Name: "settings", MountPath: mountPath}},
+			})
+			cfg := &config.InjectionConfig{
+				AuthSecretName:  "keeper-auth",
+				RefreshInterval: "5m",
+				Secrets:         []config.SecretRef{{Name: "db", Path: "/app/config/db.json", Format: "json"}},
+			}
+
+			err := newTestMutator().mutatePod(context.Background(), pod, cfg)
+			assert.ErrorContains(t, err, "overlaps mount "+mountPath)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/apis/v1alpha1"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// parseInjectionConfig parses the pod's annotations on top of the injection
+// profile it references, if any
+func parseInjectionConfig(ctx context.Context, c client.Reader, pod *corev1.Pod, namespace string) (*config.InjectionConfig, error) {
+	profile, err := loadProfile(ctx, c, namespace, pod.Annotations[config.AnnotationProfile])
+	if err != nil {
+		return nil, err
+	}
+	return config.ParseAnnotationsWithProfile(pod, profile)
+}
+
+// loadProfile returns the KeeperInjectionProfile of that name in the pod's
+// namespace, or else the ClusterKeeperInjectionProfile. With the manager's
+// client both are read from its informer cache.
+func loadProfile(ctx context.Context, c client.Reader, namespace, name string) (*v1alpha1.KeeperInjectionProfileSpec, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+
+	profile := &v1alpha1.KeeperInjectionProfile{}
+	err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, profile)
+	if err == nil {
+		return &profile.Spec, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get KeeperInjectionProfile %s: %w", name, err)
+	}
+
+	clusterProfile := &v1alpha1.ClusterKeeperInjectionProfile{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, clusterProfile); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("injection profile %q not found in namespace %s or cluster-wide", name, namespace)
+		}
+		return nil, fmt.Errorf("failed to get ClusterKeeperInjectionProfile %s: %w", name, err)
+	}
+	return &clusterProfile.Spec, nil
+}
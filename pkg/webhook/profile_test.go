@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/apis/v1alpha1"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newProfileClient(t *testing.T, objects ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func TestLoadProfile(t *testing.T) {
+	c := newProfileClient(t,
+		&v1alpha1.ClusterKeeperInjectionProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "standard"},
+			Spec:       v1alpha1.KeeperInjectionProfileSpec{KSMConfig: "cluster-auth"},
+		},
+		&v1alpha1.KeeperInjectionProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "standard", Namespace: "payments"},
+			Spec:       v1alpha1.KeeperInjectionProfileSpec{KSMConfig: "payments-auth"},
+		},
+	)
+	ctx := context.Background()
+
+	profile, err := loadProfile(ctx, c, "payments", "standard")
+	require.NoError(t, err)
+	assert.Equal(t, "payments-auth", profile.KSMConfig, "namespace profile shadows the cluster profile")
+
+	profile, err = loadProfile(ctx, c, "default", "standard")
+	require.NoError(t, err)
+	assert.Equal(t, "cluster-auth", profile.KSMConfig)
+
+	profile, err = loadProfile(ctx, c, "default", "")
+	require.NoError(t, err)
+	assert.Nil(t, profile)
+
+	_, err = loadProfile(ctx, c, "default", "missing")
+	assert.ErrorContains(t, err, `injection profile "missing" not found`)
+}
+
+func TestParseInjectionConfig_ProfileResources(t *testing.T) {
+	c := newProfileClient(t, &v1alpha1.KeeperInjectionProfile{
+		ObjectMeta: metav1.ObjectMeta{Name: "small", Namespace: "default"},
+		Spec: v1alpha1.KeeperInjectionProfileSpec{
+			KSMConfig: "keeper-auth",
+			Secrets:   []string{"db"},
+			Resources: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("16Mi")},
+			},
+		},
+	})
+	pod := newTestPod(corev1.Container{Name: "app"})
+	pod.Annotations = map[string]string{
+		config.AnnotationInject:  "true",
+		config.AnnotationProfile: "small",
+	}
+
+	cfg, err := parseInjectionConfig(context.Background(), c, pod, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "keeper-auth", cfg.AuthSecretName)
+
+	m := newTestMutator()
+	sidecar := m.buildSidecarContainer(cfg, "{}")
+	assert.Equal(t, "16Mi", sidecar.Resources.Limits.Memory().String())
+	assert.Empty(t, sidecar.Resources.Requests, "profile resources replace the webhook defaults")
+}
@@ -0,0 +1,355 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/metrics"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/sidecar"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ChecksumAnnotation holds the digest of the Keeper data a workload consumes.
+// On the workload it records the last observed digest; on the pod template it
+// is changed to start a rolling restart.
+const ChecksumAnnotation = "keeper.security/secrets-checksum"
+
+// DefaultRestartCheckInterval is how often workloads are checked for changed Keeper data
+const DefaultRestartCheckInterval = 5 * time.Minute
+
+// keeperReader is the part of the KSM client the restarter uses
+type keeperReader interface {
+	ListSecrets(ctx context.Context) ([]*ksm.SecretData, error)
+	GetNotation(ctx context.Context, notation string) ([]byte, error)
+	GetFileContent(ctx context.Context, nameOrUID, fileName string) ([]byte, error)
+	ListFolder(ctx context.Context, folder config.FolderRef) ([]*ksm.SecretData, error)
+}
+
+// ksmReader adds folder listing to the KSM client
+type ksmReader struct {
+	*ksm.Client
+}
+
+// ListFolder returns the records of a folder that pass its filter
+func (r ksmReader) ListFolder(ctx context.Context, folder config.FolderRef) ([]*ksm.SecretData, error) {
+	listing, err := sidecar.ListFolderRecords(ctx, r.Client, folderConfig(folder))
+	if err != nil {
+		return nil, err
+	}
+	return listing.Records, nil
+}
+
+// workload is a Deployment, StatefulSet or DaemonSet that opted in to restarts
+type workload struct {
+	kind     string
+	obj      client.Object
+	template *corev1.PodTemplateSpec // Points into obj
+	config   *config.InjectionConfig
+}
+
+// readerKey groups workloads that read Keeper with the same credentials
+type readerKey struct {
+	namespace  string
+	authSecret string
+	authMethod string
+	strict     bool
+}
+
+// RolloutRestarter restarts Deployments, StatefulSets and DaemonSets whose pod
+// template sets keeper.security/restart-on-change when the Keeper data they
+// consume changes. Env vars and K8s Secrets are resolved at admission, so a
+// rolling restart is the only way to deliver new values to them.
+type RolloutRestarter struct {
+	Client   client.Client
+	Interval time.Duration
+	logger   *zap.Logger
+
+	// newReader opens Keeper for a group of workloads (replaced in tests)
+	newReader func(ctx context.Context, namespace string, cfg *config.InjectionConfig) (keeperReader, func(), error)
+}
+
+// NewRolloutRestarter creates a restarter that checks workloads every interval
+func NewRolloutRestarter(c client.Client, logger *zap.Logger, interval time.Duration) *RolloutRestarter {
+	r := &RolloutRestarter{
+		Client:   c,
+		Interval: interval,
+		logger:   logger,
+	}
+	r.newReader = func(ctx context.Context, namespace string, cfg *config.InjectionConfig) (keeperReader, func(), error) {
+		ksmClient, err := newKSMClient(ctx, r.Client, r.logger, namespace, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ksmReader{ksmClient}, func() { _ = ksmClient.Close() }, nil
+	}
+	return r
+}
+
+// NeedLeaderElection runs the restarter on the leader replica only
+func (r *RolloutRestarter) NeedLeaderElection() bool {
+	return true
+}
+
+// Start checks the workloads every Interval until ctx is cancelled
+func (r *RolloutRestarter) Start(ctx context.Context) error {
+	r.logger.Info("starting workload restart controller", zap.Duration("interval", r.Interval))
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		r.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// check computes the Keeper data digest of every opted-in workload and
+// restarts those whose digest changed. Workloads sharing credentials list
+// Keeper records once.
+func (r *RolloutRestarter) check(ctx context.Context) {
+	workloads, err := r.listWorkloads(ctx)
+	if err != nil {
+		r.logger.Error("failed to list workloads", zap.Error(err))
+		return
+	}
+
+	groups := make(map[readerKey][]workload)
+	var keys []readerKey
+	for _, w := range workloads {
+		key := readerKey{
+			namespace:  w.obj.GetNamespace(),
+			authSecret: w.config.AuthSecretName,
+			authMethod: w.config.AuthMethod,
+			strict:     w.config.StrictLookup,
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], w)
+	}
+
+	for _, key := range keys {
+		r.checkGroup(ctx, key.namespace, groups[key])
+	}
+}
+
+// checkGroup checks workloads that share Keeper credentials
+func (r *RolloutRestarter) checkGroup(ctx context.Context, namespace string, workloads []workload) {
+	reader, closeReader, err := r.newReader(ctx, namespace, workloads[0].config)
+	if err != nil {
+		r.logger.Error("failed to connect to Keeper for workload restarts",
+			zap.String("namespace", namespace),
+			zap.Error(err))
+		return
+	}
+	defer closeReader()
+
+	records, err := reader.ListSecrets(ctx)
+	if err != nil {
+		r.logger.Error("failed to list Keeper records for workload restarts",
+			zap.String("namespace", namespace),
+			zap.Error(err))
+		return
+	}
+	lookup := newRecordLookup(records)
+
+	for _, w := range workloads {
+		checksum, err := workloadChecksum(ctx, reader, lookup, w.config)
+		if err != nil {
+			r.logger.Warn("failed to read Keeper data of workload",
+				zap.String("kind", w.kind),
+				zap.String("namespace", namespace),
+				zap.String("name", w.obj.GetName()),
+				zap.Error(err))
+			continue
+		}
+		if err := r.reconcile(ctx, w, checksum); err != nil {
+			r.logger.Error("failed to update workload checksum",
+				zap.String("kind", w.kind),
+				zap.String("namespace", namespace),
+				zap.String("name", w.obj.GetName()),
+				zap.Error(err))
+		}
+	}
+}
+
+// reconcile records the checksum on the workload and, when it changed since
+// the last check, on the pod template, which starts a rolling restart. The
+// first checksum seen only sets the baseline.
+func (r *RolloutRestarter) reconcile(ctx context.Context, w workload, checksum string) error {
+	previous := w.obj.GetAnnotations()[ChecksumAnnotation]
+	if previous == checksum {
+		return nil
+	}
+
+	patch := client.MergeFrom(w.obj.DeepCopyObject().(client.Object))
+	annotations := w.obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[ChecksumAnnotation] = checksum
+	w.obj.SetAnnotations(annotations)
+
+	restart := previous != ""
+	if restart {
+		if w.template.Annotations == nil {
+			w.template.Annotations = make(map[string]string)
+		}
+		w.template.Annotations[ChecksumAnnotation] = checksum
+	}
+
+	if err := r.Client.Patch(ctx, w.obj, patch); err != nil {
+		return err
+	}
+
+	if restart {
+		metrics.RecordWorkloadRestart(w.obj.GetNamespace(), w.kind)
+		r.logger.Info("restarting workload, Keeper data changed",
+			zap.String("kind", w.kind),
+			zap.String("namespace", w.obj.GetNamespace()),
+			zap.String("name", w.obj.GetName()))
+	}
+	return nil
+}
+
+// listWorkloads returns the workloads whose pod template opted in to restarts
+func (r *RolloutRestarter) listWorkloads(ctx context.Context) ([]workload, error) {
+	var workloads []workload
+	add := func(kind string, obj client.Object, template *corev1.PodTemplateSpec) {
+		pod := &corev1.Pod{ObjectMeta: template.ObjectMeta, Spec: template.Spec}
+		pod.Namespace = obj.GetNamespace()
+		if !config.ShouldInject(pod) || pod.Annotations[config.AnnotationRestartOnChange] == "" {
+			return
+		}
+		cfg, err := parseInjectionConfig(ctx, r.Client, pod, obj.GetNamespace())
+		if err != nil {
+			r.logger.Warn("skipping workload with invalid injection annotations",
+				zap.String("kind", kind),
+				zap.String("namespace", obj.GetNamespace()),
+				zap.String("name", obj.GetName()),
+				zap.Error(err))
+			return
+		}
+		if cfg.RestartOnChange {
+			workloads = append(workloads, workload{kind: kind, obj: obj, template: template, config: cfg})
+		}
+	}
+
+	var deployments appsv1.DeploymentList
+	if err := r.Client.List(ctx, &deployments); err != nil {
+		return nil, fmt.Errorf("failed to list Deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		add("Deployment", &deployments.Items[i], &deployments.Items[i].Spec.Template)
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := r.Client.List(ctx, &statefulSets); err != nil {
+		return nil, fmt.Errorf("failed to list StatefulSets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		add("StatefulSet", &statefulSets.Items[i], &statefulSets.Items[i].Spec.Template)
+	}
+
+	var daemonSets appsv1.DaemonSetList
+	if err := r.Client.List(ctx, &daemonSets); err != nil {
+		return nil, fmt.Errorf("failed to list DaemonSets: %w", err)
+	}
+	for i := range daemonSets.Items {
+		add("DaemonSet", &daemonSets.Items[i], &daemonSets.Items[i].Spec.Template)
+	}
+
+	return workloads, nil
+}
+
+// recordLookup finds listed records by UID or title
+type recordLookup struct {
+	byUID   map[string]*ksm.SecretData
+	byTitle map[string]*ksm.SecretData
+}
+
+func newRecordLookup(records []*ksm.SecretData) recordLookup {
+	lookup := recordLookup{
+		byUID:   make(map[string]*ksm.SecretData, len(records)),
+		byTitle: make(map[string]*ksm.SecretData, len(records)),
+	}
+	for _, record := range records {
+		lookup.byUID[record.RecordUID] = record
+		lookup.byTitle[record.Title] = record
+	}
+	return lookup
+}
+
+// get returns the record named by a title or UID, or nil
+func (l recordLookup) get(name string) *ksm.SecretData {
+	if record, ok := l.byUID[name]; ok && looksLikeUID(name) {
+		return record
+	}
+	return l.byTitle[name]
+}
+
+// workloadChecksum digests the Keeper data a workload consumes: its secrets,
+// template records and folders. Missing records are digested as missing, so a
+// record that appears later restarts the workload too.
+func workloadChecksum(ctx context.Context, reader keeperReader, lookup recordLookup, cfg *config.InjectionConfig) (string, error) {
+	h := sha256.New()
+	write := func(kind, name string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00", kind, name, data)
+	}
+
+	for _, s := range cfg.Secrets {
+		switch {
+		case s.Notation != "":
+			value, err := reader.GetNotation(ctx, s.Notation)
+			if err != nil {
+				return "", fmt.Errorf("notation %s: %w", s.Notation, err)
+			}
+			write("notation", s.Notation, string(value))
+		case s.IsFile:
+			content, err := reader.GetFileContent(ctx, s.Name, s.FileName)
+			if err != nil {
+				return "", fmt.Errorf("file %s of %s: %w", s.FileName, s.Name, err)
+			}
+			write("file", s.Name+"/"+s.FileName, content)
+		default:
+			write("secret", s.Name, lookup.get(s.Name))
+		}
+	}
+
+	for _, t := range cfg.Templates {
+		var names []string
+		for _, name := range t.Records {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range slices.Compact(names) {
+			write("record", name, lookup.get(name))
+		}
+	}
+
+	for _, f := range cfg.Folders {
+		records, err := reader.ListFolder(ctx, f)
+		if err != nil {
+			return "", fmt.Errorf("folder %s: %w", folderName(f), err)
+		}
+		sort.Slice(records, func(i, j int) bool { return records[i].RecordUID < records[j].RecordUID })
+		write("folder", folderName(f)+"\x00"+f.OutputPath, records)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/keeper-security/keeper-k8s-injector/pkg/config"
+	"github.com/keeper-security/keeper-k8s-injector/pkg/ksm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeKeeper serves records from memory
+type fakeKeeper struct {
+	records []*ksm.SecretData
+	folders map[string][]*ksm.SecretData
+}
+
+func (f *fakeKeeper) ListSecrets(context.Context) ([]*ksm.SecretData, error) {
+	return f.records, nil
+}
+
+func (f *fakeKeeper) GetNotation(_ context.Context, notation string) ([]byte, error) {
+	return []byte("value of " + notation), nil
+}
+
+func (f *fakeKeeper) GetFileContent(_ context.Context, nameOrUID, fileName string) ([]byte, error) {
+	return []byte(nameOrUID + "/" + fileName), nil
+}
+
+func (f *fakeKeeper) ListFolder(_ context.Context, folder config.FolderRef) ([]*ksm.SecretData, error) {
+	return f.folders[folder.FolderUID], nil
+}
+
+func newTestDeployment(name string, annotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+			},
+		},
+	}
+}
+
+func TestRolloutRestarter(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+
+	optedIn := newTestDeployment("api", map[string]string{
+		"keeper.security/inject":            "true",
+		"keeper.security/ksm-config":        "keeper-auth",
+		"keeper.security/secret":            "db",
+		"keeper.security/restart-on-change": "true",
+	})
+	other := newTestDeployment("worker", map[string]string{
+		"keeper.security/inject":     "true",
+		"keeper.security/ksm-config": "keeper-auth",
+		"keeper.security/secret":     "db",
+	})
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(optedIn, other).Build()
+
+	keeper := &fakeKeeper{records: []*ksm.SecretData{
+		{RecordUID: "AAAAAAAAAAAAAAAAAAAAAA", Title: "db", Fields: map[string]interface{}{"password": "v1"}},
+	}}
+	restarter := NewRolloutRestarter(fakeClient, zap.NewNop(), time.Minute)
+	restarter.newReader = func(context.Context, string, *config.InjectionConfig) (keeperReader, func(), error) {
+		return keeper, func() {}, nil
+	}
+	get := func(name string) *appsv1.Deployment {
+		d := &appsv1.Deployment{}
+		require.NoError(t, fakeClient.Get(context.Background(), client.ObjectKey{Namespace: "apps", Name: name}, d))
+		return d
+	}
+
+	// First check records the baseline without restarting
+	restarter.check(context.Background())
+	baseline := get("api").Annotations[ChecksumAnnotation]
+	assert.NotEmpty(t, baseline)
+	assert.NotContains(t, get("api").Spec.Template.Annotations, ChecksumAnnotation)
+
+	// Unchanged data: nothing happens
+	restarter.check(context.Background())
+	assert.NotContains(t, get("api").Spec.Template.Annotations, ChecksumAnnotation)
+
+	// Changed data: the pod template changes, which rolls the Deployment
+	keeper.records[0].Fields["password"] = "v2"
+	restarter.check(context.Background())
+	api := get("api")
+	assert.NotEqual(t, baseline, api.Annotations[ChecksumAnnotation])
+	assert.Equal(t, api.Annotations[ChecksumAnnotation], api.Spec.Template.Annotations[ChecksumAnnotation])
+
+	// Workloads that did not opt in are never touched
+	assert.NotContains(t, get("worker").Annotations, ChecksumAnnotation)
+	assert.NotContains(t, get("worker").Spec.Template.Annotations, ChecksumAnnotation)
+}
+
+func TestWorkloadChecksum(t *testing.T) {
+	keeper := &fakeKeeper{
+		records: []*ksm.SecretData{
+			{RecordUID: "AAAAAAAAAAAAAAAAAAAAAA", Title: "db", Fields: map[string]interface{}{"password": "v1"}},
+			{RecordUID: "BBBBBBBBBBBBBBBBBBBBBB", Title: "api", Fields: map[string]interface{}{"token": "t1"}},
+		},
+		folders: map[string][]*ksm.SecretData{
+			"FOLDER1": {{RecordUID: "CCCCCCCCCCCCCCCCCCCCCC", Title: "stripe", Fields: map[string]interface{}{"key": "k1"}}},
+		},
+	}
+	checksum := func(cfg *config.InjectionConfig) string {
+		sum, err := workloadChecksum(context.Background(), keeper, newRecordLookup(keeper.records), cfg)
+		require.NoError(t, err)
+		return sum
+	}
+
+	// Records are found by title or UID
+	assert.Equal(t,
+		checksum(&config.InjectionConfig{Templates: []config.TemplateRef{{Records: map[string]string{"a": "db", "b": "api"}}}}),
+		checksum(&config.InjectionConfig{Templates: []config.TemplateRef{{Records: map[string]string{"x": "api", "y": "db"}}}}),
+		"aliases and order do not matter")
+	assert.NotEqual(t,
+		checksum(&config.InjectionConfig{Secrets: []config.SecretRef{{Name: "db"}}}),
+		checksum(&config.InjectionConfig{Secrets: []config.SecretRef{{Name: "missing"}}}))
+
+	// Folder records are part of the digest
+	folders := &config.InjectionConfig{Folders: []config.FolderRef{{FolderUID: "FOLDER1", OutputPath: "/keeper/secrets/f"}}}
+	before := checksum(folders)
+	keeper.folders["FOLDER1"][0].Fields["key"] = "k2"
+	assert.NotEqual(t, before, checksum(folders))
+}